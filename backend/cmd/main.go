@@ -65,4 +65,12 @@ func main() {
 	} else {
 		log.Println("✅ Server exited gracefully")
 	}
+
+	// 8. Flush clicks buffered by redirects served before shutdown and
+	// close the databases
+	if err := app.Shutdown(ctx); err != nil {
+		log.Printf("❌ Failed to shut down cleanly: %v", err)
+	} else {
+		log.Println("✅ Buffered clicks flushed and databases closed")
+	}
 }
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// ClickEvent records a single redirect through a short link
+type ClickEvent struct {
+	ID        string    `json:"id"`
+	Code      string    `json:"code"`
+	Timestamp time.Time `json:"timestamp"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPPrefix  string    `json:"ip_prefix,omitempty"` // Anonymized client IP, never the full address
+	Source    string    `json:"source,omitempty"`    // Source tag from the query string, e.g. ?src=newsletter
+	Country   string    `json:"country,omitempty"`   // ISO 3166-1 alpha-2 code from GeoIP
+	Region    string    `json:"region,omitempty"`    // ISO 3166-2 subdivision code from GeoIP
+	Bot       bool      `json:"bot"`                 // Crawler, unfurler, monitor or prefetch
+}
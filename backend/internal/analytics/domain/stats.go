@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// Interval is the width of a time-series bucket
+type Interval string
+
+const (
+	IntervalHour Interval = "hour"
+	IntervalDay  Interval = "day"
+	IntervalWeek Interval = "week"
+)
+
+// Bucket counts the clicks starting at Start and lasting one interval
+type Bucket struct {
+	Start  time.Time `json:"start"`
+	Clicks int       `json:"clicks"`
+}
+
+// BreakdownEntry counts the clicks sharing one dimension value
+type BreakdownEntry struct {
+	Key    string `json:"key"`
+	Clicks int    `json:"clicks"`
+}
+
+// LinkStats is the click analytics of a link over a time range
+type LinkStats struct {
+	Code             string           `json:"code"`
+	From             time.Time        `json:"from"`
+	To               time.Time        `json:"to"`
+	Interval         Interval         `json:"interval"`
+	TotalClicks      int              `json:"totalClicks"`
+	BotClicks        int              `json:"botClicks"`   // Automated clicks in range, whether included or not
+	IncludeBots      bool             `json:"includeBots"` // Whether bot clicks are part of the other figures
+	Series           []Bucket         `json:"series"`
+	Referrers        []BreakdownEntry `json:"referrers"`
+	Browsers         []BreakdownEntry `json:"browsers"`
+	OperatingSystems []BreakdownEntry `json:"operatingSystems"`
+	Devices          []BreakdownEntry `json:"devices"`
+	Countries        []BreakdownEntry `json:"countries"`
+	Regions          []BreakdownEntry `json:"regions"`
+}
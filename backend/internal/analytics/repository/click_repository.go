@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"ecolink-core/internal/analytics/domain"
+	"time"
+)
+
+// ClickRepository defines the persistence port for click events
+type ClickRepository interface {
+	RecordClick(ctx context.Context, event *domain.ClickEvent) error
+	// RecordClicks stores a batch of events in as few writes as possible
+	RecordClicks(ctx context.Context, events []*domain.ClickEvent) error
+	// FindByCode returns the events of a link in [from, to), oldest first
+	FindByCode(ctx context.Context, code string, from, to time.Time) ([]*domain.ClickEvent, error)
+	// ScanByCode calls fn for each event of a link in [from, to), oldest
+	// first, without holding them all in memory. It stops at the first
+	// error fn returns.
+	ScanByCode(ctx context.Context, code string, from, to time.Time, fn func(*domain.ClickEvent) error) error
+	// ReassignCode moves events to a link's new code after an alias change
+	ReassignCode(ctx context.Context, oldCode, newCode string) error
+	DeleteByCode(ctx context.Context, code string) error
+}
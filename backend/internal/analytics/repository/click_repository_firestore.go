@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"ecolink-core/internal/analytics/domain"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+const clickEventsCollection = "click_events"
+
+type FirestoreClickRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreClickRepository(client *firestore.Client) *FirestoreClickRepository {
+	return &FirestoreClickRepository{client: client}
+}
+
+func (r *FirestoreClickRepository) RecordClick(ctx context.Context, event *domain.ClickEvent) error {
+	_, err := r.client.Collection(clickEventsCollection).Doc(event.ID).Set(ctx, clickEventToData(event))
+	return err
+}
+
+func (r *FirestoreClickRepository) RecordClicks(ctx context.Context, events []*domain.ClickEvent) error {
+	bw := r.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(events))
+	for _, event := range events {
+		job, err := bw.Set(r.client.Collection(clickEventsCollection).Doc(event.ID), clickEventToData(event))
+		if err != nil {
+			bw.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *FirestoreClickRepository) FindByCode(ctx context.Context, code string, from, to time.Time) ([]*domain.ClickEvent, error) {
+	var events []*domain.ClickEvent
+	err := r.ScanByCode(ctx, code, from, to, func(event *domain.ClickEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ScanByCode streams the query; the iterator fetches documents in pages
+func (r *FirestoreClickRepository) ScanByCode(ctx context.Context, code string, from, to time.Time, fn func(*domain.ClickEvent) error) error {
+	iter := r.client.Collection(clickEventsCollection).
+		Where("code", "==", code).
+		Where("timestamp", ">=", from).
+		Where("timestamp", "<", to).
+		OrderBy("timestamp", firestore.Asc).
+		Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read click events: %w", err)
+		}
+		if err := fn(clickEventFromDoc(doc)); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *FirestoreClickRepository) ReassignCode(ctx context.Context, oldCode, newCode string) error {
+	return r.forEachByCode(ctx, oldCode, func(bw *firestore.BulkWriter, ref *firestore.DocumentRef) (*firestore.BulkWriterJob, error) {
+		return bw.Update(ref, []firestore.Update{{Path: "code", Value: newCode}})
+	})
+}
+
+func (r *FirestoreClickRepository) DeleteByCode(ctx context.Context, code string) error {
+	return r.forEachByCode(ctx, code, func(bw *firestore.BulkWriter, ref *firestore.DocumentRef) (*firestore.BulkWriterJob, error) {
+		return bw.Delete(ref)
+	})
+}
+
+// forEachByCode applies a bulk write to every event of a link
+func (r *FirestoreClickRepository) forEachByCode(ctx context.Context, code string, write func(*firestore.BulkWriter, *firestore.DocumentRef) (*firestore.BulkWriterJob, error)) error {
+	iter := r.client.Collection(clickEventsCollection).Where("code", "==", code).Documents(ctx)
+	defer iter.Stop()
+
+	bw := r.client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bw.End()
+			return fmt.Errorf("failed to read click events: %w", err)
+		}
+
+		job, err := write(bw, doc.Ref)
+		if err != nil {
+			bw.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func clickEventToData(event *domain.ClickEvent) map[string]interface{} {
+	return map[string]interface{}{
+		"code":       event.Code,
+		"timestamp":  event.Timestamp,
+		"referrer":   event.Referrer,
+		"user_agent": event.UserAgent,
+		"ip_prefix":  event.IPPrefix,
+		"source":     event.Source,
+		"country":    event.Country,
+		"region":     event.Region,
+		"bot":        event.Bot,
+	}
+}
+
+func clickEventFromDoc(doc *firestore.DocumentSnapshot) *domain.ClickEvent {
+	data := doc.Data()
+	event := &domain.ClickEvent{ID: doc.Ref.ID}
+
+	event.Code, _ = data["code"].(string)
+	event.Timestamp, _ = data["timestamp"].(time.Time)
+	event.Referrer, _ = data["referrer"].(string)
+	event.UserAgent, _ = data["user_agent"].(string)
+	event.IPPrefix, _ = data["ip_prefix"].(string)
+	event.Source, _ = data["source"].(string)
+	event.Country, _ = data["country"].(string)
+	event.Region, _ = data["region"].(string)
+	event.Bot, _ = data["bot"].(bool)
+
+	return event
+}
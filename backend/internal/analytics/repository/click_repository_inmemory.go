@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"ecolink-core/internal/analytics/domain"
+	"sort"
+	"sync"
+	"time"
+)
+
+type InMemoryClickRepository struct {
+	mu     sync.RWMutex
+	events map[string][]*domain.ClickEvent
+}
+
+func NewInMemoryClickRepository() *InMemoryClickRepository {
+	return &InMemoryClickRepository{
+		events: make(map[string][]*domain.ClickEvent),
+	}
+}
+
+func (r *InMemoryClickRepository) RecordClick(ctx context.Context, event *domain.ClickEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events[event.Code] = append(r.events[event.Code], event)
+	return nil
+}
+
+func (r *InMemoryClickRepository) RecordClicks(ctx context.Context, events []*domain.ClickEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, event := range events {
+		r.events[event.Code] = append(r.events[event.Code], event)
+	}
+	return nil
+}
+
+func (r *InMemoryClickRepository) FindByCode(ctx context.Context, code string, from, to time.Time) ([]*domain.ClickEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []*domain.ClickEvent
+	for _, event := range r.events[code] {
+		if !event.Timestamp.Before(from) && event.Timestamp.Before(to) {
+			events = append(events, event)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+	return events, nil
+}
+
+// ScanByCode works on a snapshot of the events, which are in memory anyway,
+// and calls fn outside the lock
+func (r *InMemoryClickRepository) ScanByCode(ctx context.Context, code string, from, to time.Time, fn func(*domain.ClickEvent) error) error {
+	events, _ := r.FindByCode(ctx, code, from, to)
+	for _, event := range events {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryClickRepository) ReassignCode(ctx context.Context, oldCode, newCode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, event := range r.events[oldCode] {
+		event.Code = newCode
+	}
+	r.events[newCode] = append(r.events[newCode], r.events[oldCode]...)
+	delete(r.events, oldCode)
+	return nil
+}
+
+func (r *InMemoryClickRepository) DeleteByCode(ctx context.Context, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.events, code)
+	return nil
+}
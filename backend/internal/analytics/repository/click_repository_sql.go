@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"ecolink-core/internal/analytics/domain"
+	"fmt"
+	"time"
+)
+
+const clickEventColumns = `id, code, timestamp, referrer, user_agent, ip_prefix, source, country, region, bot`
+
+// SQLClickRepository stores click events in the click_events table created
+// by the PostgreSQL and SQLite migrations. Timestamps are written in UTC so
+// range queries also work on SQLite's text timestamps.
+type SQLClickRepository struct {
+	db *sql.DB
+}
+
+func NewSQLClickRepository(db *sql.DB) *SQLClickRepository {
+	return &SQLClickRepository{db: db}
+}
+
+func (r *SQLClickRepository) RecordClick(ctx context.Context, event *domain.ClickEvent) error {
+	return r.RecordClicks(ctx, []*domain.ClickEvent{event})
+}
+
+func (r *SQLClickRepository) RecordClicks(ctx context.Context, events []*domain.ClickEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO click_events (`+clickEventColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (id) DO NOTHING`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, event := range events {
+		_, err := stmt.ExecContext(ctx, event.ID, event.Code, event.Timestamp.UTC(), event.Referrer, event.UserAgent,
+			event.IPPrefix, event.Source, event.Country, event.Region, event.Bot)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *SQLClickRepository) FindByCode(ctx context.Context, code string, from, to time.Time) ([]*domain.ClickEvent, error) {
+	var events []*domain.ClickEvent
+	err := r.ScanByCode(ctx, code, from, to, func(event *domain.ClickEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *SQLClickRepository) ScanByCode(ctx context.Context, code string, from, to time.Time, fn func(*domain.ClickEvent) error) error {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+clickEventColumns+` FROM click_events
+		WHERE code = $1 AND timestamp >= $2 AND timestamp < $3 ORDER BY timestamp`, code, from.UTC(), to.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to read click events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event domain.ClickEvent
+		err := rows.Scan(&event.ID, &event.Code, &event.Timestamp, &event.Referrer, &event.UserAgent,
+			&event.IPPrefix, &event.Source, &event.Country, &event.Region, &event.Bot)
+		if err != nil {
+			return fmt.Errorf("failed to read click events: %w", err)
+		}
+		event.Timestamp = event.Timestamp.UTC()
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *SQLClickRepository) ReassignCode(ctx context.Context, oldCode, newCode string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE click_events SET code = $2 WHERE code = $1`, oldCode, newCode)
+	return err
+}
+
+func (r *SQLClickRepository) DeleteByCode(ctx context.Context, code string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM click_events WHERE code = $1`, code)
+	return err
+}
@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"ecolink-core/internal/analytics/domain"
+	"ecolink-core/internal/analytics/repository"
+	"ecolink-core/pkg/geoip"
+	"ecolink-core/pkg/utils"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// maxFieldLength caps client-controlled strings stored with each event
+const maxFieldLength = 512
+
+// sourceParams are the query parameters read as a click's source tag, in
+// order of precedence
+var sourceParams = []string{"src", "utm_source", "ref"}
+
+// ClickInput holds the raw request data of a redirect
+type ClickInput struct {
+	Code      string
+	Referrer  string
+	UserAgent string
+	ClientIP  string
+	Query     map[string][]string
+	Bot       bool      // Set by the caller's bot classifier
+	Counted   bool      // Already added to the link's counters by the caller
+	Timestamp time.Time // Time of the redirect; defaults to now
+}
+
+type AnalyticsService struct {
+	clickRepo repository.ClickRepository
+	locator   geoip.Locator
+}
+
+// NewAnalyticsService creates the service; locator may be nil to disable
+// GeoIP enrichment
+func NewAnalyticsService(clickRepo repository.ClickRepository, locator geoip.Locator) *AnalyticsService {
+	return &AnalyticsService{
+		clickRepo: clickRepo,
+		locator:   locator,
+	}
+}
+
+// RecordClick stores a click event, anonymizing the client IP
+func (s *AnalyticsService) RecordClick(ctx context.Context, input ClickInput) error {
+	return s.clickRepo.RecordClick(ctx, s.newClickEvent(input))
+}
+
+// RecordClicks stores a batch of click events, anonymizing client IPs
+func (s *AnalyticsService) RecordClicks(ctx context.Context, inputs []ClickInput) error {
+	events := make([]*domain.ClickEvent, len(inputs))
+	for i, input := range inputs {
+		events[i] = s.newClickEvent(input)
+	}
+	return s.clickRepo.RecordClicks(ctx, events)
+}
+
+// newClickEvent builds the event stored for a redirect. The location is
+// resolved from the full IP before it is anonymized.
+func (s *AnalyticsService) newClickEvent(input ClickInput) *domain.ClickEvent {
+	event := &domain.ClickEvent{
+		ID:        generateEventID(),
+		Code:      input.Code,
+		Timestamp: input.Timestamp.UTC(),
+		Referrer:  truncate(input.Referrer),
+		UserAgent: truncate(input.UserAgent),
+		IPPrefix:  utils.AnonymizeIP(input.ClientIP),
+		Source:    truncate(sourceTag(input.Query)),
+		Bot:       input.Bot,
+	}
+
+	if input.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	if s.locator != nil {
+		if location, err := s.locator.Lookup(input.ClientIP); err == nil {
+			event.Country = location.Country
+			event.Region = location.Region
+		}
+	}
+
+	return event
+}
+
+// GetClicks returns the click events of a link in [from, to)
+func (s *AnalyticsService) GetClicks(ctx context.Context, code string, from, to time.Time) ([]*domain.ClickEvent, error) {
+	return s.clickRepo.FindByCode(ctx, code, from, to)
+}
+
+// ReassignClicks keeps a link's history when its code changes
+func (s *AnalyticsService) ReassignClicks(ctx context.Context, oldCode, newCode string) error {
+	return s.clickRepo.ReassignCode(ctx, oldCode, newCode)
+}
+
+// DeleteClicks drops a deleted link's history so a future link reusing the
+// code starts clean
+func (s *AnalyticsService) DeleteClicks(ctx context.Context, code string) error {
+	return s.clickRepo.DeleteByCode(ctx, code)
+}
+
+func sourceTag(query map[string][]string) string {
+	for _, param := range sourceParams {
+		if values := query[param]; len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// truncate caps a string's length, dropping any rune cut in half
+func truncate(value string) string {
+	if len(value) > maxFieldLength {
+		value = value[:maxFieldLength]
+	}
+	return strings.ToValidUTF8(value, "")
+}
+
+func generateEventID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
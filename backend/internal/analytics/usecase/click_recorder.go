@@ -0,0 +1,229 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClickCounter is the port used to add batched clicks to link counters
+type ClickCounter interface {
+	AddClicks(ctx context.Context, code string, humans, bots int) error
+}
+
+// RecorderConfig tunes the click recorder buffer and batching
+type RecorderConfig struct {
+	BufferSize    int           // Pending clicks kept before new ones are dropped
+	BatchSize     int           // Clicks written per flush at most
+	FlushInterval time.Duration // Longest time a click waits in the buffer
+}
+
+const (
+	defaultBufferSize    = 10000
+	defaultBatchSize     = 500 // Firestore's limit of writes per batch
+	defaultFlushInterval = 2 * time.Second
+	flushTimeout         = 10 * time.Second
+)
+
+// ClickRecorder takes clicks off the redirect path: redirects enqueue them
+// without blocking and a background worker batches counter increments and
+// event writes. A full buffer or a failed write loses analytics, never a
+// redirect.
+type ClickRecorder struct {
+	analytics *AnalyticsService
+	counter   ClickCounter
+	config    RecorderConfig
+
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan ClickInput
+	flushes chan chan struct{}
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+// NewClickRecorder starts the background worker; Close stops it
+func NewClickRecorder(analytics *AnalyticsService, counter ClickCounter, config RecorderConfig) *ClickRecorder {
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaultBufferSize
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultFlushInterval
+	}
+
+	r := &ClickRecorder{
+		analytics: analytics,
+		counter:   counter,
+		config:    config,
+		queue:     make(chan ClickInput, config.BufferSize),
+		flushes:   make(chan chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Enqueue hands a click to the worker without blocking. It reports false
+// when the click was dropped because the buffer is full or the recorder is
+// closed.
+func (r *ClickRecorder) Enqueue(input ClickInput) bool {
+	if input.Timestamp.IsZero() {
+		input.Timestamp = time.Now()
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return false
+	}
+
+	select {
+	case r.queue <- input:
+		return true
+	default:
+		if dropped := r.dropped.Add(1); dropped == 1 || dropped%1000 == 0 {
+			log.Printf("⚠️  Click buffer full, %d clicks dropped so far", dropped)
+		}
+		return false
+	}
+}
+
+// Dropped returns the number of clicks lost to a full buffer
+func (r *ClickRecorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Close stops accepting clicks and waits until buffered ones are flushed
+// or ctx expires
+func (r *ClickRecorder) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush waits until the clicks enqueued so far are written, so they are
+// stored under the codes they were made on before a link is renamed
+func (r *ClickRecorder) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case r.flushes <- flushed:
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ReassignClicks moves the recorded clicks of a renamed link to its new
+// code
+func (r *ClickRecorder) ReassignClicks(ctx context.Context, oldCode, newCode string) error {
+	return r.analytics.ReassignClicks(ctx, oldCode, newCode)
+}
+
+func (r *ClickRecorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]ClickInput, 0, r.config.BatchSize)
+	add := func(input ClickInput) {
+		batch = append(batch, input)
+		if len(batch) >= r.config.BatchSize {
+			r.flush(batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case input, ok := <-r.queue:
+			if !ok {
+				r.flush(batch)
+				return
+			}
+			add(input)
+		case flushed := <-r.flushes:
+			// Only clicks already buffered; later ones wait for their turn
+			for pending := len(r.queue); pending > 0; pending-- {
+				input, ok := <-r.queue
+				if !ok {
+					break
+				}
+				add(input)
+			}
+			r.flush(batch)
+			batch = batch[:0]
+			close(flushed)
+		case <-ticker.C:
+			if len(batch) > 0 {
+				r.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// clickCounts accumulates the clicks of one link within a batch
+type clickCounts struct {
+	humans, bots int
+}
+
+func (r *ClickRecorder) flush(batch []ClickInput) {
+	if len(batch) == 0 {
+		return
+	}
+
+	counts := make(map[string]*clickCounts)
+	for _, input := range batch {
+		if input.Counted {
+			continue
+		}
+		c, ok := counts[input.Code]
+		if !ok {
+			c = &clickCounts{}
+			counts[input.Code] = c
+		}
+		if input.Bot {
+			c.bots++
+		} else {
+			c.humans++
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	for code, c := range counts {
+		if err := r.counter.AddClicks(ctx, code, c.humans, c.bots); err != nil {
+			log.Printf("⚠️  Failed to add %d clicks to %s: %v", c.humans+c.bots, code, err)
+		}
+	}
+
+	if err := r.analytics.RecordClicks(ctx, batch); err != nil {
+		log.Printf("⚠️  Failed to record %d click events: %v", len(batch), err)
+	}
+}
@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"context"
+	"ecolink-core/internal/analytics/domain"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mileusna/useragent"
+)
+
+// MaxBuckets bounds the size of a time series response
+const MaxBuckets = 1000
+
+// MaxStatsEvents bounds the click events read by one stats request
+const MaxStatsEvents = 1000000
+
+var (
+	ErrInvalidInterval = errors.New("interval must be hour, day or week")
+	ErrInvalidRange    = errors.New("invalid time range")
+)
+
+const (
+	directReferrer = "direct"
+	unknownValue   = "Unknown"
+)
+
+// StatsQuery selects the range and bucket width of a stats request
+type StatsQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval domain.Interval
+	// IncludeBots counts automated clicks in the series and breakdowns;
+	// by default only human clicks are reported
+	IncludeBots bool
+	// MaxEvents caps the click events read; defaults to MaxStatsEvents
+	MaxEvents int
+}
+
+// GetLinkStats aggregates a link's click events into a time series and
+// breakdowns by referrer domain, browser, OS, device class, country and
+// region. Events are streamed from the store; a range holding more than
+// query.MaxEvents of them fails with ErrInvalidRange.
+func (s *AnalyticsService) GetLinkStats(ctx context.Context, code string, query StatsQuery) (*domain.LinkStats, error) {
+	if err := validateStatsQuery(query); err != nil {
+		return nil, err
+	}
+	if query.MaxEvents <= 0 {
+		query.MaxEvents = MaxStatsEvents
+	}
+
+	from := bucketStart(query.From.UTC(), query.Interval)
+	series := make([]domain.Bucket, 0)
+	for start := from; start.Before(query.To); start = nextBucket(start, query.Interval) {
+		series = append(series, domain.Bucket{Start: start})
+	}
+
+	referrers := make(map[string]int)
+	browsers := make(map[string]int)
+	systems := make(map[string]int)
+	devices := make(map[string]int)
+	countries := make(map[string]int)
+	regions := make(map[string]int)
+
+	read, totalClicks, botClicks := 0, 0, 0
+	err := s.clickRepo.ScanByCode(ctx, code, query.From, query.To, func(event *domain.ClickEvent) error {
+		read++
+		if read > query.MaxEvents {
+			return fmt.Errorf("%w: more than %d clicks in range, request a shorter one", ErrInvalidRange, query.MaxEvents)
+		}
+
+		if event.Bot {
+			botClicks++
+			if !query.IncludeBots {
+				return nil
+			}
+		}
+		totalClicks++
+
+		index := bucketIndex(from, event.Timestamp.UTC(), query.Interval)
+		if index >= 0 && index < len(series) {
+			series[index].Clicks++
+		}
+
+		ua := useragent.Parse(event.UserAgent)
+		referrers[referrerDomain(event.Referrer)]++
+		browsers[valueOrUnknown(ua.Name)]++
+		systems[valueOrUnknown(ua.OS)]++
+		devices[deviceClass(ua)]++
+		countries[valueOrUnknown(event.Country)]++
+		regions[regionKey(event.Country, event.Region)]++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.LinkStats{
+		Code:             code,
+		From:             query.From,
+		To:               query.To,
+		Interval:         query.Interval,
+		TotalClicks:      totalClicks,
+		BotClicks:        botClicks,
+		IncludeBots:      query.IncludeBots,
+		Series:           series,
+		Referrers:        toBreakdown(referrers),
+		Browsers:         toBreakdown(browsers),
+		OperatingSystems: toBreakdown(systems),
+		Devices:          toBreakdown(devices),
+		Countries:        toBreakdown(countries),
+		Regions:          toBreakdown(regions),
+	}, nil
+}
+
+func validateStatsQuery(query StatsQuery) error {
+	switch query.Interval {
+	case domain.IntervalHour, domain.IntervalDay, domain.IntervalWeek:
+	default:
+		return ErrInvalidInterval
+	}
+
+	if !query.From.Before(query.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidRange)
+	}
+
+	if buckets := bucketIndex(bucketStart(query.From.UTC(), query.Interval), query.To.UTC(), query.Interval); buckets > MaxBuckets {
+		return fmt.Errorf("%w: more than %d %s buckets requested", ErrInvalidRange, MaxBuckets, query.Interval)
+	}
+
+	return nil
+}
+
+// bucketStart aligns t to the start of its bucket in UTC; weeks start on Monday
+func bucketStart(t time.Time, interval domain.Interval) time.Time {
+	switch interval {
+	case domain.IntervalHour:
+		return t.Truncate(time.Hour)
+	case domain.IntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func nextBucket(start time.Time, interval domain.Interval) time.Time {
+	switch interval {
+	case domain.IntervalHour:
+		return start.Add(time.Hour)
+	case domain.IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// bucketIndex returns the position of t's bucket in a series starting at from
+func bucketIndex(from, t time.Time, interval domain.Interval) int {
+	switch interval {
+	case domain.IntervalHour:
+		return int(t.Sub(from) / time.Hour)
+	case domain.IntervalWeek:
+		return int(t.Sub(from) / (7 * 24 * time.Hour))
+	default:
+		return int(t.Sub(from) / (24 * time.Hour))
+	}
+}
+
+// referrerDomain reduces a referrer URL to its host without "www."
+func referrerDomain(referrer string) string {
+	if referrer == "" {
+		return directReferrer
+	}
+
+	parsed, err := url.Parse(referrer)
+	if err != nil || parsed.Hostname() == "" {
+		return unknownValue
+	}
+
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
+
+func deviceClass(ua useragent.UserAgent) string {
+	switch {
+	case ua.Bot:
+		return "bot"
+	case ua.Tablet:
+		return "tablet"
+	case ua.Mobile:
+		return "mobile"
+	case ua.Desktop:
+		return "desktop"
+	default:
+		return "unknown"
+	}
+}
+
+// regionKey qualifies a subdivision with its country, e.g. "BR-SP"
+func regionKey(country, region string) string {
+	if country == "" || region == "" {
+		return unknownValue
+	}
+	return country + "-" + region
+}
+
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return unknownValue
+	}
+	return value
+}
+
+// toBreakdown sorts counts by clicks descending, then key ascending
+func toBreakdown(counts map[string]int) []domain.BreakdownEntry {
+	entries := make([]domain.BreakdownEntry, 0, len(counts))
+	for key, clicks := range counts {
+		entries = append(entries, domain.BreakdownEntry{Key: key, Clicks: clicks})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Clicks != entries[j].Clicks {
+			return entries[i].Clicks > entries[j].Clicks
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"ecolink-core/internal/auth/domain"
+	"ecolink-core/internal/auth/usecase"
+	"ecolink-core/internal/errors"
+	"ecolink-core/internal/validation"
+	"ecolink-core/pkg/database"
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	apiKeys   *usecase.APIKeyService
+	validator *validation.Validator
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,min=1,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func NewAPIKeyHandler(apiKeys *usecase.APIKeyService, validator *validation.Validator) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeys:   apiKeys,
+		validator: validator,
+	}
+}
+
+// CreateAPIKey issues a personal API key. The key is in this response only;
+// it cannot be retrieved later.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("User ID not found in context"))
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid request format"))
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	key, value, err := h.apiKeys.CreateKey(c.Request.Context(), userID.(string), req.Name, req.Scopes, req.ExpiresAt)
+	switch {
+	case err == nil:
+	case stderrors.Is(err, usecase.ErrUnknownScope), stderrors.Is(err, usecase.ErrExpiryInPast):
+		c.JSON(http.StatusBadRequest, errors.NewValidationError(err.Error()))
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created; store it now, it will not be shown again",
+		"key":     value,
+		"api_key": key,
+	})
+}
+
+// ListAPIKeys returns the current user's API keys, without their values
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("User ID not found in context"))
+		return
+	}
+
+	keys, err := h.apiKeys.ListKeys(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+		return
+	}
+	if keys == nil {
+		keys = []*domain.APIKey{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys": keys,
+	})
+}
+
+// DeleteAPIKey revokes one of the current user's API keys
+func (h *APIKeyHandler) DeleteAPIKey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("User ID not found in context"))
+		return
+	}
+
+	err := h.apiKeys.DeleteKey(c.Request.Context(), userID.(string), c.Param("id"))
+	switch {
+	case err == nil:
+	case stderrors.Is(err, database.ErrNotFound):
+		c.JSON(http.StatusNotFound, errors.NewNotFoundError("API key"))
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key deleted",
+	})
+}
@@ -1,17 +1,32 @@
 package http
 
 import (
+	"ecolink-core/internal/auth/domain"
 	"ecolink-core/internal/auth/usecase"
 	"ecolink-core/internal/errors"
+	"ecolink-core/internal/middleware"
 	"ecolink-core/internal/validation"
+	"ecolink-core/pkg/database"
+	stderrors "errors"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	authCookie    = "ecolink_token"
+	refreshCookie = "ecolink_refresh"
+	// refreshCookiePath limits the refresh cookie to the auth routes
+	refreshCookiePath = "/auth"
+)
+
 type AuthHandler struct {
 	authService usecase.AuthService
+	emails      *usecase.AccountEmailService
+	mfa         *usecase.MFAService
 	validator   *validation.Validator
 }
 
@@ -26,15 +41,30 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,max=128"`
+}
+
 type GoogleCallbackRequest struct {
 	Code        string `json:"code" validate:"required"`
 	State       string `json:"state" validate:"required"`
 	RedirectURI string `json:"redirect_uri" validate:"required"`
 }
 
-func NewAuthHandler(authService usecase.AuthService, validator *validation.Validator) *AuthHandler {
+func NewAuthHandler(authService usecase.AuthService, emails *usecase.AccountEmailService, mfa *usecase.MFAService, validator *validation.Validator) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
+		emails:      emails,
+		mfa:         mfa,
 		validator:   validator,
 	}
 }
@@ -60,12 +90,132 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// The account is usable either way; a lost email can be sent again
+	message := "User registered successfully; check your email to verify your address"
+	if err := h.emails.SendVerification(c.Request.Context(), user); err != nil {
+		message = "User registered successfully, but the verification email could not be sent"
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully",
+		"message": message,
+		"user":    user,
+	})
+}
+
+// VerifyEmail verifies the address a verification link was sent to
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid request format"))
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	user, err := h.emails.VerifyEmail(c.Request.Context(), req.Token, clientOf(c))
+	if err != nil {
+		h.respondEmailTokenError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified",
 		"user":    user,
 	})
 }
 
+// ResendVerification emails the current user a new verification link
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("User ID not found in context"))
+		return
+	}
+
+	user, err := h.authService.GetUserByID(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusNotFound, errors.NewBusinessError("User not found", err.Error()))
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusConflict, errors.NewBusinessError("Email already verified", "Your email address is already verified"))
+		return
+	}
+
+	if err := h.emails.SendVerification(c.Request.Context(), user); err != nil {
+		c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Verification email sent",
+	})
+}
+
+// ForgotPassword emails a password reset link. The answer is the same for
+// registered and unknown emails.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid request format"))
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	if err := h.emails.RequestPasswordReset(c.Request.Context(), req.Email, clientOf(c)); err != nil {
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, errors.NewInternalError())
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If an account uses this email, a password reset link is on its way",
+	})
+}
+
+// ResetPassword sets a new password through a reset link. Every session of
+// the account is logged out, this client's included.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid request format"))
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	if err := h.emails.ResetPassword(c.Request.Context(), req.Token, req.Password, clientOf(c)); err != nil {
+		h.respondEmailTokenError(c, err)
+		return
+	}
+
+	h.clearSessionCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password reset; log in with your new password",
+	})
+}
+
+// respondEmailTokenError answers a failed use of a link sent by email
+func (h *AuthHandler) respondEmailTokenError(c *gin.Context, err error) {
+	switch {
+	case stderrors.Is(err, usecase.ErrInvalidEmailToken):
+		c.JSON(http.StatusBadRequest, errors.NewSecurityError("Invalid or expired link"))
+	case stderrors.Is(err, database.ErrUnavailable):
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, errors.NewInternalError())
+	default:
+		c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+	}
+}
+
 // Login handles user authentication with local credentials
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
@@ -81,14 +231,31 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// Authenticate user
-	token, err := h.authService.Login(c.Request.Context(), req.Email, req.Password)
-	if err != nil {
+	token, err := h.authService.Login(c.Request.Context(), req.Email, req.Password, clientOf(c))
+	var (
+		locked      *usecase.LoginLockedError
+		mfaRequired *usecase.MFARequiredError
+	)
+	switch {
+	case err == nil:
+	case stderrors.As(err, &mfaRequired):
+		h.respondMFARequired(c, mfaRequired)
+		return
+	case stderrors.As(err, &locked):
+		respondLocked(c, locked)
+		return
+	case stderrors.Is(err, database.ErrUnavailable):
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, errors.NewInternalError())
+		return
+	default:
 		c.JSON(http.StatusUnauthorized, errors.NewAuthError("Authentication failed"))
 		return
 	}
 
-	// Set secure HTTP-only cookie
+	// Set secure HTTP-only cookies
 	h.setAuthCookie(c, token.Token, token.ExpiresAt)
+	h.setRefreshCookie(c, token.RefreshToken, token.RefreshExpiresAt)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Login successful",
@@ -169,14 +336,20 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 	}
 
 	// Process OAuth callback
-	token, err := h.authService.HandleGoogleCallback(c.Request.Context(), req.Code, req.State)
+	token, err := h.authService.HandleGoogleCallback(c.Request.Context(), req.Code, req.State, clientOf(c))
+	var mfaRequired *usecase.MFARequiredError
+	if stderrors.As(err, &mfaRequired) {
+		h.respondMFARequired(c, mfaRequired)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, errors.NewAuthError("OAuth authentication failed"))
 		return
 	}
 
-	// Set secure HTTP-only cookie
+	// Set secure HTTP-only cookies
 	h.setAuthCookie(c, token.Token, token.ExpiresAt)
+	h.setRefreshCookie(c, token.RefreshToken, token.RefreshExpiresAt)
 
 	// Get user info for response
 	user, err := h.authService.GetUserByID(c.Request.Context(), token.UserID)
@@ -191,6 +364,41 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 	})
 }
 
+// Refresh exchanges the refresh token cookie for a new access token and a
+// new refresh token. A reused refresh token logs out every client of that
+// login.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	refreshToken, err := c.Cookie(refreshCookie)
+	if err != nil || refreshToken == "" {
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("Refresh token required"))
+		return
+	}
+
+	token, err := h.authService.Refresh(c.Request.Context(), refreshToken, clientOf(c))
+	switch {
+	case err == nil:
+	case stderrors.Is(err, usecase.ErrInvalidRefreshToken):
+		h.clearSessionCookies(c)
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("Invalid or expired refresh token"))
+		return
+	case stderrors.Is(err, database.ErrUnavailable):
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, errors.NewInternalError())
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+		return
+	}
+
+	h.setAuthCookie(c, token.Token, token.ExpiresAt)
+	h.setRefreshCookie(c, token.RefreshToken, token.RefreshExpiresAt)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Token refreshed",
+		"expires_at": token.ExpiresAt,
+	})
+}
+
 // GetCurrentUser retrieves the currently authenticated user's information.
 func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -208,58 +416,180 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
-// Logout clears the authentication cookie with auto-detection
+// Logout ends the login: the access token and its refresh tokens are
+// revoked and the cookies cleared
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// Auto-detect HTTPS for secure flag
-	secure := c.Request.TLS != nil
+	refreshToken, _ := c.Cookie(refreshCookie)
+	if err := h.authService.Logout(c.Request.Context(), middleware.ExtractToken(c), refreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+		return
+	}
 
-	// Set SameSite based on HTTPS
-	sameSite := "Lax"
-	if secure {
-		sameSite = "Strict"
+	h.clearSessionCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logout successful",
+	})
+}
+
+// LogoutEverywhere revokes every token of the current user, on all devices
+func (h *AuthHandler) LogoutEverywhere(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("User ID not found in context"))
+		return
+	}
+
+	if err := h.authService.RevokeAllSessions(c.Request.Context(), userID.(string)); err != nil {
+		c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+		return
 	}
 
-	// Clear auth cookie with proper security attributes
-	c.SetCookie("ecolink_token", "", -1, "/", "", secure, true)
+	h.clearSessionCookies(c)
 
-	// Set SameSite manually
-	existingCookie := c.Writer.Header().Get("Set-Cookie")
-	if existingCookie != "" {
-		c.Writer.Header().Set("Set-Cookie", existingCookie+"; SameSite="+sameSite)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "All sessions revoked",
+	})
+}
+
+// ListSessions returns the active logins of the current user. The one making
+// the request is flagged as current.
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("User ID not found in context"))
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+		return
+	}
+
+	currentID := c.GetString("session_id")
+	items := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, sessionResponse{Session: session, Current: session.ID == currentID})
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Logout successful",
+		"sessions": items,
 	})
 }
 
+// RevokeSession logs the current user out of one of their logins
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("User ID not found in context"))
+		return
+	}
+
+	sessionID := c.Param("id")
+	err := h.authService.RevokeSession(c.Request.Context(), userID.(string), sessionID)
+	switch {
+	case err == nil:
+	case stderrors.Is(err, database.ErrNotFound):
+		c.JSON(http.StatusNotFound, errors.NewNotFoundError("Session"))
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+		return
+	}
+
+	if sessionID == c.GetString("session_id") {
+		h.clearSessionCookies(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked",
+	})
+}
+
+// ListAuthEvents returns the latest logins and failed login attempts on the
+// current user's account
+func (h *AuthHandler) ListAuthEvents(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("User ID not found in context"))
+		return
+	}
+
+	events, err := h.authService.ListAuthEvents(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+		return
+	}
+	if events == nil {
+		events = []*domain.AuthEvent{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+	})
+}
+
+// sessionResponse is a session as listed to its owner
+type sessionResponse struct {
+	*domain.Session
+	Current bool `json:"current"`
+}
+
+// respondLocked answers a login refused during a lockout, telling the
+// client when to try again
+func respondLocked(c *gin.Context, locked *usecase.LoginLockedError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(locked.Until).Seconds()))))
+	c.JSON(http.StatusTooManyRequests, errors.NewRateLimitError())
+}
+
+// clientOf describes the client making the request, for its session record
+func clientOf(c *gin.Context) domain.Client {
+	return domain.Client{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
 // setAuthCookie sets a secure HTTP-only authentication cookie
 func (h *AuthHandler) setAuthCookie(c *gin.Context, token string, expiresAt time.Time) {
-	maxAge := int(time.Until(expiresAt).Seconds())
+	h.setSecureCookie(c, authCookie, token, "/", int(time.Until(expiresAt).Seconds()))
+}
+
+// setRefreshCookie sets the refresh token cookie. It is only sent to the
+// auth routes, never with ordinary API calls.
+func (h *AuthHandler) setRefreshCookie(c *gin.Context, token string, expiresAt time.Time) {
+	h.setSecureCookie(c, refreshCookie, token, refreshCookiePath, int(time.Until(expiresAt).Seconds()))
+}
 
+// clearSessionCookies removes the authentication and refresh cookies
+func (h *AuthHandler) clearSessionCookies(c *gin.Context) {
+	h.setSecureCookie(c, authCookie, "", "/", -1)
+	h.setSecureCookie(c, refreshCookie, "", refreshCookiePath, -1)
+}
+
+// setSecureCookie sets an HTTP-only cookie, Secure and SameSite=Strict over
+// HTTPS. A negative maxAge deletes the cookie.
+func (h *AuthHandler) setSecureCookie(c *gin.Context, name, value, path string, maxAge int) {
 	// Auto-detect HTTPS for secure flag
 	secure := c.Request.TLS != nil
 
-	// Set secure cookie with proper attributes
-	c.SetCookie(
-		"ecolink_token", // name
-		token,           // value
-		maxAge,          // maxAge
-		"/",             // path
-		"",              // domain (empty for same-origin)
-		secure,          // secure flag based on HTTPS
-		true,            // httpOnly
-	)
-
-	// Set SameSite attribute manually via header
-	sameSite := "Lax"
+	// Set SameSite attribute, stricter for HTTPS
+	sameSite := http.SameSiteLaxMode
 	if secure {
-		sameSite = "Strict" // Stricter for HTTPS
+		sameSite = http.SameSiteStrictMode
 	}
 
-	// Get existing Set-Cookie header and append SameSite
-	existingCookie := c.Writer.Header().Get("Set-Cookie")
-	if existingCookie != "" {
-		c.Writer.Header().Set("Set-Cookie", existingCookie+"; SameSite="+sameSite)
-	}
+	// Set the attribute through gin rather than by editing the Set-Cookie
+	// header, so several cookies can be set in one response
+	c.SetSameSite(sameSite)
+	c.SetCookie(
+		name,   // name
+		value,  // value
+		maxAge, // maxAge
+		path,   // path
+		"",     // domain (empty for same-origin)
+		secure, // secure flag based on HTTPS
+		true,   // httpOnly
+	)
 }
@@ -0,0 +1,211 @@
+package http
+
+import (
+	"ecolink-core/internal/auth/usecase"
+	"ecolink-core/internal/errors"
+	"ecolink-core/pkg/database"
+	stderrors "errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+// CompleteMFALogin finishes a login held back for its second factor,
+// exchanging the challenge and a code from the app, or a recovery code, for
+// the session cookies
+func (h *AuthHandler) CompleteMFALogin(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid request format"))
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	token, err := h.mfa.CompleteLogin(c.Request.Context(), req.MFAToken, req.Code, clientOf(c))
+	var locked *usecase.LoginLockedError
+	switch {
+	case err == nil:
+	case stderrors.As(err, &locked):
+		respondLocked(c, locked)
+		return
+	case stderrors.Is(err, usecase.ErrInvalidMFAChallenge):
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("Invalid or expired MFA challenge; log in again"))
+		return
+	case stderrors.Is(err, usecase.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("Invalid authentication code"))
+		return
+	case stderrors.Is(err, database.ErrUnavailable):
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, errors.NewInternalError())
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+		return
+	}
+
+	h.setAuthCookie(c, token.Token, token.ExpiresAt)
+	h.setRefreshCookie(c, token.RefreshToken, token.RefreshExpiresAt)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Login successful",
+		"expires_at": token.ExpiresAt,
+	})
+}
+
+// GetMFAStatus tells whether the current user has a second factor
+func (h *AuthHandler) GetMFAStatus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("User ID not found in context"))
+		return
+	}
+
+	status, err := h.mfa.Status(c.Request.Context(), userID.(string))
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// EnrollMFA starts setting up an authenticator app for the current user.
+// The response holds the secret as a QR code and as text; codes are only
+// asked for at login once ConfirmMFA checked one.
+func (h *AuthHandler) EnrollMFA(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("User ID not found in context"))
+		return
+	}
+
+	enrollment, err := h.mfa.Enroll(c.Request.Context(), userID.(string))
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmMFA enables the authenticator app being enrolled with one of its
+// codes. The recovery codes are in this response only.
+func (h *AuthHandler) ConfirmMFA(c *gin.Context) {
+	userID, code, ok := h.bindMFACode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.mfa.Confirm(c.Request.Context(), userID, code, clientOf(c))
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled; store your recovery codes now, they will not be shown again",
+		"recovery_codes": codes,
+	})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes. The
+// new ones are in this response only.
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, code, ok := h.bindMFACode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.mfa.RegenerateRecoveryCodes(c.Request.Context(), userID, code, clientOf(c))
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Recovery codes replaced; the previous ones no longer work",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableMFA removes the current user's second factor
+func (h *AuthHandler) DisableMFA(c *gin.Context) {
+	userID, code, ok := h.bindMFACode(c)
+	if !ok {
+		return
+	}
+
+	if err := h.mfa.Disable(c.Request.Context(), userID, code, clientOf(c)); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// bindMFACode reads the current user and the code of an MFA request,
+// answering the request itself if either is missing
+func (h *AuthHandler) bindMFACode(c *gin.Context) (string, string, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, errors.NewAuthError("User ID not found in context"))
+		return "", "", false
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid request format"))
+		return "", "", false
+	}
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewValidationError(err.Error()))
+		return "", "", false
+	}
+	return userID.(string), req.Code, true
+}
+
+// respondMFARequired answers a login that needs its second factor: no
+// cookies yet, only the challenge to send back with a code
+func (h *AuthHandler) respondMFARequired(c *gin.Context, challenge *usecase.MFARequiredError) {
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Enter a code from your authenticator app",
+		"mfa_required": true,
+		"mfa_token":    challenge.Token,
+		"expires_at":   challenge.ExpiresAt,
+	})
+}
+
+// respondMFAError answers a failed change to the current user's second
+// factor
+func respondMFAError(c *gin.Context, err error) {
+	var locked *usecase.LoginLockedError
+	switch {
+	case stderrors.As(err, &locked):
+		respondLocked(c, locked)
+	case stderrors.Is(err, usecase.ErrInvalidMFACode):
+		c.JSON(http.StatusBadRequest, errors.NewSecurityError("Invalid authentication code"))
+	case stderrors.Is(err, usecase.ErrMFAEnabled):
+		c.JSON(http.StatusConflict, errors.NewBusinessError("Two-factor authentication already enabled", "Disable it before setting up another app"))
+	case stderrors.Is(err, usecase.ErrMFANotEnabled):
+		c.JSON(http.StatusConflict, errors.NewBusinessError("Two-factor authentication not enabled", "Set up an authenticator app first"))
+	case stderrors.Is(err, database.ErrUnavailable):
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, errors.NewInternalError())
+	default:
+		c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+	}
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Scopes an API key can be granted
+const (
+	ScopeLinksRead  = "links:read"
+	ScopeLinksWrite = "links:write"
+	ScopeStatsRead  = "stats:read"
+)
+
+// Scopes lists every scope known to the API
+var Scopes = []string{ScopeLinksRead, ScopeLinksWrite, ScopeStatsRead}
+
+// APIKey is a personal key a user created for scripts and CI jobs. Only a
+// hash of the key is stored; the key itself is shown once, at creation.
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // Start of the key, to tell keys apart
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // Nil for keys that never expire
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// IsExpired reports whether the key can no longer be used at now
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+// LoginAttempts counts the recent failed logins under one subject: an
+// account's email or a client address
+type LoginAttempts struct {
+	Subject       string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time // Zero unless a lockout was imposed
+}
+
+// IsLocked reports whether logins under the subject are refused at now
+func (a *LoginAttempts) IsLocked(now time.Time) bool {
+	return now.Before(a.LockedUntil)
+}
+
+// Types of auth events
+const (
+	AuthEventLoginSucceeded = "login_succeeded"
+	AuthEventLoginFailed    = "login_failed"
+	AuthEventLoginLocked    = "login_locked" // A login refused during a lockout
+	AuthEventLockoutStarted = "lockout_started"
+
+	AuthEventEmailVerified          = "email_verified"
+	AuthEventPasswordResetRequested = "password_reset_requested"
+	AuthEventPasswordReset          = "password_reset"
+
+	AuthEventMFAEnabled               = "mfa_enabled"
+	AuthEventMFADisabled              = "mfa_disabled"
+	AuthEventRecoveryCodeUsed         = "recovery_code_used"
+	AuthEventRecoveryCodesRegenerated = "recovery_codes_regenerated"
+)
+
+// AuthEvent is an entry of the auth audit trail
+type AuthEvent struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	UserID    string    `json:"-"` // Empty for attempts on unknown emails
+	Email     string    `json:"-"`
+	IPPrefix  string    `json:"ip_prefix"` // Anonymized client IP, never the full address
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// Purposes of an email token
+const (
+	EmailTokenVerify        = "verify_email"
+	EmailTokenResetPassword = "reset_password"
+)
+
+// EmailToken is the server-side record of a single-use link sent by email,
+// to verify an address or to reset a password. Only a hash of the token is
+// stored.
+type EmailToken struct {
+	TokenHash string
+	UserID    string
+	Email     string // Address the token was sent to
+	Purpose   string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time // Zero until used
+}
+
+// IsUsed reports whether the token was already used or superseded
+func (t *EmailToken) IsUsed() bool {
+	return !t.UsedAt.IsZero()
+}
+
+// IsExpired reports whether the token can no longer be used at now
+func (t *EmailToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// MFA is a user's second factor: an authenticator app sharing a TOTP
+// secret with the server. The secret is only stored encrypted.
+type MFA struct {
+	UserID    string
+	Secret    []byte // Encrypted TOTP secret
+	CreatedAt time.Time
+	EnabledAt time.Time // Zero until a first code confirmed the app was set up
+	LastStep  int64     // TOTP time step of the last code accepted
+}
+
+// IsEnabled reports whether logins of the user ask for a code
+func (m *MFA) IsEnabled() bool {
+	return !m.EnabledAt.IsZero()
+}
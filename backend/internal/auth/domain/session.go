@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// Session is one login of a user on one device. It shares its ID with the
+// refresh token family of the login, and every access token of the login
+// carries that ID in its sid claim.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	IPPrefix   string    `json:"ip_prefix"` // Anonymized client IP, never the full address
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"` // Renewed with every refresh
+	RevokedAt  time.Time `json:"-"`
+}
+
+// IsActive reports whether the session can still be used at now
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt.IsZero() && now.Before(s.ExpiresAt)
+}
+
+// Client describes the device a request comes from
+type Client struct {
+	UserAgent string
+	IP        string
+}
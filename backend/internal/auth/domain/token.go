@@ -2,17 +2,51 @@ package domain
 
 import "time"
 
-// AuthToken represents a JWT authentication token
+// AuthToken represents a JWT authentication token, issued together with the
+// refresh token that renews it
 type AuthToken struct {
 	Token     string    `json:"token"`
 	ExpiresAt time.Time `json:"expires_at"`
 	UserID    string    `json:"user_id"`
+
+	RefreshToken     string    `json:"-"` // Only ever sent in an HTTP-only cookie
+	RefreshExpiresAt time.Time `json:"-"`
 }
 
 // TokenClaims represents the claims within a JWT token
 type TokenClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Exp    int64  `json:"exp"`
-	Iat    int64  `json:"iat"`
-}
\ No newline at end of file
+	ID        string `json:"jti"` // Unique per token, the key to revoke it
+	SessionID string `json:"sid"` // Session the token was issued for
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Exp       int64  `json:"exp"`
+	Iat       int64  `json:"iat"`
+}
+
+// RefreshToken is the server-side record of an opaque refresh token. Only a
+// hash of the token is stored. Every refresh replaces the token with a new
+// one of the same family, so presenting a used token again means it leaked.
+type RefreshToken struct {
+	TokenHash string
+	FamilyID  string // Shared by all the rotations of one login
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time // Zero until rotated
+	RevokedAt time.Time // Zero unless the family was revoked
+}
+
+// IsUsed reports whether the token was already exchanged for a new one
+func (t *RefreshToken) IsUsed() bool {
+	return !t.UsedAt.IsZero()
+}
+
+// IsRevoked reports whether the token's family was revoked
+func (t *RefreshToken) IsRevoked() bool {
+	return !t.RevokedAt.IsZero()
+}
+
+// IsExpired reports whether the token can no longer be used at now
+func (t *RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
@@ -10,6 +10,8 @@ type User struct {
 	Picture   string    `json:"picture,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// EmailVerifiedAt is nil until the user proved they own the address
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
 }
 
 // Credential holds local authentication data for a user
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+)
+
+// APIKeyRepository defines the persistence port for personal API keys,
+// looked up by the hash of their value
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *domain.APIKey) error
+	FindAPIKeyByHash(ctx context.Context, keyHash string) (*domain.APIKey, error)
+	// ListUserAPIKeys returns the keys of userID, newest first
+	ListUserAPIKeys(ctx context.Context, userID string) ([]*domain.APIKey, error)
+	TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error
+	// DeleteAPIKey removes a key of userID; keys of other users are
+	// reported as missing
+	DeleteAPIKey(ctx context.Context, userID, keyID string) error
+	// DeleteUserAPIKeys removes every key of userID
+	DeleteUserAPIKeys(ctx context.Context, userID string) error
+}
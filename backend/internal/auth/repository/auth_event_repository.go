@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+)
+
+// AuthEventRepository defines the persistence port for the auth audit trail
+type AuthEventRepository interface {
+	RecordAuthEvent(ctx context.Context, event *domain.AuthEvent) error
+	// ListUserAuthEvents returns the latest limit events of userID, newest
+	// first
+	ListUserAuthEvents(ctx context.Context, userID string, limit int) ([]*domain.AuthEvent, error)
+}
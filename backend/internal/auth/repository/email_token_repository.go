@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+)
+
+// EmailTokenRepository defines the persistence port for the single-use
+// tokens sent by email, looked up by the hash of their value
+type EmailTokenRepository interface {
+	CreateEmailToken(ctx context.Context, token *domain.EmailToken) error
+	// UseEmailToken marks the token with tokenHash and purpose used and
+	// returns it. Only one caller can use a token; the others get an error,
+	// and a token of another purpose is reported as missing.
+	UseEmailToken(ctx context.Context, tokenHash, purpose string, usedAt time.Time) (*domain.EmailToken, error)
+	// FindEmailToken returns the token with tokenHash and purpose without
+	// using it; a token of another purpose is reported as missing
+	FindEmailToken(ctx context.Context, tokenHash, purpose string) (*domain.EmailToken, error)
+	// ResetPasswordWithToken uses the password reset token with tokenHash
+	// and sets the password of its user in one step, so a failed write
+	// leaves the token unused. It fails like UseEmailToken.
+	ResetPasswordWithToken(ctx context.Context, tokenHash, passwordHash string, usedAt time.Time) error
+	// InvalidateEmailTokens marks every unused token of userID for purpose
+	// used, so links sent earlier stop working
+	InvalidateEmailTokens(ctx context.Context, userID, purpose string, usedAt time.Time) error
+}
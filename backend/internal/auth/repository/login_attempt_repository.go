@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+)
+
+// LoginAttemptRepository defines the persistence port for failed login
+// counters, kept per subject (an email or a client address)
+type LoginAttemptRepository interface {
+	// FindLoginAttempts returns the failures under subject, failing with a
+	// not found error if there are none
+	FindLoginAttempts(ctx context.Context, subject string) (*domain.LoginAttempts, error)
+	// RecordLoginFailure counts one failure under subject and returns the
+	// updated counter. The count starts over if the previous failure was
+	// before resetBefore; concurrent failures are all counted.
+	RecordLoginFailure(ctx context.Context, subject string, failedAt, resetBefore time.Time) (*domain.LoginAttempts, error)
+	LockLogin(ctx context.Context, subject string, until time.Time) error
+	// ClearLoginAttempts forgets the failures under subject
+	ClearLoginAttempts(ctx context.Context, subject string) error
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+)
+
+// MFARepository defines the persistence port for second factors: one
+// authenticator app per user and its recovery codes, looked up by hash
+type MFARepository interface {
+	FindMFA(ctx context.Context, userID string) (*domain.MFA, error)
+	// SaveMFA stores mfa in place of any earlier one of its user, whose
+	// recovery codes go with it
+	SaveMFA(ctx context.Context, mfa *domain.MFA) error
+	// EnableMFA marks the MFA of userID enabled, together with its first
+	// recovery codes
+	EnableMFA(ctx context.Context, userID string, enabledAt time.Time, codeHashes []string) error
+	// UseTOTPStep records that a code of step was accepted. It fails with
+	// a conflict unless step is later than the last one recorded, so each
+	// code works once.
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	// ReplaceRecoveryCodes swaps the recovery codes of userID for new ones
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+	// UseRecoveryCode spends a recovery code of userID. Only one caller can
+	// use a code; the others find it missing.
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) error
+	// CountRecoveryCodes returns how many recovery codes of userID are left
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+	// DeleteMFA removes the MFA of userID and its recovery codes
+	DeleteMFA(ctx context.Context, userID string) error
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+)
+
+// RefreshTokenRepository defines the persistence port for refresh tokens,
+// looked up by the hash of their value
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error
+	FindRefreshToken(ctx context.Context, tokenHash string) (*domain.RefreshToken, error)
+	// UseRefreshToken marks a token as rotated. Only one caller can use a
+	// token; the others get an error, so concurrent refreshes cannot both
+	// succeed.
+	UseRefreshToken(ctx context.Context, tokenHash string, usedAt time.Time) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string, revokedAt time.Time) error
+	// RevokeUserRefreshTokens revokes every refresh token of userID
+	RevokeUserRefreshTokens(ctx context.Context, userID string, revokedAt time.Time) error
+}
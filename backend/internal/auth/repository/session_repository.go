@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+)
+
+// SessionRepository defines the persistence port for the logins of users
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *domain.Session) error
+	FindSession(ctx context.Context, sessionID string) (*domain.Session, error)
+	// ListUserSessions returns the sessions of userID active at now, most
+	// recently seen first
+	ListUserSessions(ctx context.Context, userID string, now time.Time) ([]*domain.Session, error)
+	// UpdateSession stores the client, last-seen time and expiry of a
+	// session after a refresh
+	UpdateSession(ctx context.Context, session *domain.Session) error
+	// TouchSession only moves the last-seen time, on authenticated requests
+	TouchSession(ctx context.Context, sessionID string, seenAt time.Time) error
+	RevokeSession(ctx context.Context, sessionID string, revokedAt time.Time) error
+	RevokeUserSessions(ctx context.Context, userID string, revokedAt time.Time) error
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// TokenRevocationRepository defines the persistence port for access tokens
+// revoked one by one, through their jti, before they expire. Revoking all
+// the tokens of a user revokes their sessions instead.
+type TokenRevocationRepository interface {
+	// RevokeToken revokes the token with the given jti. The record is only
+	// needed until expiresAt, when the token stops validating anyway.
+	RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// TokenRepository stores everything the auth service tracks about issued
+// tokens and the sessions they belong to
+type TokenRepository interface {
+	RefreshTokenRepository
+	TokenRevocationRepository
+	SessionRepository
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"ecolink-core/internal/auth/domain"
+	"time"
 )
 
 // UserRepository defines the persistence port for user-related data
@@ -13,4 +14,10 @@ type UserRepository interface {
 	CreateUser(ctx context.Context, user *domain.User, cred *domain.Credential) error
 	CreateUserFromSocial(ctx context.Context, user *domain.User, social *domain.SocialProfile) (*domain.User, error)
 	UpdateUser(ctx context.Context, user *domain.User) error
+	// UpdatePassword sets the password of userID, adding local credentials
+	// to an account that had none
+	UpdatePassword(ctx context.Context, userID, passwordHash string) error
+	// MarkEmailVerified records that userID owns their email; an address
+	// verified earlier keeps its original time
+	MarkEmailVerified(ctx context.Context, userID string, verifiedAt time.Time) error
 }
\ No newline at end of file
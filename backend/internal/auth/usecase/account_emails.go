@@ -0,0 +1,274 @@
+package usecase
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"ecolink-core/internal/auth/repository"
+	"ecolink-core/pkg/database"
+	"ecolink-core/pkg/mailer"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Lifetimes of the links sent by email
+const (
+	VerificationTokenTTL  = 48 * time.Hour
+	PasswordResetTokenTTL = time.Hour
+)
+
+// mailTimeout bounds the delivery of an email sent after the response
+const mailTimeout = 30 * time.Second
+
+// ErrInvalidEmailToken is returned for an unknown, expired or already used
+// link sent by email
+var ErrInvalidEmailToken = errors.New("invalid or expired token")
+
+// AccountEmailService runs the flows driven by links sent by email:
+// verifying the address of a new account and resetting a forgotten
+// password. Each link carries a single-use token of which only a hash is
+// stored.
+type AccountEmailService struct {
+	auth        *AuthService
+	tokens      repository.EmailTokenRepository
+	keys        repository.APIKeyRepository
+	mailer      mailer.Mailer
+	frontendURL string
+}
+
+func NewAccountEmailService(auth *AuthService, tokens repository.EmailTokenRepository, keys repository.APIKeyRepository, mailer mailer.Mailer, frontendURL string) *AccountEmailService {
+	return &AccountEmailService{
+		auth:        auth,
+		tokens:      tokens,
+		keys:        keys,
+		mailer:      mailer,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// SendVerification emails user a link to verify their address. Links sent
+// earlier keep working until they expire. Verified users get nothing.
+func (s *AccountEmailService) SendVerification(ctx context.Context, user *domain.User) error {
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	token, err := s.issue(ctx, user, domain.EmailTokenVerify, VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your EcoLink email",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm that this is your email address by opening the link below:\n\n%s\n\n"+
+			"The link expires in %s. If you did not create an EcoLink account, ignore this email.\n",
+			user.Name, s.link("/verify-email", token), formatTTL(VerificationTokenTTL)),
+	})
+}
+
+// VerifyEmail marks the address a verification link was sent to as
+// verified and returns its user
+func (s *AccountEmailService) VerifyEmail(ctx context.Context, token string, client domain.Client) (*domain.User, error) {
+	user, err := s.use(ctx, token, domain.EmailTokenVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if err := s.auth.UserRepo.MarkEmailVerified(ctx, user.ID, now); err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+	if err := s.tokens.InvalidateEmailTokens(ctx, user.ID, domain.EmailTokenVerify, now); err != nil {
+		log.Printf("⚠️  Failed to invalidate verification links: %v", err)
+	}
+	s.auth.guard.record(ctx, domain.AuthEventEmailVerified, user.Email, user.ID, client)
+
+	return s.auth.UserRepo.FindByID(ctx, user.ID)
+}
+
+// RequestPasswordReset emails a password reset link to the account with
+// email. It answers alike whether or not such an account exists, and
+// accounts without a password, which sign in through their provider, get
+// nothing. Only a failure to look the email up is returned; the request is
+// recorded and the email sent after returning, since how long that takes
+// would tell registered addresses apart.
+func (s *AccountEmailService) RequestPasswordReset(ctx context.Context, email string, client domain.Client) error {
+	user, credential, err := s.auth.UserRepo.FindByEmail(ctx, email)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up user: %w", err)
+	}
+	if credential == nil {
+		return nil
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mailTimeout)
+		defer cancel()
+
+		s.auth.guard.record(ctx, domain.AuthEventPasswordResetRequested, user.Email, user.ID, client)
+		token, err := s.issue(ctx, user, domain.EmailTokenResetPassword, PasswordResetTokenTTL)
+		if err != nil {
+			log.Printf("⚠️  Failed to issue password reset link: %v", err)
+			return
+		}
+		s.send(ctx, mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your EcoLink password",
+			Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your EcoLink account. "+
+				"To choose a new one, open the link below:\n\n%s\n\n"+
+				"The link expires in %s and works once. If you did not ask for it, ignore this email; "+
+				"your password stays unchanged.\n",
+				user.Name, s.link("/reset-password", token), formatTTL(PasswordResetTokenTTL)),
+		})
+	}()
+	return nil
+}
+
+// ResetPassword sets a new password for the account a reset link was sent
+// to. Every other reset link of the account stops working, all its
+// sessions are logged out, its API keys are deleted and a lockout of its
+// email is lifted. Opening
+// the link also proves the user owns the address.
+func (s *AccountEmailService) ResetPassword(ctx context.Context, token, password string, client domain.Client) error {
+	now := time.Now().UTC()
+	tokenHash := hashOpaqueToken(token)
+	stored, err := s.tokens.FindEmailToken(ctx, tokenHash, domain.EmailTokenResetPassword)
+	if errors.Is(err, database.ErrNotFound) {
+		return ErrInvalidEmailToken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up token: %w", err)
+	}
+	if stored.IsUsed() {
+		return ErrInvalidEmailToken
+	}
+	user, err := s.tokenUser(ctx, stored, now)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	// The token is only spent together with the new password, so a failed
+	// write leaves the link working
+	err = s.tokens.ResetPasswordWithToken(ctx, tokenHash, string(hashedPassword), now)
+	if errors.Is(err, database.ErrNotFound) || errors.Is(err, database.ErrConflict) {
+		return ErrInvalidEmailToken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err := s.tokens.InvalidateEmailTokens(ctx, user.ID, domain.EmailTokenResetPassword, now); err != nil {
+		log.Printf("⚠️  Failed to invalidate password reset links: %v", err)
+	}
+	if err := s.auth.UserRepo.MarkEmailVerified(ctx, user.ID, now); err != nil {
+		log.Printf("⚠️  Failed to verify email: %v", err)
+	}
+	if err := s.auth.RevokeAllSessions(ctx, user.ID); err != nil {
+		return err
+	}
+	// Whoever knew the old password may have made keys of their own
+	if err := s.keys.DeleteUserAPIKeys(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete API keys: %w", err)
+	}
+	s.auth.guard.PasswordReset(ctx, user.Email, user.ID, client)
+
+	// Tell the owner, in case it was not them
+	s.send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your EcoLink password was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe password of your EcoLink account was just reset, all your sessions "+
+			"were logged out and your API keys deleted. If this was not you, reset your password again right away.\n", user.Name),
+	})
+	return nil
+}
+
+// issue stores a new email token of purpose for user and returns its value
+func (s *AccountEmailService) issue(ctx context.Context, user *domain.User, purpose string, ttl time.Duration) (string, error) {
+	value, err := generateOpaqueToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	token := &domain.EmailToken{
+		TokenHash: hashOpaqueToken(value),
+		UserID:    user.ID,
+		Email:     user.Email,
+		Purpose:   purpose,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := s.tokens.CreateEmailToken(ctx, token); err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+	return value, nil
+}
+
+// use spends an email token of purpose and returns the user it was sent
+// to. Tokens sent to an address the user no longer has are refused.
+func (s *AccountEmailService) use(ctx context.Context, value, purpose string) (*domain.User, error) {
+	now := time.Now().UTC()
+	token, err := s.tokens.UseEmailToken(ctx, hashOpaqueToken(value), purpose, now)
+	if errors.Is(err, database.ErrNotFound) || errors.Is(err, database.ErrConflict) {
+		return nil, ErrInvalidEmailToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to use token: %w", err)
+	}
+	return s.tokenUser(ctx, token, now)
+}
+
+// tokenUser returns the user an unexpired email token was sent to, refusing
+// tokens sent to an address the user no longer has
+func (s *AccountEmailService) tokenUser(ctx context.Context, token *domain.EmailToken, now time.Time) (*domain.User, error) {
+	if token.IsExpired(now) {
+		return nil, ErrInvalidEmailToken
+	}
+
+	user, err := s.auth.UserRepo.FindByID(ctx, token.UserID)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidEmailToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if !strings.EqualFold(user.Email, token.Email) {
+		return nil, ErrInvalidEmailToken
+	}
+	return user, nil
+}
+
+// send delivers msg, logging a failure as well as returning it
+func (s *AccountEmailService) send(ctx context.Context, msg mailer.Message) error {
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("⚠️  Failed to send email %q: %v", msg.Subject, err)
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// link is the frontend page at path, handed token
+func (s *AccountEmailService) link(path, token string) string {
+	return s.frontendURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// formatTTL words a token lifetime for an email, in hours
+func formatTTL(ttl time.Duration) string {
+	if hours := int(ttl.Hours()); hours != 1 {
+		return fmt.Sprintf("%d hours", hours)
+	}
+	return "1 hour"
+}
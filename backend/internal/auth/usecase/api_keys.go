@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"ecolink-core/internal/auth/repository"
+	"ecolink-core/pkg/database"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// APIKeyPrefix starts every API key, telling keys from JWTs in an
+	// Authorization header and making leaked keys easy to scan for
+	APIKeyPrefix = "eck_"
+	// apiKeyTouchInterval limits how often a key's last use is written
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	// ErrInvalidAPIKey is returned for unknown and expired API keys
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrUnknownScope is returned when creating a key with a scope the API
+	// does not define
+	ErrUnknownScope = errors.New("unknown scope")
+	// ErrExpiryInPast is returned when creating a key that is already expired
+	ErrExpiryInPast = errors.New("expiry is in the past")
+)
+
+// APIKeyService manages the personal API keys scripts and CI jobs use in
+// place of a login
+type APIKeyService struct {
+	keys repository.APIKeyRepository
+}
+
+func NewAPIKeyService(keys repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{keys: keys}
+}
+
+// IsAPIKey reports whether a bearer token is an API key rather than a JWT
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// CreateKey issues a key for userID with the given scopes, expiring at
+// expiresAt unless it is nil. The key is returned once, next to its record;
+// only its hash is stored.
+func (s *APIKeyService) CreateKey(ctx context.Context, userID, name string, scopes []string, expiresAt *time.Time) (*domain.APIKey, string, error) {
+	for _, scope := range scopes {
+		if !slices.Contains(domain.Scopes, scope) {
+			return nil, "", fmt.Errorf("%w: %s", ErrUnknownScope, scope)
+		}
+	}
+	now := time.Now().UTC()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, "", ErrExpiryInPast
+	}
+
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	value := APIKeyPrefix + secret
+
+	key := &domain.APIKey{
+		ID:        generateSecureID(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    value[:len(APIKeyPrefix)+6],
+		KeyHash:   hashOpaqueToken(value),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.keys.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("failed to store API key: %w", err)
+	}
+	return key, value, nil
+}
+
+// ListKeys returns the keys of userID, newest first
+func (s *APIKeyService) ListKeys(ctx context.Context, userID string) ([]*domain.APIKey, error) {
+	keys, err := s.keys.ListUserAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// DeleteKey revokes a key of userID. Keys of other users are reported as
+// missing.
+func (s *APIKeyService) DeleteKey(ctx context.Context, userID, keyID string) error {
+	return s.keys.DeleteAPIKey(ctx, userID, keyID)
+}
+
+// Authenticate resolves a key presented by a client and records its use
+func (s *APIKeyService) Authenticate(ctx context.Context, value string) (*domain.APIKey, error) {
+	key, err := s.keys.FindAPIKeyByHash(ctx, hashOpaqueToken(value))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if key.IsExpired(now) {
+		return nil, ErrInvalidAPIKey
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.keys.TouchAPIKey(ctx, key.ID, now); err != nil {
+			log.Printf("⚠️  Failed to record API key use: %v", err)
+		}
+	}
+	return key, nil
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"ecolink-core/internal/auth/domain"
 	"ecolink-core/internal/auth/repository"
+	"ecolink-core/pkg/database"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -14,16 +15,35 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthService struct {
 	UserRepo     repository.UserRepository
+	tokens       repository.TokenRepository
+	mfa          repository.MFARepository
+	guard        *LoginGuard
 	tokenService TokenService
 	googleConfig GoogleConfig
+	refreshTTL   time.Duration
 }
 
+// ErrInvalidCredentials is returned for every failed password login,
+// whatever the reason
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// authEventsListed is how many auth events a user sees
+const authEventsListed = 50
+
+// dummyPasswordHash is compared against when there is no password to check
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("no password"), bcrypt.DefaultCost)
+	return hash
+})
+
 type GoogleConfig struct {
 	ClientID     string
 	ClientSecret string
@@ -44,11 +64,15 @@ type GoogleUserInfo struct {
 	Picture string `json:"picture"`
 }
 
-func NewAuthService(userRepo repository.UserRepository, tokenService TokenService, googleConfig GoogleConfig) *AuthService {
+func NewAuthService(userRepo repository.UserRepository, tokens repository.TokenRepository, mfa repository.MFARepository, guard *LoginGuard, tokenService TokenService, googleConfig GoogleConfig, refreshTTL time.Duration) *AuthService {
 	return &AuthService{
 		UserRepo:     userRepo,
+		tokens:       tokens,
+		mfa:          mfa,
+		guard:        guard,
 		tokenService: tokenService,
 		googleConfig: googleConfig,
+		refreshTTL:   refreshTTL,
 	}
 }
 
@@ -59,6 +83,9 @@ func (s *AuthService) Register(ctx context.Context, name, email, password string
 	if err == nil && existingUser != nil {
 		return nil, errors.New("user already exists")
 	}
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
 
 	// Hash password using bcrypt (fixes MD5 vulnerability)
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -68,7 +95,7 @@ func (s *AuthService) Register(ctx context.Context, name, email, password string
 
 	// Create user and credential entities
 	user := &domain.User{
-		ID:    s.generateSecureID(),
+		ID:    generateSecureID(),
 		Email: email,
 		Name:  name,
 	}
@@ -86,26 +113,75 @@ func (s *AuthService) Register(ctx context.Context, name, email, password string
 	return user, nil
 }
 
-// Login validates credentials and generates a token
-func (s *AuthService) Login(ctx context.Context, email, password string) (*domain.AuthToken, error) {
+// Login validates credentials and generates an access and a refresh token.
+// Unknown emails, social-only accounts and wrong passwords all fail alike
+// with ErrInvalidCredentials, taking as long, and repeated failures lock
+// the email and the client address out with a LoginLockedError. Accounts
+// with a second factor get an MFARequiredError holding the challenge to
+// complete with MFAService.CompleteLogin instead of tokens.
+func (s *AuthService) Login(ctx context.Context, email, password string, client domain.Client) (*domain.AuthToken, error) {
 	// Retrieve user and credentials
 	user, credential, err := s.UserRepo.FindByEmail(ctx, email)
-	if err != nil {
-		return nil, errors.New("invalid credentials")
+	if errors.Is(err, database.ErrUnavailable) {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	var userID string
+	if err == nil {
+		userID = user.ID
+	}
+
+	if err := s.guard.Check(ctx, email, userID, client); err != nil {
+		return nil, err
+	}
+
+	// Accounts created through a social login have no password; compare
+	// against a dummy hash so they answer as slowly as the others
+	hasPassword := err == nil && credential != nil
+	passwordHash := dummyPasswordHash()
+	if hasPassword {
+		passwordHash = []byte(credential.PasswordHash)
 	}
 
 	// Verify password using bcrypt
-	if err := bcrypt.CompareHashAndPassword([]byte(credential.PasswordHash), []byte(password)); err != nil {
-		return nil, errors.New("invalid credentials")
+	if bcrypt.CompareHashAndPassword(passwordHash, []byte(password)) != nil || !hasPassword {
+		s.guard.Fail(ctx, email, userID, client)
+		return nil, ErrInvalidCredentials
 	}
 
-	// Generate JWT token
-	token, err := s.tokenService.GenerateToken(user.ID, user.Email)
+	// The failures of the email are only forgotten once the second factor
+	// checked out too
+	if err := s.challengeMFA(ctx, user); err != nil {
+		return nil, err
+	}
+	s.guard.Succeed(ctx, email, userID, client)
+
+	// Generate JWT token and start a session
+	return s.issueTokens(ctx, user, nil, client)
+}
+
+// challengeMFA returns an MFARequiredError if user has a second factor
+func (s *AuthService) challengeMFA(ctx context.Context, user *domain.User) error {
+	mfa, err := s.mfa.FindMFA(ctx, user.ID)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up MFA: %w", err)
+	}
+	if !mfa.IsEnabled() {
+		return nil
+	}
+
+	token, expiresAt, err := s.tokenService.GenerateMFAChallenge(user.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return fmt.Errorf("failed to generate MFA challenge: %w", err)
 	}
+	return &MFARequiredError{Token: token, ExpiresAt: expiresAt}
+}
 
-	return token, nil
+// ListAuthEvents returns the latest entries of a user's auth audit trail
+func (s *AuthService) ListAuthEvents(ctx context.Context, userID string) ([]*domain.AuthEvent, error) {
+	return s.guard.ListEvents(ctx, userID, authEventsListed)
 }
 
 // HandleGoogleLogin initiates Google OAuth flow
@@ -123,8 +199,9 @@ func (s *AuthService) HandleGoogleLogin(ctx context.Context) (redirectURL string
 	return redirectURL, state
 }
 
-// HandleGoogleCallback processes Google OAuth callback
-func (s *AuthService) HandleGoogleCallback(ctx context.Context, code, state string) (*domain.AuthToken, error) {
+// HandleGoogleCallback processes Google OAuth callback. Like Login, it
+// returns an MFARequiredError for accounts with a second factor.
+func (s *AuthService) HandleGoogleCallback(ctx context.Context, code, state string, client domain.Client) (*domain.AuthToken, error) {
 	// Exchange code for token
 	tokenResp, err := s.exchangeCodeForToken(code)
 	if err != nil {
@@ -139,13 +216,18 @@ func (s *AuthService) HandleGoogleCallback(ctx context.Context, code, state stri
 
 	// Find or create user
 	user, err := s.UserRepo.FindByProviderID(ctx, "google", userInfo.ID)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
 	if err != nil {
-		// Create new user from social profile
+		// Create new user from social profile; Google verified the email
+		verifiedAt := time.Now().UTC()
 		newUser := &domain.User{
-			ID:      s.generateSecureID(),
-			Email:   userInfo.Email,
-			Name:    userInfo.Name,
-			Picture: userInfo.Picture,
+			ID:              generateSecureID(),
+			Email:           userInfo.Email,
+			Name:            userInfo.Name,
+			Picture:         userInfo.Picture,
+			EmailVerifiedAt: &verifiedAt,
 		}
 
 		socialProfile := &domain.SocialProfile{
@@ -160,13 +242,12 @@ func (s *AuthService) HandleGoogleCallback(ctx context.Context, code, state stri
 		}
 	}
 
-	// Generate application JWT token
-	token, err := s.tokenService.GenerateToken(user.ID, user.Email)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+	if err := s.challengeMFA(ctx, user); err != nil {
+		return nil, err
 	}
 
-	return token, nil
+	// Generate application JWT token and start a session
+	return s.issueTokens(ctx, user, nil, client)
 }
 
 func (s *AuthService) exchangeCodeForToken(code string) (*GoogleTokenResponse, error) {
@@ -235,7 +316,7 @@ func (s *AuthService) getUserInfo(accessToken string) (*GoogleUserInfo, error) {
 }
 
 // generateSecureID creates a cryptographically secure ID (fixes MD5 vulnerability)
-func (s *AuthService) generateSecureID() string {
+func generateSecureID() string {
 	bytes := make([]byte, 16)
 	rand.Read(bytes)
 	hash := sha256.Sum256(bytes)
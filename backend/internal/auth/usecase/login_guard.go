@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"ecolink-core/internal/auth/repository"
+	"ecolink-core/pkg/database"
+	"ecolink-core/pkg/utils"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ErrLoginLocked matches the error of a login refused during a lockout
+var ErrLoginLocked = errors.New("too many failed login attempts")
+
+// LoginLockedError is returned for logins refused during a lockout
+type LoginLockedError struct {
+	Until time.Time
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrLoginLocked.Error()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ErrLoginLocked
+}
+
+// LockoutPolicy decides when failed logins lock an account or a client
+// address out. Each failure from the threshold on locks it for twice as
+// long as the previous one, from Lockout up to MaxLockout.
+type LockoutPolicy struct {
+	MaxFailures      int // Per account, counted for unknown emails too
+	MaxFailuresPerIP int
+	Lockout          time.Duration
+	MaxLockout       time.Duration
+	// Window is how long failures are remembered after the last one
+	Window time.Duration
+}
+
+// DefaultLockoutPolicy locks an account after 5 failures and an address
+// after 20, for 1 minute doubling up to an hour
+var DefaultLockoutPolicy = LockoutPolicy{
+	MaxFailures:      5,
+	MaxFailuresPerIP: 20,
+	Lockout:          time.Minute,
+	MaxLockout:       time.Hour,
+	Window:           24 * time.Hour,
+}
+
+// LoginGuard throttles password guessing and keeps the auth audit trail.
+// Failures are tracked per email whether or not an account has it, so a
+// lockout tells nothing about which emails are registered.
+type LoginGuard struct {
+	attempts repository.LoginAttemptRepository
+	events   repository.AuthEventRepository
+	policy   LockoutPolicy
+}
+
+func NewLoginGuard(attempts repository.LoginAttemptRepository, events repository.AuthEventRepository, policy LockoutPolicy) *LoginGuard {
+	return &LoginGuard{
+		attempts: attempts,
+		events:   events,
+		policy:   policy,
+	}
+}
+
+// Check refuses a login for email from client while either is locked out.
+// userID is that of the account with email, if any.
+func (g *LoginGuard) Check(ctx context.Context, email, userID string, client domain.Client) error {
+	now := time.Now().UTC()
+	var lockedUntil time.Time
+	for _, subject := range g.subjects(email, client) {
+		attempts, err := g.attempts.FindLoginAttempts(ctx, subject)
+		if errors.Is(err, database.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check login attempts: %w", err)
+		}
+		if attempts.IsLocked(now) && attempts.LockedUntil.After(lockedUntil) {
+			lockedUntil = attempts.LockedUntil
+		}
+	}
+
+	if lockedUntil.IsZero() {
+		return nil
+	}
+	g.record(ctx, domain.AuthEventLoginLocked, email, userID, client)
+	return &LoginLockedError{Until: lockedUntil}
+}
+
+// Fail counts a failed login, locking the email or the client address out
+// once they reach their threshold
+func (g *LoginGuard) Fail(ctx context.Context, email, userID string, client domain.Client) {
+	now := time.Now().UTC()
+	g.record(ctx, domain.AuthEventLoginFailed, email, userID, client)
+
+	for _, subject := range g.subjects(email, client) {
+		attempts, err := g.attempts.RecordLoginFailure(ctx, subject, now, now.Add(-g.policy.Window))
+		if err != nil {
+			log.Printf("⚠️  Failed to record login failure: %v", err)
+			continue
+		}
+
+		threshold := g.policy.MaxFailures
+		if strings.HasPrefix(subject, "ip:") {
+			threshold = g.policy.MaxFailuresPerIP
+		}
+		if attempts.Failures < threshold {
+			continue
+		}
+
+		if err := g.attempts.LockLogin(ctx, subject, now.Add(g.lockout(attempts.Failures-threshold))); err != nil {
+			log.Printf("⚠️  Failed to lock login: %v", err)
+			continue
+		}
+		kind, _, _ := strings.Cut(subject, ":")
+		log.Printf("⚠️  Login locked for an %s after %d failures", kind, attempts.Failures)
+		g.record(ctx, domain.AuthEventLockoutStarted, email, userID, client)
+	}
+}
+
+// Succeed records a successful login and forgets the failures of its
+// account. Those of the client address stay, or an attacker could reset
+// them by logging into an account of their own.
+func (g *LoginGuard) Succeed(ctx context.Context, email, userID string, client domain.Client) {
+	g.record(ctx, domain.AuthEventLoginSucceeded, email, userID, client)
+	if err := g.attempts.ClearLoginAttempts(ctx, emailSubject(email)); err != nil {
+		log.Printf("⚠️  Failed to clear login attempts: %v", err)
+	}
+}
+
+// PasswordReset records a password reset and lifts any lockout of its
+// account, whose owner just proved they hold its email
+func (g *LoginGuard) PasswordReset(ctx context.Context, email, userID string, client domain.Client) {
+	g.record(ctx, domain.AuthEventPasswordReset, email, userID, client)
+	if err := g.attempts.ClearLoginAttempts(ctx, emailSubject(email)); err != nil {
+		log.Printf("⚠️  Failed to clear login attempts: %v", err)
+	}
+}
+
+// ListEvents returns the latest auth events of a user, newest first
+func (g *LoginGuard) ListEvents(ctx context.Context, userID string, limit int) ([]*domain.AuthEvent, error) {
+	events, err := g.events.ListUserAuthEvents(ctx, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list auth events: %w", err)
+	}
+	return events, nil
+}
+
+// lockout is the lockout imposed for the nth failure past the threshold,
+// counting from zero
+func (g *LoginGuard) lockout(n int) time.Duration {
+	lockout := g.policy.Lockout
+	for i := 0; i < n && lockout < g.policy.MaxLockout; i++ {
+		lockout *= 2
+	}
+	return min(lockout, g.policy.MaxLockout)
+}
+
+// subjects are the counters a login attempt counts against
+func (g *LoginGuard) subjects(email string, client domain.Client) []string {
+	subjects := []string{emailSubject(email)}
+	if client.IP != "" {
+		subjects = append(subjects, "ip:"+client.IP)
+	}
+	return subjects
+}
+
+// record appends to the audit trail; a failure to do so is logged and
+// does not block the login or account change
+func (g *LoginGuard) record(ctx context.Context, eventType, email, userID string, client domain.Client) {
+	event := &domain.AuthEvent{
+		ID:        generateSecureID(),
+		Type:      eventType,
+		UserID:    userID,
+		Email:     email,
+		IPPrefix:  utils.AnonymizeIP(client.IP),
+		UserAgent: client.UserAgent,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := g.events.RecordAuthEvent(ctx, event); err != nil {
+		log.Printf("⚠️  Failed to record auth event %s: %v", eventType, err)
+	}
+}
+
+// emailSubject is the counter of an email, case-insensitive so variants of
+// one address share it
+func emailSubject(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
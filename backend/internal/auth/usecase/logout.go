@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Logout ends one login: the access token is revoked by its jti until it
+// expires, and its session and refresh token family are revoked. Either
+// token may be empty, and invalid or already revoked tokens are ignored so
+// logging out twice is harmless.
+func (s *AuthService) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	if accessToken != "" {
+		if claims, err := s.tokenService.ValidateToken(accessToken); err == nil {
+			if err := s.tokens.RevokeToken(ctx, claims.ID, claims.UserID, time.Unix(claims.Exp, 0)); err != nil {
+				return fmt.Errorf("failed to revoke token: %w", err)
+			}
+			if err := s.endSession(ctx, claims.SessionID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if refreshToken != "" {
+		return s.RevokeRefreshToken(ctx, refreshToken)
+	}
+	return nil
+}
+
+// RevokeAllSessions logs a user out everywhere: every session and refresh
+// token is revoked, so every access token issued so far stops validating
+// with its session. Logins that follow get new sessions and are unaffected.
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID string) error {
+	now := time.Now().UTC()
+	if err := s.tokens.RevokeUserSessions(ctx, userID, now); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := s.tokens.RevokeUserRefreshTokens(ctx, userID, now); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,456 @@
+package usecase
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"ecolink-core/internal/auth/domain"
+	"ecolink-core/internal/auth/repository"
+	"ecolink-core/pkg/database"
+	"ecolink-core/pkg/totp"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	// RecoveryCodeCount is how many recovery codes a user gets at a time
+	RecoveryCodeCount = 10
+	// recoveryCodeLength is the length of a recovery code without its
+	// dashes: 16 base32 characters, 80 random bits
+	recoveryCodeLength = 16
+	// totpSkew is how many time steps a code may be early or late
+	totpSkew = 1
+)
+
+var (
+	// ErrMFARequired matches the error of a login waiting for its second
+	// factor
+	ErrMFARequired = errors.New("two-factor authentication required")
+	// ErrMFANotEnabled is returned when confirming, using or disabling a
+	// second factor the user has not set up
+	ErrMFANotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrMFAEnabled is returned when enrolling a user who already has a
+	// second factor
+	ErrMFAEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrInvalidMFACode is returned for a wrong, reused or expired code
+	ErrInvalidMFACode = errors.New("invalid authentication code")
+	// ErrInvalidMFAChallenge is returned for an unknown or expired login
+	// challenge
+	ErrInvalidMFAChallenge = errors.New("invalid or expired MFA challenge")
+)
+
+// MFARequiredError is returned for logins of accounts with a second
+// factor, in place of their tokens
+type MFARequiredError struct {
+	Token     string // Challenge to complete with a code
+	ExpiresAt time.Time
+}
+
+func (e *MFARequiredError) Error() string {
+	return ErrMFARequired.Error()
+}
+
+func (e *MFARequiredError) Unwrap() error {
+	return ErrMFARequired
+}
+
+// MFAConfig sets up second factors
+type MFAConfig struct {
+	Issuer string // Names the account in authenticator apps
+	// EncryptionKey is the AES-256 key TOTP secrets are stored under
+	EncryptionKey []byte
+	// RequiredDomains are the email domains whose users must enable a
+	// second factor to manage links
+	RequiredDomains []string
+}
+
+// MFAStatus describes the second factor of a user
+type MFAStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	Required          bool       `json:"required"` // Links can only be managed once enabled
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// MFAEnrollment is what an authenticator app needs to be set up
+type MFAEnrollment struct {
+	Secret string `json:"secret"` // Base32, to type into the app by hand
+	URI    string `json:"uri"`
+	QRCode string `json:"qr_code"` // Base64 PNG of URI
+}
+
+// MFAService manages two-factor authentication with an RFC 6238
+// authenticator app, backed by single-use recovery codes, and completes
+// the logins it holds back. TOTP secrets are stored encrypted and recovery
+// codes as hashes. Wrong codes count as failed logins, so guessing them
+// locks the account out like guessing its password.
+type MFAService struct {
+	auth            *AuthService
+	store           repository.MFARepository
+	issuer          string
+	key             []byte
+	requiredDomains []string
+}
+
+func NewMFAService(auth *AuthService, store repository.MFARepository, cfg MFAConfig) *MFAService {
+	domains := make([]string, 0, len(cfg.RequiredDomains))
+	for _, host := range cfg.RequiredDomains {
+		domains = append(domains, strings.ToLower(strings.TrimPrefix(strings.TrimSpace(host), "@")))
+	}
+
+	return &MFAService{
+		auth:            auth,
+		store:           store,
+		issuer:          cfg.Issuer,
+		key:             cfg.EncryptionKey,
+		requiredDomains: domains,
+	}
+}
+
+// Status returns the second factor status of a user
+func (s *MFAService) Status(ctx context.Context, userID string) (*MFAStatus, error) {
+	user, err := s.auth.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	status := &MFAStatus{Required: s.Required(user)}
+
+	mfa, err := s.find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil || !mfa.IsEnabled() {
+		return status, nil
+	}
+	status.Enabled = true
+	status.EnabledAt = &mfa.EnabledAt
+
+	if status.RecoveryCodesLeft, err = s.store.CountRecoveryCodes(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return status, nil
+}
+
+// Enroll starts setting up an authenticator app for a user, replacing an
+// enrollment left unconfirmed. Logins ask for codes once Confirm checked
+// the app works.
+func (s *MFAService) Enroll(ctx context.Context, userID string) (*MFAEnrollment, error) {
+	user, err := s.auth.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	mfa, err := s.find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa != nil && mfa.IsEnabled() {
+		return nil, ErrMFAEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	sealed, err := s.seal(userID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SaveMFA(ctx, &domain.MFA{UserID: userID, Secret: sealed, CreatedAt: time.Now().UTC()}); err != nil {
+		return nil, fmt.Errorf("failed to store MFA: %w", err)
+	}
+
+	uri := totp.ProvisioningURI(s.issuer, user.Email, secret)
+	qr, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code: %w", err)
+	}
+
+	return &MFAEnrollment{
+		Secret: totp.EncodeSecret(secret),
+		URI:    uri,
+		QRCode: base64.StdEncoding.EncodeToString(qr),
+	}, nil
+}
+
+// Confirm enables the second factor being enrolled once code shows the app
+// was set up, and returns the user's recovery codes. They are shown this
+// once; only their hashes are stored.
+func (s *MFAService) Confirm(ctx context.Context, userID, code string, client domain.Client) ([]string, error) {
+	user, mfa, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.IsEnabled() {
+		return nil, ErrMFAEnabled
+	}
+	if err := s.verify(ctx, user, mfa, code, false, client); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.EnableMFA(ctx, userID, time.Now().UTC(), hashes); err != nil {
+		return nil, fmt.Errorf("failed to enable MFA: %w", err)
+	}
+	s.auth.guard.record(ctx, domain.AuthEventMFAEnabled, user.Email, user.ID, client)
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user, who proves
+// they hold their second factor with code, and returns the new ones
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID, code string, client domain.Client) ([]string, error) {
+	user, mfa, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.IsEnabled() {
+		return nil, ErrMFANotEnabled
+	}
+	if err := s.verify(ctx, user, mfa, code, true, client); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	s.auth.guard.record(ctx, domain.AuthEventRecoveryCodesRegenerated, user.Email, user.ID, client)
+	return codes, nil
+}
+
+// Disable removes the second factor of a user, who proves they hold it
+// with code
+func (s *MFAService) Disable(ctx context.Context, userID, code string, client domain.Client) error {
+	user, mfa, err := s.load(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !mfa.IsEnabled() {
+		return ErrMFANotEnabled
+	}
+	if err := s.verify(ctx, user, mfa, code, true, client); err != nil {
+		return err
+	}
+
+	if err := s.store.DeleteMFA(ctx, userID); err != nil {
+		return fmt.Errorf("failed to disable MFA: %w", err)
+	}
+	s.auth.guard.record(ctx, domain.AuthEventMFADisabled, user.Email, user.ID, client)
+	return nil
+}
+
+// CompleteLogin finishes a login held back by an MFARequiredError: code,
+// from the app or a recovery code, is checked against the user of
+// challenge, then tokens are issued as by Login
+func (s *MFAService) CompleteLogin(ctx context.Context, challenge, code string, client domain.Client) (*domain.AuthToken, error) {
+	userID, err := s.auth.tokenService.ValidateMFAChallenge(challenge)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	// The second factor may have gone since the challenge was issued
+	user, mfa, err := s.load(ctx, userID)
+	if errors.Is(err, ErrMFANotEnabled) || errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.IsEnabled() {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	if err := s.verify(ctx, user, mfa, code, true, client); err != nil {
+		return nil, err
+	}
+	s.auth.guard.Succeed(ctx, user.Email, user.ID, client)
+	return s.auth.issueTokens(ctx, user, nil, client)
+}
+
+// Required reports whether policy requires user to have a second factor
+func (s *MFAService) Required(user *domain.User) bool {
+	_, host, _ := strings.Cut(strings.ToLower(user.Email), "@")
+	return slices.Contains(s.requiredDomains, host)
+}
+
+// Missing reports whether a user is required to have a second factor and
+// has not enabled one
+func (s *MFAService) Missing(ctx context.Context, userID string) (bool, error) {
+	if len(s.requiredDomains) == 0 {
+		return false, nil
+	}
+
+	user, err := s.auth.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if !s.Required(user) {
+		return false, nil
+	}
+
+	mfa, err := s.find(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return mfa == nil || !mfa.IsEnabled(), nil
+}
+
+// verify checks a code of the app of mfa, or one of user's recovery codes
+// if allowRecovery. Lockouts apply as to logins.
+func (s *MFAService) verify(ctx context.Context, user *domain.User, mfa *domain.MFA, code string, allowRecovery bool, client domain.Client) error {
+	if err := s.auth.guard.Check(ctx, user.Email, user.ID, client); err != nil {
+		return err
+	}
+
+	ok, err := s.accept(ctx, user, mfa, normalizeCode(code), allowRecovery, client)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.auth.guard.Fail(ctx, user.Email, user.ID, client)
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// accept spends code if it is valid: a TOTP code can't be used twice and
+// a recovery code only once
+func (s *MFAService) accept(ctx context.Context, user *domain.User, mfa *domain.MFA, code string, allowRecovery bool, client domain.Client) (bool, error) {
+	if len(code) == totp.Digits {
+		secret, err := s.open(mfa)
+		if err != nil {
+			return false, err
+		}
+		step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+		if !ok {
+			return false, nil
+		}
+
+		// A code seen once may have been read over a shoulder
+		err = s.store.UseTOTPStep(ctx, user.ID, step)
+		if errors.Is(err, database.ErrConflict) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to record code: %w", err)
+		}
+		return true, nil
+	}
+
+	if !allowRecovery || len(code) != recoveryCodeLength {
+		return false, nil
+	}
+	err := s.store.UseRecoveryCode(ctx, user.ID, hashOpaqueToken(code))
+	if errors.Is(err, database.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	s.auth.guard.record(ctx, domain.AuthEventRecoveryCodeUsed, user.Email, user.ID, client)
+	return true, nil
+}
+
+// load returns a user and their second factor, enabled or not
+func (s *MFAService) load(ctx context.Context, userID string) (*domain.User, *domain.MFA, error) {
+	user, err := s.auth.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	mfa, err := s.find(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mfa == nil {
+		return nil, nil, ErrMFANotEnabled
+	}
+	return user, mfa, nil
+}
+
+// find returns the second factor of a user, or nil if they have none
+func (s *MFAService) find(ctx context.Context, userID string) (*domain.MFA, error) {
+	mfa, err := s.store.FindMFA(ctx, userID)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up MFA: %w", err)
+	}
+	return mfa, nil
+}
+
+// seal encrypts a TOTP secret with AES-256-GCM, bound to its user so a
+// ciphertext copied to another account does not open
+func (s *MFAService) seal(userID string, secret []byte) ([]byte, error) {
+	aead, err := s.aead()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, secret, []byte(userID)), nil
+}
+
+// open decrypts the TOTP secret of mfa
+func (s *MFAService) open(mfa *domain.MFA) ([]byte, error) {
+	aead, err := s.aead()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(mfa.Secret) < aead.NonceSize() {
+		return nil, errors.New("failed to decrypt TOTP secret: ciphertext too short")
+	}
+	nonce, sealed := mfa.Secret[:aead.NonceSize()], mfa.Secret[aead.NonceSize():]
+	secret, err := aead.Open(nil, nonce, sealed, []byte(mfa.UserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+	}
+	return secret, nil
+}
+
+func (s *MFAService) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MFA encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// generateRecoveryCodes returns new recovery codes, grouped by four
+// characters for reading, and the hashes to store
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	for range RecoveryCodeCount {
+		random := make([]byte, recoveryCodeLength*5/8)
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		code := base32.StdEncoding.EncodeToString(random)
+		codes = append(codes, code[0:4]+"-"+code[4:8]+"-"+code[8:12]+"-"+code[12:16])
+		hashes = append(hashes, hashOpaqueToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeCode drops the spaces and dashes users type or paste with codes
+func normalizeCode(code string) string {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+	return strings.ToUpper(code)
+}
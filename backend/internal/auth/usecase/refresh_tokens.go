@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"ecolink-core/internal/auth/domain"
+	"ecolink-core/pkg/database"
+	"ecolink-core/pkg/utils"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// DefaultRefreshTokenTTL is how long a login lasts without any refresh
+const DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	// ErrInvalidRefreshToken is returned by Refresh for an unknown, expired
+	// or revoked refresh token; the client has to log in again
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned by Refresh for a token that was
+	// already rotated. Its whole family is revoked, logging out both the
+	// legitimate client and whoever replayed the token.
+	ErrRefreshTokenReused = fmt.Errorf("refresh token reused: %w", ErrInvalidRefreshToken)
+)
+
+// Refresh rotates a refresh token: it is marked used and exchanged for a new
+// access token and a new refresh token of the same family
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string, client domain.Client) (*domain.AuthToken, error) {
+	stored, err := s.tokens.FindRefreshToken(ctx, hashOpaqueToken(refreshToken))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up refresh token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if stored.IsRevoked() || stored.IsExpired(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.IsUsed() {
+		return nil, s.revokeReusedFamily(ctx, stored)
+	}
+
+	// Of two concurrent refreshes with the same token only one wins; the
+	// other is treated as a replay
+	err = s.tokens.UseRefreshToken(ctx, stored.TokenHash, now)
+	if errors.Is(err, database.ErrConflict) {
+		return nil, s.revokeReusedFamily(ctx, stored)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	user, err := s.UserRepo.FindByID(ctx, stored.UserID)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	session, err := s.tokens.FindSession(ctx, stored.FamilyID)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up session: %w", err)
+	}
+	if !session.IsActive(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.issueTokens(ctx, user, session, client)
+}
+
+// RevokeRefreshToken ends the login a refresh token belongs to. Unknown
+// tokens are ignored, so logging out twice is harmless.
+func (s *AuthService) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	stored, err := s.tokens.FindRefreshToken(ctx, hashOpaqueToken(refreshToken))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up refresh token: %w", err)
+	}
+	return s.endSession(ctx, stored.FamilyID)
+}
+
+// issueTokens signs an access token for user together with a refresh token,
+// continuing session or starting a new one (a new login) when it is nil
+func (s *AuthService) issueTokens(ctx context.Context, user *domain.User, session *domain.Session, client domain.Client) (*domain.AuthToken, error) {
+	now := time.Now().UTC()
+	newSession := session == nil
+	if newSession {
+		session = &domain.Session{
+			ID:        generateSecureID(),
+			UserID:    user.ID,
+			CreatedAt: now,
+		}
+	}
+	session.UserAgent = client.UserAgent
+	session.IPPrefix = utils.AnonymizeIP(client.IP)
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(s.refreshTTL)
+
+	token, err := s.tokenService.GenerateToken(user.ID, user.Email, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	refreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	if newSession {
+		err = s.tokens.CreateSession(ctx, session)
+	} else {
+		err = s.tokens.UpdateSession(ctx, session)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store session: %w", err)
+	}
+
+	// The refresh token family shares the session's ID
+	stored := &domain.RefreshToken{
+		TokenHash: hashOpaqueToken(refreshToken),
+		FamilyID:  session.ID,
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := s.tokens.CreateRefreshToken(ctx, stored); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	token.RefreshToken = refreshToken
+	token.RefreshExpiresAt = stored.ExpiresAt
+	return token, nil
+}
+
+func (s *AuthService) revokeReusedFamily(ctx context.Context, token *domain.RefreshToken) error {
+	log.Printf("⚠️  Refresh token reused for user %s, revoking its token family", token.UserID)
+	if err := s.endSession(ctx, token.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// generateOpaqueToken creates an opaque token with 256 bits of entropy
+func generateOpaqueToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// hashOpaqueToken derives the storage key of a refresh token or API key.
+// Both are random, so an unsalted fast hash is enough to keep a database leak from
+// yielding usable tokens.
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
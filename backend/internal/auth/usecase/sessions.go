@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"ecolink-core/pkg/database"
+	"fmt"
+	"time"
+)
+
+// ListSessions returns the active logins of a user, most recently seen
+// first
+func (s *AuthService) ListSessions(ctx context.Context, userID string) ([]*domain.Session, error) {
+	sessions, err := s.tokens.ListUserSessions(ctx, userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession logs a user out of one of their logins. Sessions of other
+// users are reported as missing.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	session, err := s.tokens.FindSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return database.ErrSessionNotFound
+	}
+	return s.endSession(ctx, sessionID)
+}
+
+// endSession revokes a session, which rejects its access tokens, and its
+// refresh token family
+func (s *AuthService) endSession(ctx context.Context, sessionID string) error {
+	now := time.Now().UTC()
+	if err := s.tokens.RevokeRefreshTokenFamily(ctx, sessionID, now); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	if err := s.tokens.RevokeSession(ctx, sessionID, now); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package usecase
 import (
 	"crypto/rand"
 	"ecolink-core/internal/auth/domain"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -10,26 +11,44 @@ import (
 )
 
 type TokenService interface {
-	GenerateToken(userID, email string) (*domain.AuthToken, error)
+	GenerateToken(userID, email, sessionID string) (*domain.AuthToken, error)
 	ValidateToken(tokenString string) (*domain.TokenClaims, error)
+	// GenerateMFAChallenge issues the token a login gets once the password
+	// of an account with a second factor checked out. It is exchanged for
+	// an AuthToken together with a code, and grants nothing else.
+	GenerateMFAChallenge(userID string) (string, time.Time, error)
+	// ValidateMFAChallenge returns the user ID of a challenge token
+	ValidateMFAChallenge(tokenString string) (string, error)
 }
 
+// DefaultAccessTokenTTL keeps access tokens short-lived; clients renew them
+// with their refresh token
+const DefaultAccessTokenTTL = 15 * time.Minute
+
+// MFAChallengeTTL is how long a login has to provide its second factor
+const MFAChallengeTTL = 5 * time.Minute
+
+// mfaChallengeType marks challenge tokens, which access tokens never are
+const mfaChallengeType = "mfa_challenge"
+
 type JWTTokenService struct {
 	secretKey []byte
 	issuer    string
+	ttl       time.Duration
 }
 
-func NewJWTTokenService(secretKey, issuer string) TokenService {
+func NewJWTTokenService(secretKey, issuer string, ttl time.Duration) TokenService {
 	return &JWTTokenService{
 		secretKey: []byte(secretKey),
 		issuer:    issuer,
+		ttl:       ttl,
 	}
 }
 
 // GenerateToken creates a new JWT token with secure claims
-func (s *JWTTokenService) GenerateToken(userID, email string) (*domain.AuthToken, error) {
+func (s *JWTTokenService) GenerateToken(userID, email, sessionID string) (*domain.AuthToken, error) {
 	now := time.Now()
-	expiresAt := now.Add(24 * time.Hour) // 24 hour expiration
+	expiresAt := now.Add(s.ttl)
 
 	// Create secure random JTI (JWT ID) to prevent replay attacks
 	jti := make([]byte, 16)
@@ -43,7 +62,8 @@ func (s *JWTTokenService) GenerateToken(userID, email string) (*domain.AuthToken
 		"iss":    s.issuer,
 		"iat":    now.Unix(),
 		"exp":    expiresAt.Unix(),
-		"jti":    string(jti), // Unique token ID
+		"jti":    hex.EncodeToString(jti), // Unique token ID
+		"sid":    sessionID, // Login the token belongs to
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -61,28 +81,27 @@ func (s *JWTTokenService) GenerateToken(userID, email string) (*domain.AuthToken
 
 // ValidateToken parses and validates a JWT token
 func (s *JWTTokenService) ValidateToken(tokenString string) (*domain.TokenClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return s.secretKey, nil
-	})
-
+	claims, err := s.parse(tokenString)
 	if err != nil {
 		return nil, err
 	}
 
-	if !token.Valid {
-		return nil, errors.New("invalid token")
+	// A challenge token must not pass for an access token
+	if _, ok := claims["typ"]; ok {
+		return nil, errors.New("invalid token type")
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, errors.New("invalid token claims")
+	// Extract and validate claims
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return nil, errors.New("invalid token ID claim")
+	}
+
+	sessionID, ok := claims["sid"].(string)
+	if !ok || sessionID == "" {
+		return nil, errors.New("invalid session ID claim")
 	}
 
-	// Extract and validate claims
 	userID, ok := claims["sub"].(string)
 	if !ok {
 		return nil, errors.New("invalid user ID claim")
@@ -104,9 +123,81 @@ func (s *JWTTokenService) ValidateToken(tokenString string) (*domain.TokenClaims
 	}
 
 	return &domain.TokenClaims{
-		UserID: userID,
-		Email:  email,
-		Exp:    int64(exp),
-		Iat:    int64(iat),
+		ID:        jti,
+		SessionID: sessionID,
+		UserID:    userID,
+		Email:     email,
+		Exp:       int64(exp),
+		Iat:       int64(iat),
 	}, nil
+}
+
+// GenerateMFAChallenge creates a short-lived JWT naming the user whose
+// password was checked
+func (s *JWTTokenService) GenerateMFAChallenge(userID string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(MFAChallengeTTL)
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", time.Time{}, err
+	}
+
+	claims := jwt.MapClaims{
+		"sub": userID,
+		"iss": s.issuer,
+		"iat": now.Unix(),
+		"exp": expiresAt.Unix(),
+		"jti": hex.EncodeToString(jti),
+		"typ": mfaChallengeType,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secretKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ValidateMFAChallenge parses and validates a challenge token
+func (s *JWTTokenService) ValidateMFAChallenge(tokenString string) (string, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return "", err
+	}
+
+	if typ, _ := claims["typ"].(string); typ != mfaChallengeType {
+		return "", errors.New("invalid token type")
+	}
+
+	userID, ok := claims["sub"].(string)
+	if !ok || userID == "" {
+		return "", errors.New("invalid user ID claim")
+	}
+	return userID, nil
+}
+
+// parse checks the signature and expiry of a JWT and returns its claims
+func (s *JWTTokenService) parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Validate signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("invalid signing method")
+		}
+		return s.secretKey, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	return claims, nil
 }
\ No newline at end of file
@@ -0,0 +1,41 @@
+package bootstrap
+
+import (
+	"ecolink-core/internal/analytics/repository"
+	"ecolink-core/internal/config"
+	"ecolink-core/pkg/database"
+	"ecolink-core/pkg/geoip"
+	"log"
+)
+
+// NewClickRepository stores click events alongside links, in the same
+// Firestore project or SQL database, and in memory otherwise
+func NewClickRepository(db database.Database) repository.ClickRepository {
+	switch db := db.(type) {
+	case *database.FirestoreDB:
+		return repository.NewFirestoreClickRepository(db.Client())
+	case *database.PostgresDB:
+		return repository.NewSQLClickRepository(db.DB())
+	case *database.SQLiteDB:
+		return repository.NewSQLClickRepository(db.DB())
+	default:
+		return repository.NewInMemoryClickRepository()
+	}
+}
+
+// NewGeoLocator opens the configured GeoIP database. GeoIP is optional, so
+// a missing or unreadable file disables enrichment instead of failing.
+func NewGeoLocator(cfg *config.Config) geoip.Locator {
+	if cfg.GeoIP.DatabasePath == "" {
+		return nil
+	}
+
+	locator, err := geoip.OpenMMDB(cfg.GeoIP.DatabasePath)
+	if err != nil {
+		log.Printf("⚠️  GeoIP disabled: %v", err)
+		return nil
+	}
+
+	log.Println("✅ GeoIP database loaded")
+	return locator
+}
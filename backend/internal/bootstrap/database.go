@@ -25,6 +25,28 @@ func NewDBConnection(cfg *config.Config) (database.Database, error) {
 		log.Println("✅ Firestore database connected")
 		return db, nil
 		
+	case "postgres":
+		if cfg.Database.URL == "" {
+			return nil, fmt.Errorf("DATABASE_URL is required for postgres")
+		}
+
+		db, err := database.NewPostgresDB(cfg.Database.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Println("✅ PostgreSQL database connected, migrations applied")
+		return db, nil
+
+	case "sqlite":
+		db, err := database.NewSQLiteDB(cfg.Database.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("✅ SQLite database opened at %s, migrations applied", cfg.Database.Path)
+		return db, nil
+
 	case "memory":
 		log.Println("⚠️  Using in-memory database (data will not persist)")
 		return database.NewMemoryDB(), nil
@@ -32,4 +54,18 @@ func NewDBConnection(cfg *config.Config) (database.Database, error) {
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", cfg.Database.Type)
 	}
-}
\ No newline at end of file
+}
+
+// NewLinkCache puts the configured LRU cache in front of link lookups, or
+// returns db unchanged when the cache is disabled
+func NewLinkCache(cfg *config.Config, db database.Database) database.Database {
+	if cfg.LinkCache.Size == 0 {
+		return db
+	}
+
+	return database.NewCachedDB(db, database.CacheConfig{
+		Size:        cfg.LinkCache.Size,
+		TTL:         cfg.LinkCache.TTL,
+		NegativeTTL: cfg.LinkCache.NegativeTTL,
+	})
+}
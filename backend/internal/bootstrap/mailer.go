@@ -0,0 +1,35 @@
+package bootstrap
+
+import (
+	"ecolink-core/internal/config"
+	"ecolink-core/pkg/mailer"
+	"log"
+)
+
+// NewMailer returns the configured mailer. An SMTP relay that cannot be
+// set up falls back to logging, so sign-ups keep working while the mail
+// settings are fixed.
+func NewMailer(cfg *config.Config) mailer.Mailer {
+	switch cfg.Mail.Driver {
+	case "smtp":
+		smtpMailer, err := mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.Mail.SMTP.Host,
+			Port:     cfg.Mail.SMTP.Port,
+			Username: cfg.Mail.SMTP.Username,
+			Password: cfg.Mail.SMTP.Password,
+			From:     cfg.Mail.From,
+		})
+		if err != nil {
+			log.Printf("⚠️  SMTP mailer disabled, logging emails instead: %v", err)
+			return mailer.NewLogMailer(cfg.Mail.From)
+		}
+		log.Printf("✅ Sending emails through %s", cfg.Mail.SMTP.Host)
+		return smtpMailer
+	case "file":
+		log.Printf("📧 Writing emails to %s", cfg.Mail.File)
+		return mailer.NewFileMailer(cfg.Mail.File, cfg.Mail.From)
+	default:
+		log.Println("⚠️  Emails are logged, not sent; set SMTP_HOST to deliver them")
+		return mailer.NewLogMailer(cfg.Mail.From)
+	}
+}
@@ -0,0 +1,28 @@
+package bootstrap
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"ecolink-core/internal/config"
+	"encoding/base64"
+	"log"
+)
+
+// mfaKeyInfo separates the key derived for TOTP secrets from any other use
+// of JWT_SECRET
+const mfaKeyInfo = "ecolink mfa secret encryption"
+
+// NewMFAEncryptionKey returns the AES-256 key TOTP secrets are stored
+// under: MFA_ENCRYPTION_KEY, or else a key derived from JWT_SECRET
+func NewMFAEncryptionKey(cfg *config.Config) []byte {
+	if cfg.Security.MFA.EncryptionKey != "" {
+		// Checked when the configuration was loaded
+		key, _ := base64.StdEncoding.DecodeString(cfg.Security.MFA.EncryptionKey)
+		return key
+	}
+
+	log.Println("⚠️  TOTP secrets are encrypted with a key derived from JWT_SECRET; set MFA_ENCRYPTION_KEY so rotating JWT_SECRET keeps them readable")
+	// Only fails for keys longer than 255 hashes
+	key, _ := hkdf.Key(sha256.New, []byte(cfg.Security.JWTSecret), nil, mfaKeyInfo, 32)
+	return key
+}
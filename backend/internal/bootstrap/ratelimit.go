@@ -0,0 +1,41 @@
+package bootstrap
+
+import (
+	"ecolink-core/internal/config"
+	"ecolink-core/internal/middleware"
+	"ecolink-core/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewRateLimitStore keeps rate limit buckets in process, which holds the
+// limits per instance. Deployments running several instances return a
+// ratelimit.Store on a shared backend here instead.
+func NewRateLimitStore(cfg *config.Config) ratelimit.Store {
+	return ratelimit.NewMemoryStore()
+}
+
+// rateLimits is the middleware of each rate limit policy
+type rateLimits struct {
+	redirects gin.HandlerFunc // Per client IP
+	auth      gin.HandlerFunc // Per client IP, for password guesses and sign-ups
+	writes    gin.HandlerFunc // Per user or API key
+}
+
+func newRateLimits(cfg *config.Config, store ratelimit.Store) rateLimits {
+	limits := cfg.Security.RateLimit
+	return rateLimits{
+		redirects: rateLimit(store, "redirects", float64(limits.RedirectRPS), limits.RedirectRPS, middleware.ByClientIP),
+		auth:      rateLimit(store, "auth", float64(limits.AuthPerMinute)/60, limits.AuthPerMinute, middleware.ByClientIP),
+		writes:    rateLimit(store, "writes", float64(limits.WritesPerMinute)/60, limits.WritesPerMinute, middleware.ByCaller),
+	}
+}
+
+// rateLimit allows burst requests at once, refilled at rate per second; a
+// zero burst disables the limit
+func rateLimit(store ratelimit.Store, name string, rate float64, burst int, key middleware.RateLimitKey) gin.HandlerFunc {
+	if burst == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return middleware.RateLimit(store, ratelimit.Policy{Name: name, Rate: rate, Burst: burst}, key)
+}
@@ -1,15 +1,24 @@
 package bootstrap
 
 import (
+	"context"
+	analytics "ecolink-core/internal/analytics/usecase"
 	"ecolink-core/internal/auth/delivery/http"
-	"ecolink-core/internal/auth/repository"
+	"ecolink-core/internal/auth/domain"
 	"ecolink-core/internal/auth/usecase"
 	"ecolink-core/internal/config"
 	"ecolink-core/internal/handlers"
 	"ecolink-core/internal/middleware"
+	"ecolink-core/internal/security"
 	"ecolink-core/internal/services"
 	"ecolink-core/internal/validation"
 	"ecolink-core/pkg/database"
+	"ecolink-core/pkg/geoip"
+	"ecolink-core/pkg/ratelimit"
+	"errors"
+	"io"
+	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,28 +28,73 @@ type Application struct {
 	Config *config.Config
 	DB     database.Database
 	Router *gin.Engine
+	Clicks *analytics.ClickRecorder
+	GeoIP  geoip.Locator // nil when GeoIP is disabled
+
+	store database.Database // DB without the link cache, closed on shutdown
 }
 
 // NewApplication creates and wires all application dependencies
 func NewApplication(cfg *config.Config, db database.Database) *Application {
-	router := setupRouter(cfg, db)
+	store := db
+	locator := NewGeoLocator(cfg)
+	analyticsService := analytics.NewAnalyticsService(NewClickRepository(db), locator)
+	// Link reads and writes go through the cache so writes invalidate it
+	db = NewLinkCache(cfg, db)
+	clicks := analytics.NewClickRecorder(analyticsService, db, analytics.RecorderConfig{
+		BufferSize:    cfg.Clicks.BufferSize,
+		BatchSize:     cfg.Clicks.BatchSize,
+		FlushInterval: cfg.Clicks.FlushInterval,
+	})
+
+	router := setupRouter(cfg, db, analyticsService, clicks, NewRateLimitStore(cfg))
 	return &Application{
 		Config: cfg,
 		DB:     db,
 		Router: router,
+		Clicks: clicks,
+		GeoIP:  locator,
+		store:  store,
+	}
+}
+
+// Shutdown flushes buffered clicks, then closes the GeoIP database their
+// enrichment reads and the store they are written to, releasing pooled
+// connections and SQLite's file lock; call it once the HTTP server stopped
+// accepting requests
+func (a *Application) Shutdown(ctx context.Context) error {
+	if err := a.Clicks.Close(ctx); err != nil {
+		// The recorder may still be looking clicks up and writing them, so
+		// the databases stay open until the process exits
+		return err
+	}
+
+	var errs []error
+	for _, resource := range []any{a.GeoIP, a.store} {
+		if closer, ok := resource.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
 	}
+	return errors.Join(errs...)
 }
 
 // setupRouter configures the HTTP router with all middleware and routes
-func setupRouter(cfg *config.Config, db database.Database) *gin.Engine {
+func setupRouter(cfg *config.Config, db database.Database, analyticsService *analytics.AnalyticsService, clicks *analytics.ClickRecorder, limiter ratelimit.Store) *gin.Engine {
 	// Initialize services
-	linkService := services.NewLinkService(db, cfg.BaseURL)
+	linkService := services.NewLinkService(db, cfg.BaseURL, services.CodeConfig{
+		Length:      cfg.ShortCode.Length,
+		Alphabet:    cfg.ShortCode.Alphabet,
+		MaxAttempts: cfg.ShortCode.MaxAttempts,
+	}, clicks)
+	// Links and accounts share the database, so both user services read the
+	// same users
 	userService := services.NewUserService(db)
 
 	// Initialize auth services
 	tokenService := usecase.NewJWTTokenService(
 		cfg.Security.JWTSecret,
 		"ecolink",
+		cfg.Security.AccessTokenTTL,
 	)
 
 	googleConfig := usecase.GoogleConfig{
@@ -49,14 +103,25 @@ func setupRouter(cfg *config.Config, db database.Database) *gin.Engine {
 		RedirectURI:  cfg.FrontendURL + "/auth/callback/google",
 	}
 
-	// Initialize user repository
-	userRepo := repository.NewInMemoryUserRepository()
-
 	// Initialize auth service
-	authService := usecase.NewAuthService(userRepo, tokenService, googleConfig)
+	lockout := usecase.DefaultLockoutPolicy
+	lockout.MaxFailures = cfg.Security.Lockout.MaxFailures
+	lockout.MaxFailuresPerIP = cfg.Security.Lockout.MaxFailuresPerIP
+	lockout.Lockout = cfg.Security.Lockout.Duration
+	lockout.MaxLockout = cfg.Security.Lockout.MaxDuration
+	loginGuard := usecase.NewLoginGuard(db, db, lockout)
+	authService := usecase.NewAuthService(db, db, db, loginGuard, tokenService, googleConfig, cfg.Security.RefreshTokenTTL)
+	accountEmails := usecase.NewAccountEmailService(authService, db, db, NewMailer(cfg), cfg.FrontendURL)
+	mfaService := usecase.NewMFAService(authService, db, usecase.MFAConfig{
+		Issuer:          "EcoLink",
+		EncryptionKey:   NewMFAEncryptionKey(cfg),
+		RequiredDomains: cfg.Security.MFA.RequiredDomains,
+	})
+	apiKeyService := usecase.NewAPIKeyService(db)
 
 	// Initialize handlers
-	linkHandler := handlers.NewLinkHandler(linkService)
+	linkUnlocker := security.NewLinkUnlocker(cfg.Security.JWTSecret, 15*time.Minute)
+	linkHandler := handlers.NewLinkHandler(linkService, analyticsService, clicks, linkUnlocker)
 	userHandler := handlers.NewUserHandler(userService)
 	csrfHandler := handlers.NewCSRFHandler()
 
@@ -64,58 +129,97 @@ func setupRouter(cfg *config.Config, db database.Database) *gin.Engine {
 	validator := validation.NewValidator()
 
 	// Secure auth handler with auto-detection
-	authHandler := http.NewAuthHandler(*authService, validator)
+	authHandler := http.NewAuthHandler(*authService, accountEmails, mfaService, validator)
+	apiKeyHandler := http.NewAPIKeyHandler(apiKeyService, validator)
 
 	// Setup router
 	r := gin.Default()
+	// Client IPs feed the rate limits, so only the configured proxies may
+	// set them; without any, X-Forwarded-For is ignored. Gin trusts every
+	// proxy by default.
+	if err := r.SetTrustedProxies(cfg.Security.TrustedProxies); err != nil {
+		log.Printf("⚠️  Ignoring TRUSTED_PROXIES: %v", err)
+		_ = r.SetTrustedProxies(nil)
+	}
+	limits := newRateLimits(cfg, limiter)
 
 	// Global middleware
 	r.Use(middleware.SecurityHeaders())
 	r.Use(middleware.CORS([]string{cfg.FrontendURL}))
 
 	// Public routes
-	r.GET("/:code", linkHandler.RedirectLink)
+	r.GET("/:code", limits.redirects, linkHandler.RedirectLink)
+	r.HEAD("/:code", limits.redirects, linkHandler.RedirectLink) // Link checkers probe with HEAD; counted as bots
+	r.POST("/:code/unlock", limits.auth, linkHandler.UnlockLink) // Password guesses, limited like logins
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok", "service": "ecolink-core"})
 	})
 
 	// Auth routes (public)
 	auth := r.Group("/auth")
+	auth.Use(limits.auth)
 	{
 		auth.POST("/google/callback", authHandler.GoogleCallback)
 		auth.POST("/logout", authHandler.Logout)
 		auth.POST("/register", authHandler.Register)
 		auth.POST("/login", authHandler.Login)
+		auth.POST("/mfa", authHandler.CompleteMFALogin)
+		auth.POST("/refresh", authHandler.Refresh)
+		auth.POST("/verify", authHandler.VerifyEmail)
+		auth.POST("/forgot", authHandler.ForgotPassword)
+		auth.POST("/reset", authHandler.ResetPassword)
 		auth.GET("/google", authHandler.GoogleLogin)
 	}
 
 	// Protected API routes
 	api := r.Group("/api/v1")
-	api.Use(middleware.RequireAuth(tokenService))
+	api.Use(middleware.RequireAuth(tokenService, db, apiKeyService))
 	{
-		// CSRF token endpoint
-		api.GET("/csrf-token", csrfHandler.GetCSRFToken)
-
-		// User endpoints
-		api.GET("/me", authHandler.GetCurrentUser)
-		api.GET("/profile", userHandler.GetProfile)
-
-		// Link endpoints (with CSRF protection)
-		protected := api.Group("")
-		protected.Use(middleware.CSRFMiddleware(cfg.Security.CSRFSecret))
+		// Link endpoints, also open to API keys with the matching scope
+		api.GET("/links", middleware.RequireScope(domain.ScopeLinksRead), linkHandler.GetUserLinks)
+		api.GET("/links/:code/stats", middleware.RequireScope(domain.ScopeStatsRead), linkHandler.GetLinkStats)
+
+		// Link writes (with CSRF protection, which API keys skip). Users of
+		// the MFA_REQUIRED_DOMAINS need a second factor to make them.
+		writes := api.Group("")
+		writes.Use(limits.writes, middleware.CSRFMiddleware(cfg.Security.CSRFSecret), middleware.RequireScope(domain.ScopeLinksWrite),
+			middleware.RequireMFA(mfaService))
 		{
-			protected.POST("/links", linkHandler.CreateLink)
-			protected.DELETE("/links/:code", linkHandler.DeleteLink)
+			writes.POST("/links", linkHandler.CreateLink)
+			writes.PATCH("/links/:code", linkHandler.UpdateLink)
+			writes.DELETE("/links/:code", linkHandler.DeleteLink)
 		}
 
-		// Read-only endpoints (no CSRF needed)
-		api.GET("/links", linkHandler.GetUserLinks)
+		// Account endpoints need a login session
+		account := api.Group("")
+		account.Use(middleware.RequireSession())
+		{
+			// CSRF token endpoint
+			account.GET("/csrf-token", csrfHandler.GetCSRFToken)
+
+			// User endpoints
+			account.GET("/me", authHandler.GetCurrentUser)
+			account.GET("/profile", userHandler.GetProfile)
+			account.GET("/sessions", authHandler.ListSessions)
+			account.GET("/auth-events", authHandler.ListAuthEvents)
+			account.GET("/api-keys", apiKeyHandler.ListAPIKeys)
+			account.GET("/mfa", authHandler.GetMFAStatus)
+
+			protected := account.Group("")
+			protected.Use(limits.writes, middleware.CSRFMiddleware(cfg.Security.CSRFSecret))
+			{
+				protected.POST("/me/verify-email", authHandler.ResendVerification)
+				protected.POST("/sessions/revoke-all", authHandler.LogoutEverywhere)
+				protected.DELETE("/sessions/:id", authHandler.RevokeSession)
+				protected.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+				protected.DELETE("/api-keys/:id", apiKeyHandler.DeleteAPIKey)
+				protected.POST("/mfa/enroll", authHandler.EnrollMFA)
+				protected.POST("/mfa/confirm", authHandler.ConfirmMFA)
+				protected.POST("/mfa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+				protected.POST("/mfa/disable", authHandler.DisableMFA)
+			}
+		}
 	}
 
 	return r
 }
-
-// NewRouter maintains backward compatibility (deprecated)
-func NewRouter(cfg *config.Config, db database.Database) *gin.Engine {
-	return setupRouter(cfg, db)
-}
@@ -1,10 +1,15 @@
 package config
 
 import (
+	"ecolink-core/pkg/utils"
+	"encoding/base64"
 	"fmt"
+	"net"
+	"net/mail"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,7 +19,12 @@ type Config struct {
 	BaseURL     string
 	FrontendURL string
 	Database    DatabaseConfig
+	ShortCode   ShortCodeConfig
+	GeoIP       GeoIPConfig
+	Clicks      ClickRecorderConfig
+	LinkCache   LinkCacheConfig
 	GoogleAuth  GoogleAuthConfig
+	Mail        MailConfig
 	Security    SecurityConfig
 	Cookie      CookieConfig
 }
@@ -23,6 +33,35 @@ type DatabaseConfig struct {
 	Type        string
 	ProjectID   string
 	Credentials string
+	URL         string // PostgreSQL connection string
+	Path        string // SQLite database file
+}
+
+type ShortCodeConfig struct {
+	Length      int
+	Alphabet    string
+	MaxAttempts int
+}
+
+// GeoIPConfig points to a local MaxMind-format database; empty disables it
+type GeoIPConfig struct {
+	DatabasePath string
+}
+
+// ClickRecorderConfig tunes the background writer of click counters and
+// events
+type ClickRecorderConfig struct {
+	BufferSize    int
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// LinkCacheConfig bounds the in-process cache of short code lookups; a zero
+// size disables it
+type LinkCacheConfig struct {
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration
 }
 
 type GoogleAuthConfig struct {
@@ -30,11 +69,61 @@ type GoogleAuthConfig struct {
 	ClientSecret string
 }
 
+// MailConfig selects how account emails (verification, password reset)
+// are delivered: through an SMTP relay, appended to a file, or only logged
+type MailConfig struct {
+	Driver string // "smtp", "file" or "log"
+	From   string
+	File   string // Mbox file written by the file driver
+	SMTP   SMTPConfig
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
 type SecurityConfig struct {
-	JWTSecret    string
-	CSRFSecret   string
-	BcryptCost   int
-	RateLimitRPS int
+	JWTSecret       string
+	CSRFSecret      string
+	BcryptCost      int
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	RateLimit       RateLimitConfig
+	Lockout         LockoutConfig
+	MFA             MFAConfig
+	// TrustedProxies are the addresses or CIDR ranges whose
+	// X-Forwarded-For is believed; empty ignores the header
+	TrustedProxies []string
+}
+
+// RateLimitConfig sets the request budgets of each client; zero disables a
+// limit
+type RateLimitConfig struct {
+	RedirectRPS     int // Redirects per second per client IP
+	AuthPerMinute   int // Logins, registrations, refreshes and unlocks per minute per client IP
+	WritesPerMinute int // API writes per minute per user or API key
+}
+
+// LockoutConfig sets when failed password logins lock an account or a
+// client address out
+type LockoutConfig struct {
+	MaxFailures      int           // Per account email
+	MaxFailuresPerIP int           // Per client IP, across accounts
+	Duration         time.Duration // First lockout, doubled with each further failure
+	MaxDuration      time.Duration
+}
+
+// MFAConfig sets up two-factor authentication
+type MFAConfig struct {
+	// EncryptionKey is the base64 AES-256 key TOTP secrets are stored
+	// under; empty derives one from JWT_SECRET
+	EncryptionKey string
+	// RequiredDomains are the email domains whose users must enable a
+	// second factor to create, change or delete links
+	RequiredDomains []string
 }
 
 type CookieConfig struct {
@@ -54,16 +143,64 @@ func Load() (*Config, error) {
 			Type:        getEnv("DB_TYPE", "memory"),
 			ProjectID:   getEnv("FIRESTORE_PROJECT_ID", ""),
 			Credentials: getEnv("GOOGLE_APPLICATION_CREDENTIALS", ""),
+			URL:         getEnv("DATABASE_URL", ""),
+			Path:        getEnv("SQLITE_PATH", "ecolink.db"),
+		},
+		ShortCode: ShortCodeConfig{
+			Length:      getEnvInt("SHORTCODE_LENGTH", 6),
+			Alphabet:    getEnv("SHORTCODE_ALPHABET", utils.DefaultAlphabet),
+			MaxAttempts: getEnvInt("SHORTCODE_MAX_ATTEMPTS", 10),
+		},
+		GeoIP: GeoIPConfig{
+			DatabasePath: getEnv("GEOIP_DB_PATH", ""),
+		},
+		Clicks: ClickRecorderConfig{
+			BufferSize:    getEnvInt("CLICK_BUFFER_SIZE", 10000),
+			BatchSize:     getEnvInt("CLICK_BATCH_SIZE", 500),
+			FlushInterval: getEnvDuration("CLICK_FLUSH_INTERVAL", 2*time.Second),
+		},
+		LinkCache: LinkCacheConfig{
+			Size:        getEnvInt("LINK_CACHE_SIZE", 10000),
+			TTL:         getEnvDuration("LINK_CACHE_TTL", time.Minute),
+			NegativeTTL: getEnvDuration("LINK_CACHE_NEGATIVE_TTL", 10*time.Second),
 		},
 		GoogleAuth: GoogleAuthConfig{
 			ClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
 			ClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
 		},
+		Mail: MailConfig{
+			Driver: strings.ToLower(getEnv("MAIL_DRIVER", defaultMailDriver())),
+			From:   getEnv("MAIL_FROM", "EcoLink <no-reply@localhost>"),
+			File:   getEnv("MAIL_FILE", "mail.mbox"),
+			SMTP: SMTPConfig{
+				Host:     getEnv("SMTP_HOST", ""),
+				Port:     getEnvInt("SMTP_PORT", 587),
+				Username: getEnv("SMTP_USERNAME", ""),
+				Password: getEnv("SMTP_PASSWORD", ""),
+			},
+		},
 		Security: SecurityConfig{
-			JWTSecret:    os.Getenv("JWT_SECRET"),
-			CSRFSecret:   getEnv("CSRF_SECRET", "change-me-in-production-32-chars-min"),
-			BcryptCost:   getEnvInt("BCRYPT_COST", 12),
-			RateLimitRPS: getEnvInt("RATE_LIMIT_RPS", 100),
+			JWTSecret:       os.Getenv("JWT_SECRET"),
+			CSRFSecret:      getEnv("CSRF_SECRET", "change-me-in-production-32-chars-min"),
+			BcryptCost:      getEnvInt("BCRYPT_COST", 12),
+			AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+			TrustedProxies:  getEnvList("TRUSTED_PROXIES"),
+			Lockout: LockoutConfig{
+				MaxFailures:      getEnvInt("LOGIN_MAX_FAILURES", 5),
+				MaxFailuresPerIP: getEnvInt("LOGIN_MAX_FAILURES_PER_IP", 20),
+				Duration:         getEnvDuration("LOGIN_LOCKOUT", time.Minute),
+				MaxDuration:      getEnvDuration("LOGIN_MAX_LOCKOUT", time.Hour),
+			},
+			MFA: MFAConfig{
+				EncryptionKey:   os.Getenv("MFA_ENCRYPTION_KEY"),
+				RequiredDomains: getEnvList("MFA_REQUIRED_DOMAINS"),
+			},
+			RateLimit: RateLimitConfig{
+				RedirectRPS:     getEnvInt("RATE_LIMIT_RPS", 100),
+				AuthPerMinute:   getEnvInt("RATE_LIMIT_AUTH_PER_MINUTE", 20),
+				WritesPerMinute: getEnvInt("RATE_LIMIT_WRITES_PER_MINUTE", 120),
+			},
 		},
 		Cookie: CookieConfig{
 			Domain:   getEnv("COOKIE_DOMAIN", "localhost"),
@@ -88,10 +225,89 @@ func (c *Config) validate() error {
 		return fmt.Errorf("JWT_SECRET must be at least 32 characters")
 	}
 
+	if c.Security.AccessTokenTTL <= 0 || c.Security.RefreshTokenTTL <= 0 {
+		return fmt.Errorf("ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL must be positive durations")
+	}
+
+	if c.Security.RefreshTokenTTL <= c.Security.AccessTokenTTL {
+		return fmt.Errorf("REFRESH_TOKEN_TTL must be longer than ACCESS_TOKEN_TTL")
+	}
+
+	limits := c.Security.RateLimit
+	if limits.RedirectRPS < 0 || limits.AuthPerMinute < 0 || limits.WritesPerMinute < 0 {
+		return fmt.Errorf("RATE_LIMIT_RPS, RATE_LIMIT_AUTH_PER_MINUTE and RATE_LIMIT_WRITES_PER_MINUTE must not be negative")
+	}
+
+	lockout := c.Security.Lockout
+	if lockout.MaxFailures < 1 || lockout.MaxFailuresPerIP < 1 {
+		return fmt.Errorf("LOGIN_MAX_FAILURES and LOGIN_MAX_FAILURES_PER_IP must be at least 1")
+	}
+
+	if lockout.Duration <= 0 || lockout.MaxDuration < lockout.Duration {
+		return fmt.Errorf("LOGIN_LOCKOUT must be positive and at most LOGIN_MAX_LOCKOUT")
+	}
+
+	if key := c.Security.MFA.EncryptionKey; key != "" {
+		if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 32 {
+			return fmt.Errorf("MFA_ENCRYPTION_KEY must be 32 bytes encoded in base64")
+		}
+	}
+
+	for _, proxy := range c.Security.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid TRUSTED_PROXIES entry: %s", proxy)
+		}
+	}
+
 	if c.GoogleAuth.ClientID == "" || c.GoogleAuth.ClientSecret == "" {
 		return fmt.Errorf("Google OAuth credentials are required")
 	}
 
+	if c.ShortCode.Length < 3 || c.ShortCode.Length > 32 {
+		return fmt.Errorf("SHORTCODE_LENGTH must be between 3 and 32")
+	}
+
+	if err := validateAlphabet(c.ShortCode.Alphabet); err != nil {
+		return err
+	}
+
+	if c.ShortCode.MaxAttempts < 1 {
+		return fmt.Errorf("SHORTCODE_MAX_ATTEMPTS must be at least 1")
+	}
+
+	if c.Clicks.BufferSize < 1 || c.Clicks.BatchSize < 1 {
+		return fmt.Errorf("CLICK_BUFFER_SIZE and CLICK_BATCH_SIZE must be at least 1")
+	}
+
+	if c.Clicks.BatchSize > 500 {
+		return fmt.Errorf("CLICK_BATCH_SIZE must be at most 500")
+	}
+
+	if c.Clicks.FlushInterval <= 0 {
+		return fmt.Errorf("CLICK_FLUSH_INTERVAL must be a positive duration")
+	}
+
+	if c.LinkCache.Size < 0 || c.LinkCache.TTL < 0 || c.LinkCache.NegativeTTL < 0 {
+		return fmt.Errorf("LINK_CACHE_SIZE, LINK_CACHE_TTL and LINK_CACHE_NEGATIVE_TTL must not be negative")
+	}
+
+	switch c.Mail.Driver {
+	case "smtp":
+		if c.Mail.SMTP.Host == "" {
+			return fmt.Errorf("SMTP_HOST is required with MAIL_DRIVER=smtp")
+		}
+		if c.Mail.SMTP.Port < 1 || c.Mail.SMTP.Port > 65535 {
+			return fmt.Errorf("invalid SMTP_PORT: %d", c.Mail.SMTP.Port)
+		}
+	case "file", "log":
+	default:
+		return fmt.Errorf("invalid MAIL_DRIVER value: %s", c.Mail.Driver)
+	}
+
+	if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+		return fmt.Errorf("invalid MAIL_FROM address: %s", c.Mail.From)
+	}
+
 	validSameSite := []string{"strict", "lax", "none"}
 	if !contains(validSameSite, strings.ToLower(c.Cookie.SameSite)) {
 		return fmt.Errorf("invalid COOKIE_SAMESITE value: %s", c.Cookie.SameSite)
@@ -100,6 +316,36 @@ func (c *Config) validate() error {
 	return nil
 }
 
+// validateAlphabet ensures generated codes stay URL safe and unambiguous
+func validateAlphabet(alphabet string) error {
+	if len(alphabet) < 2 {
+		return fmt.Errorf("SHORTCODE_ALPHABET must have at least 2 characters")
+	}
+
+	seen := make(map[rune]bool)
+	for _, char := range alphabet {
+		isAlnum := (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9')
+		if !isAlnum {
+			return fmt.Errorf("SHORTCODE_ALPHABET must only contain letters and digits")
+		}
+		if seen[char] {
+			return fmt.Errorf("SHORTCODE_ALPHABET contains duplicate character: %c", char)
+		}
+		seen[char] = true
+	}
+
+	return nil
+}
+
+// defaultMailDriver sends through SMTP once a relay is configured and only
+// logs emails otherwise
+func defaultMailDriver() string {
+	if os.Getenv("SMTP_HOST") != "" {
+		return "smtp"
+	}
+	return "log"
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value != "" {
@@ -124,6 +370,26 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList reads a comma-separated list, skipping empty entries
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
@@ -1,20 +1,40 @@
 package handlers
 
 import (
+	"ecolink-core/internal/analytics/domain"
+	analytics "ecolink-core/internal/analytics/usecase"
 	"ecolink-core/internal/models"
+	"ecolink-core/internal/security"
 	"ecolink-core/internal/services"
+	"ecolink-core/pkg/database"
+	"ecolink-core/pkg/utils"
+	"errors"
+	"html/template"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// UnlockCookieName holds the signed proof that a link's password was entered
+const UnlockCookieName = "ecolink_unlock"
+
 type LinkHandler struct {
 	linkService *services.LinkService
+	analytics   *analytics.AnalyticsService
+	clicks      *analytics.ClickRecorder
+	unlocker    *security.LinkUnlocker
 }
 
-func NewLinkHandler(linkService *services.LinkService) *LinkHandler {
+func NewLinkHandler(linkService *services.LinkService, analyticsService *analytics.AnalyticsService, clicks *analytics.ClickRecorder, unlocker *security.LinkUnlocker) *LinkHandler {
 	return &LinkHandler{
 		linkService: linkService,
+		analytics:   analyticsService,
+		clicks:      clicks,
+		unlocker:    unlocker,
 	}
 }
 
@@ -32,9 +52,16 @@ func (h *LinkHandler) CreateLink(c *gin.Context) {
 		return
 	}
 
-	response, err := h.linkService.CreateLink(req.URL, userID.(string))
+	response, err := h.linkService.CreateLink(c.Request.Context(), req, userID.(string))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error."})
+		switch {
+		case errors.Is(err, services.ErrInvalidAlias), errors.Is(err, services.ErrInvalidExpiry):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAliasUnavailable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			respondStorageError(c, err, nil)
+		}
 		return
 	}
 
@@ -44,15 +71,132 @@ func (h *LinkHandler) CreateLink(c *gin.Context) {
 func (h *LinkHandler) RedirectLink(c *gin.Context) {
 	shortCode := c.Param("code")
 
-	originalURL, err := h.linkService.GetOriginalURL(shortCode)
+	opts := services.ResolveOptions{Bot: utils.IsBotRequest(c.Request)}
+	if token, err := c.Cookie(UnlockCookieName); err == nil {
+		// Tokens are bound to the password, so checking one needs the link
+		opts.Unlocked = func(link *models.Link) bool {
+			return h.unlocker.Verify(shortCode, link.PasswordHash, token)
+		}
+	}
+	link, err := h.linkService.ResolveLink(c.Request.Context(), shortCode, opts)
+	if err != nil {
+		if errors.Is(err, services.ErrPasswordRequired) {
+			h.renderUnlock(c, shortCode, http.StatusUnauthorized, "")
+			return
+		}
+
+		var gone *services.GoneError
+		if errors.As(err, &gone) {
+			if gone.FallbackURL != "" {
+				c.Redirect(http.StatusFound, gone.FallbackURL)
+				return
+			}
+			c.JSON(http.StatusGone, gin.H{"error": "Link is no longer available", "reason": gone.Reason, "code": shortCode})
+			return
+		}
+		respondStorageError(c, err, gin.H{"error": "Link not found", "code": shortCode})
+		return
+	}
+
+	// Counting and analytics happen in the background and must never
+	// delay or break a redirect
+	h.clicks.Enqueue(analytics.ClickInput{
+		Code:      link.Code,
+		Referrer:  c.Request.Referer(),
+		UserAgent: c.Request.UserAgent(),
+		ClientIP:  c.ClientIP(),
+		Query:     c.Request.URL.Query(),
+		Bot:       opts.Bot,
+		Counted:   services.CountsOnResolve(link, opts),
+	})
+
+	// Links are editable and can expire, so browsers must not cache the
+	// redirect as permanent
+	c.Redirect(http.StatusFound, link.URL)
+}
+
+// UnlockLink verifies a protected link's password and issues a short-lived
+// unlock cookie scoped to the link's path
+func (h *LinkHandler) UnlockLink(c *gin.Context) {
+	shortCode := c.Param("code")
+	isForm := c.ContentType() == "application/x-www-form-urlencoded"
+
+	var req models.UnlockLinkRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is required"})
+		return
+	}
+
+	link, err := h.linkService.UnlockLink(c.Request.Context(), shortCode, req.Password)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found", "code": shortCode})
+		switch {
+		case errors.Is(err, services.ErrInvalidPassword):
+			if isForm {
+				h.renderUnlock(c, shortCode, http.StatusUnauthorized, "Incorrect password")
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Incorrect password"})
+		case errors.Is(err, services.ErrLinkGone):
+			c.JSON(http.StatusGone, gin.H{"error": "Link is no longer available", "code": shortCode})
+		default:
+			respondStorageError(c, err, gin.H{"error": "Link not found", "code": shortCode})
+		}
+		return
+	}
+
+	token, expiresAt := h.unlocker.Sign(shortCode, link.PasswordHash)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     UnlockCookieName,
+		Value:    token,
+		Path:     "/" + shortCode,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if isForm {
+		c.Redirect(http.StatusSeeOther, "/"+shortCode)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Link unlocked",
+		"expires_at": expiresAt,
+	})
+}
+
+var unlockPage = template.Must(template.New("unlock").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Protected link</title></head>
+<body style="font-family: sans-serif; max-width: 24rem; margin: 4rem auto;">
+<h1>This link is password protected</h1>
+{{if .Error}}<p style="color: #b91c1c;">{{.Error}}</p>{{end}}
+<form method="POST" action="/{{.Code}}/unlock">
+<input type="password" name="password" placeholder="Password" required autofocus>
+<button type="submit">Unlock</button>
+</form>
+</body>
+</html>`))
+
+// renderUnlock serves the password prompt to browsers and JSON to API clients
+func (h *LinkHandler) renderUnlock(c *gin.Context, shortCode string, status int, message string) {
+	c.Header("Cache-Control", "no-store")
+
+	if !strings.Contains(c.GetHeader("Accept"), "text/html") {
+		c.JSON(status, gin.H{"error": "Password required", "code": shortCode, "unlock": "/" + shortCode + "/unlock"})
 		return
 	}
 
-	c.Redirect(http.StatusMovedPermanently, originalURL)
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	unlockPage.Execute(c.Writer, gin.H{"Code": shortCode, "Error": message})
 }
 
+// GetUserLinks returns one page of the current user's links. Query
+// parameters: limit, cursor (nextCursor of the previous page), sort
+// (created or clicks), order (desc or asc), q (search), tag, status
+// (active, expired or exhausted), from and to (RFC 3339 creation range).
 func (h *LinkHandler) GetUserLinks(c *gin.Context) {
 	// Get user ID from middleware context
 	userID, exists := c.Get("user_id")
@@ -61,13 +205,195 @@ func (h *LinkHandler) GetUserLinks(c *gin.Context) {
 		return
 	}
 
-	links, err := h.linkService.GetUserLinks(userID.(string))
+	query, err := parseLinkQuery(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error."})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"links": links})
+	page, err := h.linkService.ListLinks(c.Request.Context(), userID.(string), query)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cursor is invalid or belongs to another sort order"})
+		case errors.Is(err, database.ErrInvalidQuery):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			respondStorageError(c, err, nil)
+		}
+		return
+	}
+
+	links := page.Links
+	if links == nil {
+		links = []*models.Link{}
+	}
+	c.JSON(http.StatusOK, gin.H{"links": links, "nextCursor": page.NextCursor})
+}
+
+func parseLinkQuery(c *gin.Context) (database.LinkQuery, error) {
+	query := database.LinkQuery{
+		Cursor: c.Query("cursor"),
+		Search: c.Query("q"),
+		Tag:    c.Query("tag"),
+		Sort:   database.LinkSort(c.DefaultQuery("sort", string(database.SortByCreated))),
+		Status: database.LinkStatus(c.Query("status")),
+	}
+
+	switch query.Sort {
+	case database.SortByCreated, database.SortByClicks:
+	default:
+		return query, errors.New("sort must be created or clicks")
+	}
+
+	switch query.Status {
+	case "", database.StatusActive, database.StatusExpired, database.StatusExhausted:
+	default:
+		return query, errors.New("status must be active, expired or exhausted")
+	}
+
+	switch c.DefaultQuery("order", "desc") {
+	case "desc":
+	case "asc":
+		query.Ascending = true
+	default:
+		return query, errors.New("order must be asc or desc")
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 {
+			return query, errors.New("limit must be a positive integer")
+		}
+		query.Limit = parsed
+	}
+
+	if from := c.Query("from"); from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return query, errors.New("from must be an RFC 3339 timestamp")
+		}
+		query.From = parsed
+	}
+
+	if to := c.Query("to"); to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return query, errors.New("to must be an RFC 3339 timestamp")
+		}
+		query.To = parsed
+	}
+
+	return query, nil
+}
+
+func (h *LinkHandler) UpdateLink(c *gin.Context) {
+	code := c.Param("code")
+
+	var req models.UpdateLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get user ID from middleware context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	link, err := h.linkService.UpdateLink(c.Request.Context(), code, userID.(string), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotLinkOwner):
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not own this link"})
+		case errors.Is(err, services.ErrInvalidAlias), errors.Is(err, services.ErrInvalidExpiry):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAliasUnavailable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			respondStorageError(c, err, gin.H{"error": "Link not found", "code": code})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"link": link})
+}
+
+// GetLinkStats returns click analytics of a link owned by the current user.
+// Query parameters: interval (hour, day or week; default day), from and to
+// (RFC 3339; default the last 30 days).
+func (h *LinkHandler) GetLinkStats(c *gin.Context) {
+	code := c.Param("code")
+
+	// Get user ID from middleware context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	query, err := parseStatsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.linkService.GetUserLink(c.Request.Context(), code, userID.(string)); err != nil {
+		if errors.Is(err, services.ErrNotLinkOwner) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not own this link"})
+			return
+		}
+		respondStorageError(c, err, gin.H{"error": "Link not found", "code": code})
+		return
+	}
+
+	stats, err := h.analytics.GetLinkStats(c.Request.Context(), code, query)
+	if err != nil {
+		if errors.Is(err, analytics.ErrInvalidInterval) || errors.Is(err, analytics.ErrInvalidRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		respondStorageError(c, err, gin.H{"error": "Link not found", "code": code})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func parseStatsQuery(c *gin.Context) (analytics.StatsQuery, error) {
+	query := analytics.StatsQuery{
+		To:       time.Now().UTC(),
+		Interval: domain.Interval(c.DefaultQuery("interval", string(domain.IntervalDay))),
+	}
+
+	if to := c.Query("to"); to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return query, errors.New("to must be an RFC 3339 timestamp")
+		}
+		query.To = parsed
+	}
+
+	if includeBots := c.Query("include_bots"); includeBots != "" {
+		parsed, err := strconv.ParseBool(includeBots)
+		if err != nil {
+			return query, errors.New("include_bots must be a boolean")
+		}
+		query.IncludeBots = parsed
+	}
+
+	query.From = query.To.AddDate(0, 0, -30)
+	if from := c.Query("from"); from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return query, errors.New("from must be an RFC 3339 timestamp")
+		}
+		query.From = parsed
+	}
+
+	return query, nil
 }
 
 func (h *LinkHandler) DeleteLink(c *gin.Context) {
@@ -80,11 +406,19 @@ func (h *LinkHandler) DeleteLink(c *gin.Context) {
 		return
 	}
 
-	err := h.linkService.DeleteLink(code, userID.(string))
+	err := h.linkService.DeleteLink(c.Request.Context(), code, userID.(string))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error."})
+		if errors.Is(err, services.ErrNotLinkOwner) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not own this link"})
+			return
+		}
+		respondStorageError(c, err, gin.H{"error": "Link not found", "code": code})
 		return
 	}
 
+	if err := h.analytics.DeleteClicks(c.Request.Context(), code); err != nil {
+		log.Printf("⚠️  Failed to delete click history of %s: %v", code, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Link deleted successfully"})
 }
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"ecolink-core/pkg/database"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// storageRetryAfter is the delay in seconds suggested to clients when the
+// database is unavailable
+const storageRetryAfter = "5"
+
+// respondStorageError answers an error no handler-specific case covered: a
+// missing record is 404 with the notFound body, a conflicting write is 409
+// and a transient storage failure is 503 with Retry-After. Anything else is
+// a 500, as is a missing record when notFound is nil, for endpoints where
+// nothing the client named can be missing.
+func respondStorageError(c *gin.Context, err error, notFound gin.H) {
+	switch {
+	case notFound != nil && errors.Is(err, database.ErrNotFound):
+		c.JSON(http.StatusNotFound, notFound)
+	case errors.Is(err, database.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Conflicting change, please retry"})
+	case errors.Is(err, database.ErrUnavailable):
+		c.Header("Retry-After", storageRetryAfter)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service temporarily unavailable, please retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error."})
+	}
+}
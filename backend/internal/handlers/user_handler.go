@@ -23,9 +23,9 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUser(userID.(string))
+	user, err := h.userService.GetUser(c.Request.Context(), userID.(string))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		respondStorageError(c, err, gin.H{"error": "User not found"})
 		return
 	}
 
@@ -1,25 +1,66 @@
 package middleware
 
 import (
+	"ecolink-core/internal/auth/domain"
+	"ecolink-core/internal/auth/repository"
 	"ecolink-core/internal/auth/usecase"
 	"ecolink-core/internal/errors"
+	"ecolink-core/pkg/database"
+	stderrors "errors"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// RequireAuth middleware validates JWT tokens and injects user context
-func RequireAuth(tokenService usecase.TokenService) gin.HandlerFunc {
+// sessionTouchInterval limits how often a session's last-seen time is
+// written, so busy clients don't cost a store write per request
+const sessionTouchInterval = time.Minute
+
+// RequireAuth middleware validates JWT tokens, rejects revoked ones and
+// injects user context. A personal API key may be sent as the bearer token
+// instead; see RequireScope and RequireSession for what it can reach.
+func RequireAuth(tokenService usecase.TokenService, tokens repository.TokenRepository, apiKeys *usecase.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Extract token from cookie or Authorization header
-		token := extractToken(c)
+		token := ExtractToken(c)
 		if token == "" {
 			c.JSON(http.StatusUnauthorized, errors.NewAuthError("Authentication required"))
 			c.Abort()
 			return
 		}
 
+		if usecase.IsAPIKey(token) {
+			// Keys skip CSRF checks, so one that a browser would send by
+			// itself in a cookie is refused
+			if c.GetHeader("Authorization") != "Bearer "+token {
+				c.JSON(http.StatusUnauthorized, errors.NewAuthError("API keys must be sent in the Authorization header"))
+				c.Abort()
+				return
+			}
+
+			key, err := apiKeys.Authenticate(c.Request.Context(), token)
+			if stderrors.Is(err, usecase.ErrInvalidAPIKey) {
+				c.JSON(http.StatusUnauthorized, errors.NewAuthError("Invalid or expired API key"))
+				c.Abort()
+				return
+			}
+			if err != nil {
+				log.Printf("⚠️  Failed to check API key: %v", err)
+				c.Header("Retry-After", "5")
+				c.JSON(http.StatusServiceUnavailable, errors.NewInternalError())
+				c.Abort()
+				return
+			}
+
+			c.Set("user_id", key.UserID)
+			c.Set("api_key", key)
+			c.Next()
+			return
+		}
+
 		// Validate token
 		claims, err := tokenService.ValidateToken(token)
 		if err != nil {
@@ -28,15 +69,79 @@ func RequireAuth(tokenService usecase.TokenService) gin.HandlerFunc {
 			return
 		}
 
+		// Reject tokens revoked by a logout; fail closed if the store is down
+		revoked, err := tokens.IsTokenRevoked(c.Request.Context(), claims.ID)
+		if err != nil {
+			log.Printf("⚠️  Failed to check token revocation: %v", err)
+			c.Header("Retry-After", "5")
+			c.JSON(http.StatusServiceUnavailable, errors.NewInternalError())
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, errors.NewAuthError("Token has been revoked"))
+			c.Abort()
+			return
+		}
+
+		// Reject tokens of a session that was revoked or has expired
+		session, err := tokens.FindSession(c.Request.Context(), claims.SessionID)
+		if err != nil && !stderrors.Is(err, database.ErrNotFound) {
+			log.Printf("⚠️  Failed to look up session: %v", err)
+			c.Header("Retry-After", "5")
+			c.JSON(http.StatusServiceUnavailable, errors.NewInternalError())
+			c.Abort()
+			return
+		}
+		now := time.Now().UTC()
+		if err != nil || session.UserID != claims.UserID || !session.IsActive(now) {
+			c.JSON(http.StatusUnauthorized, errors.NewAuthError("Session has ended"))
+			c.Abort()
+			return
+		}
+		if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+			if err := tokens.TouchSession(c.Request.Context(), session.ID, now); err != nil {
+				log.Printf("⚠️  Failed to update session last seen: %v", err)
+			}
+		}
+
 		// Inject user context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
+		c.Set("token_id", claims.ID)
+		c.Set("session_id", claims.SessionID)
+		c.Next()
+	}
+}
+
+// RequireScope admits requests authenticated by an API key only if the key
+// was granted scope. Requests of a login session always pass.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key, ok := c.Get("api_key"); ok && !key.(*domain.APIKey).HasScope(scope) {
+			c.JSON(http.StatusForbidden, errors.NewAuthorizationError("API key lacks the "+scope+" scope"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession rejects requests authenticated by an API key, keeping
+// account management (sessions, API keys, profile) to logged in users
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("api_key"); ok {
+			c.JSON(http.StatusForbidden, errors.NewAuthorizationError("This endpoint requires a login session"))
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
 
-// extractToken gets JWT from cookie or Authorization header
-func extractToken(c *gin.Context) string {
+// ExtractToken gets JWT from cookie or Authorization header
+func ExtractToken(c *gin.Context) string {
 	// Try cookie first (primary method)
 	if token, err := c.Cookie("ecolink_token"); err == nil && token != "" {
 		return token
@@ -121,7 +226,7 @@ func CORS(allowedOrigins []string) gin.HandlerFunc {
 		}
 		
 		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		c.Header("Access-Control-Max-Age", "86400") // 24 hours
 
 		if c.Request.Method == "OPTIONS" {
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"ecolink-core/internal/auth/usecase"
+	"ecolink-core/internal/errors"
+	"ecolink-core/pkg/database"
+	stderrors "errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireMFA rejects users whom policy requires to have a second factor
+// until they enable one. Requests made with an API key answer for the
+// key's owner. It must run after RequireAuth.
+func RequireMFA(mfa *usecase.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		missing, err := mfa.Missing(c.Request.Context(), c.GetString("user_id"))
+		if err != nil {
+			log.Printf("⚠️  Failed to check MFA policy: %v", err)
+			if stderrors.Is(err, database.ErrUnavailable) {
+				c.Header("Retry-After", "5")
+				c.JSON(http.StatusServiceUnavailable, errors.NewInternalError())
+			} else {
+				c.JSON(http.StatusInternalServerError, errors.NewInternalError())
+			}
+			c.Abort()
+			return
+		}
+		if missing {
+			c.JSON(http.StatusForbidden, errors.NewAuthorizationError("Enable two-factor authentication to manage links"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
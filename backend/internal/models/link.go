@@ -1,17 +1,94 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Link struct {
-	URL       string    `json:"originalUrl" firestore:"originalUrl"`
-	Code      string    `json:"shortCode" firestore:"shortCode"`
-	UserID    string    `json:"userId" firestore:"userId"`
-	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
-	Clicks    int       `json:"clickCount" firestore:"clickCount"`
+	URL          string     `json:"originalUrl" firestore:"originalUrl"`
+	Code         string     `json:"shortCode" firestore:"shortCode"`
+	UserID       string     `json:"userId" firestore:"userId"`
+	CreatedAt    time.Time  `json:"createdAt" firestore:"createdAt"`
+	Clicks       int        `json:"clickCount" firestore:"clickCount"` // Human clicks only
+	BotClicks    int        `json:"botClickCount" firestore:"botClickCount"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty" firestore:"expiresAt,omitempty"`
+	MaxClicks    int        `json:"maxClicks,omitempty" firestore:"maxClicks,omitempty"`
+	FallbackURL  string     `json:"fallbackUrl,omitempty" firestore:"fallbackUrl,omitempty"`
+	PasswordHash string     `json:"-" firestore:"passwordHash,omitempty"` // bcrypt hash, never serialized
+	Title        string     `json:"title,omitempty" firestore:"title,omitempty"`
+	Tags         []string   `json:"tags,omitempty" firestore:"tags,omitempty"`
+	UpdatedAt    time.Time  `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// IsExpired reports whether the link's expiration date has passed
+func (l *Link) IsExpired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// IsExhausted reports whether the link has used up its click budget
+func (l *Link) IsExhausted() bool {
+	return l.MaxClicks > 0 && l.Clicks >= l.MaxClicks
+}
+
+// IsProtected reports whether the link requires a password to be followed
+func (l *Link) IsProtected() bool {
+	return l.PasswordHash != ""
+}
+
+// HasLifecycle reports whether the link has any expiration rule
+func (l *Link) HasLifecycle() bool {
+	return l.ExpiresAt != nil || l.MaxClicks > 0
 }
 
 type CreateLinkRequest struct {
-	URL string `json:"url" binding:"required,url"`
+	URL         string     `json:"url" binding:"required,url"`
+	Alias       string     `json:"alias,omitempty" binding:"omitempty,max=32"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	MaxClicks   int        `json:"maxClicks,omitempty" binding:"omitempty,min=1"`
+	FallbackURL string     `json:"fallbackUrl,omitempty" binding:"omitempty,url"`
+	Password    string     `json:"password,omitempty" binding:"omitempty,min=4,max=72"`
+	Title       string     `json:"title,omitempty" binding:"omitempty,max=200"`
+	Tags        []string   `json:"tags,omitempty" binding:"omitempty,max=20,dive,min=1,max=50"`
+}
+
+// UpdateLinkRequest is a partial update: absent fields are left unchanged.
+// An empty fallbackUrl or password, a zero maxClicks and a null expiresAt
+// remove the corresponding rule.
+type UpdateLinkRequest struct {
+	URL         *string      `json:"url" binding:"omitempty,url"`
+	Alias       *string      `json:"alias" binding:"omitempty,max=32"`
+	ExpiresAt   OptionalTime `json:"expiresAt"`
+	MaxClicks   *int         `json:"maxClicks" binding:"omitempty,min=0"`
+	FallbackURL *string      `json:"fallbackUrl" binding:"omitnil,eq=|url"`
+	Password    *string      `json:"password" binding:"omitnil,eq=|min=4,max=72"`
+	Title       *string      `json:"title" binding:"omitempty,max=200"`
+	Tags        *[]string    `json:"tags" binding:"omitempty,max=20,dive,min=1,max=50"`
+}
+
+// OptionalTime tells an absent JSON field apart from an explicit null
+type OptionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (o *OptionalTime) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+
+	var t time.Time
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	o.Value = &t
+	return nil
+}
+
+type UnlockLinkRequest struct {
+	Password string `json:"password" form:"password" binding:"required"`
 }
 
 type CreateLinkResponse struct {
@@ -1,60 +0,0 @@
-package security
-
-import (
-	"errors"
-	"time"
-
-	"github.com/golang-jwt/jwt/v5"
-)
-
-type JWTService struct {
-	secretKey []byte
-	issuer    string
-	audience  string
-}
-
-type Claims struct {
-	UserID string `json:"sub"`
-	Email  string `json:"email"`
-	jwt.RegisteredClaims
-}
-
-func NewJWTService(secretKey, issuer, audience string) *JWTService {
-	return &JWTService{
-		secretKey: []byte(secretKey),
-		issuer:    issuer,
-		audience:  audience,
-	}
-}
-
-func (j *JWTService) GenerateToken(userID, email string) (string, error) {
-	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    j.issuer,
-			Audience:  []string{j.audience},
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
-}
-
-func (j *JWTService) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return j.secretKey, nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
-	}
-
-	return nil, errors.New("invalid token")
-}
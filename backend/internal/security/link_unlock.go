@@ -0,0 +1,56 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LinkUnlocker issues and verifies short-lived tokens proving that the
+// password of a protected link was entered
+type LinkUnlocker struct {
+	secretKey []byte
+	ttl       time.Duration
+}
+
+func NewLinkUnlocker(secretKey string, ttl time.Duration) *LinkUnlocker {
+	return &LinkUnlocker{
+		secretKey: []byte(secretKey),
+		ttl:       ttl,
+	}
+}
+
+// Sign creates an unlock token bound to the given short code and to the
+// hash of the password it was unlocked with, so changing or removing the
+// password revokes the token
+func (u *LinkUnlocker) Sign(code, passwordHash string) (string, time.Time) {
+	expiresAt := time.Now().Add(u.ttl)
+	exp := strconv.FormatInt(expiresAt.Unix(), 10)
+	return exp + "." + u.signature(code, passwordHash, exp), expiresAt
+}
+
+// Verify checks the token signature, code and password binding and
+// expiration
+func (u *LinkUnlocker) Verify(code, passwordHash, token string) bool {
+	exp, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+
+	expUnix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() >= expUnix {
+		return false
+	}
+
+	// Constant-time comparison prevents timing attacks on the signature
+	return hmac.Equal([]byte(signature), []byte(u.signature(code, passwordHash, exp)))
+}
+
+func (u *LinkUnlocker) signature(code, passwordHash, exp string) string {
+	mac := hmac.New(sha256.New, u.secretKey)
+	mac.Write([]byte("link-unlock|" + code + "|" + exp + "|" + passwordHash))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
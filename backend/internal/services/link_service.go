@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"ecolink-core/internal/models"
 	"ecolink-core/pkg/database"
 	"ecolink-core/pkg/utils"
@@ -10,54 +11,196 @@ import (
 	"time"
 
 	"github.com/skip2/go-qrcode"
+	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidAlias       = errors.New("invalid alias")
+	ErrAliasUnavailable   = errors.New("alias is already in use")
+	ErrCodeSpaceExhausted = errors.New("could not allocate a free short code")
+	ErrInvalidExpiry      = errors.New("expiration date must be in the future")
+	ErrLinkGone           = errors.New("link is no longer available")
+	ErrPasswordRequired   = errors.New("link is password protected")
+	ErrInvalidPassword    = errors.New("invalid link password")
+	// ErrLinkNotFound is the storage error, so it also matches
+	// database.ErrNotFound
+	ErrLinkNotFound = database.ErrLinkNotFound
+	ErrNotLinkOwner = errors.New("unauthorized")
+)
+
+// GoneError is returned when a link exists but has expired or run out of
+// clicks. It matches ErrLinkGone with errors.Is.
+type GoneError struct {
+	Reason      string
+	FallbackURL string
+}
+
+func (e *GoneError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrLinkGone.Error(), e.Reason)
+}
+
+func (e *GoneError) Is(target error) bool {
+	return target == ErrLinkGone
+}
+
+// Page sizes of link listings
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 100
+)
+
+// collisionsBeforeGrowth is how many collisions at one length are tolerated
+// before the code space is considered crowded and the length grows by one
+const collisionsBeforeGrowth = 3
+
+// CodeConfig controls how short codes are generated
+type CodeConfig struct {
+	Length      int
+	Alphabet    string
+	MaxAttempts int
+}
+
+// ClickHistory is the port used to keep a link's clicks when its code
+// changes
+type ClickHistory interface {
+	// Flush writes the clicks still buffered under their current codes
+	Flush(ctx context.Context) error
+	// ReassignClicks moves recorded clicks from oldCode to newCode
+	ReassignClicks(ctx context.Context, oldCode, newCode string) error
+}
+
 type LinkService struct {
-	db      database.Database
-	baseURL string
+	db         database.Database
+	baseURL    string
+	codeConfig CodeConfig
+	history    ClickHistory
 }
 
-func NewLinkService(db database.Database, baseURL string) *LinkService {
+// NewLinkService creates the service; history may be nil when clicks are
+// not recorded
+func NewLinkService(db database.Database, baseURL string, codeConfig CodeConfig, history ClickHistory) *LinkService {
+	if codeConfig.Length == 0 {
+		codeConfig.Length = 6
+	}
+	if codeConfig.Alphabet == "" {
+		codeConfig.Alphabet = utils.DefaultAlphabet
+	}
+	if codeConfig.MaxAttempts == 0 {
+		codeConfig.MaxAttempts = 10
+	}
+
 	return &LinkService{
-		db:      db,
-		baseURL: baseURL,
+		db:         db,
+		baseURL:    baseURL,
+		codeConfig: codeConfig,
+		history:    history,
 	}
 }
 
-func (s *LinkService) CreateLink(originalURL, userID string) (*models.CreateLinkResponse, error) {
-	// Check if link already exists for this URL and user
-	userLinks, err := s.db.GetUserLinks(userID)
-	if err == nil {
-		for _, existingLink := range userLinks {
-			if existingLink.URL == originalURL {
-				// Return existent link
-				shortURL := s.baseURL + "/" + existingLink.Code
-				qrCode, err := s.generateQRCode(shortURL)
-				if err != nil {
-					return nil, err
-				}
-				return &models.CreateLinkResponse{
-					ShortURL: shortURL,
-					QRCode:   qrCode,
-				}, nil
-			}
+func (s *LinkService) CreateLink(ctx context.Context, req models.CreateLinkRequest, userID string) (*models.CreateLinkResponse, error) {
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, ErrInvalidExpiry
+	}
+
+	link := &models.Link{
+		URL:         req.URL,
+		UserID:      userID,
+		CreatedAt:   now,
+		Clicks:      0,
+		ExpiresAt:   req.ExpiresAt,
+		MaxClicks:   req.MaxClicks,
+		FallbackURL: req.FallbackURL,
+		Title:       req.Title,
+		Tags:        req.Tags,
+		UpdatedAt:   now,
+	}
+
+	if req.Password != "" {
+		passwordHash, err := hashLinkPassword(req.Password)
+		if err != nil {
+			return nil, err
 		}
+		link.PasswordHash = passwordHash
 	}
 
-	shortCode := utils.GenerateShortCode(originalURL)
+	if req.Alias != "" {
+		if err := utils.ValidateAlias(req.Alias); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAlias, err)
+		}
 
-	link := &models.Link{
-		URL:       originalURL,
-		Code:      shortCode,
-		UserID:    userID,
-		CreatedAt: time.Now(),
-		Clicks:    0,
+		link.Code = req.Alias
+		if err := s.db.SaveLink(ctx, link); err != nil {
+			if errors.Is(err, database.ErrCodeTaken) {
+				return nil, ErrAliasUnavailable
+			}
+			return nil, err
+		}
+
+		return s.buildResponse(link.Code)
 	}
 
-	if err := s.db.SaveLink(link); err != nil {
+	// Check if link already exists for this URL and user. Links with an
+	// expiration rule or a password are never shared since their access
+	// rules differ.
+	existing, err := s.db.ListLinks(ctx, database.LinkQuery{UserID: userID, URL: req.URL})
+	if err == nil && isShareable(link) {
+		for _, existingLink := range existing.Links {
+			if existingLink.URL == req.URL && isShareable(existingLink) {
+				// Return existent link
+				return s.buildResponse(existingLink.Code)
+			}
+		}
+	}
+
+	if err := s.allocateCode(ctx, link); err != nil {
 		return nil, err
 	}
 
+	return s.buildResponse(link.Code)
+}
+
+// allocateCode assigns a free random code to link and saves it. SaveLink is
+// create-only, so a collision is detected atomically and retried with a new
+// code, growing the length whenever the current length is crowded.
+func (s *LinkService) allocateCode(ctx context.Context, link *models.Link) error {
+	length := s.codeConfig.Length
+	collisions := 0
+
+	for attempt := 0; attempt < s.codeConfig.MaxAttempts; attempt++ {
+		code, err := utils.GenerateCode(s.codeConfig.Alphabet, length)
+		if err != nil {
+			return err
+		}
+
+		// A reserved code would be shadowed by a route; treat it as taken
+		if !utils.IsReservedCode(code) {
+			link.Code = code
+			err = s.db.SaveLink(ctx, link)
+			if err == nil {
+				return nil
+			}
+			if !errors.Is(err, database.ErrCodeTaken) {
+				return err
+			}
+		}
+
+		collisions++
+		if collisions >= collisionsBeforeGrowth && length < utils.MaxCodeLength {
+			length++
+			collisions = 0
+		}
+	}
+
+	return ErrCodeSpaceExhausted
+}
+
+// isShareable reports whether a link may be reused for the same URL
+func isShareable(link *models.Link) bool {
+	return !link.HasLifecycle() && !link.IsProtected()
+}
+
+func (s *LinkService) buildResponse(shortCode string) (*models.CreateLinkResponse, error) {
 	shortURL := s.baseURL + "/" + shortCode
 	qrCode, err := s.generateQRCode(shortURL)
 	if err != nil {
@@ -70,36 +213,212 @@ func (s *LinkService) CreateLink(originalURL, userID string) (*models.CreateLink
 	}, nil
 }
 
-func (s *LinkService) GetOriginalURL(shortCode string) (string, error) {
-	link, err := s.db.GetLink(shortCode)
+// ResolveOptions describes the request following a short link
+type ResolveOptions struct {
+	// Unlocked tells whether the caller presented a valid unlock token for
+	// the link; nil if they presented none
+	Unlocked func(link *models.Link) bool
+	// Bot marks automated requests, counted apart from human clicks and
+	// never charged against the click budget
+	Bot bool
+}
+
+// ResolveLink looks up a link for redirection, refusing expired or exhausted
+// links with a GoneError and locked protected links with
+// ErrPasswordRequired. Only clicks charged against a click budget are
+// counted here; the caller hands every other click to the click recorder.
+func (s *LinkService) ResolveLink(ctx context.Context, shortCode string, opts ResolveOptions) (*models.Link, error) {
+	link, err := s.getAvailableLink(ctx, shortCode)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Increment click counter
-	if err := s.db.IncrementClicks(shortCode); err != nil {
-		return "", fmt.Errorf("failed to increment click counter: %w", err)
+	if link.IsProtected() && (opts.Unlocked == nil || !opts.Unlocked(link)) {
+		return nil, ErrPasswordRequired
 	}
 
-	return link.URL, nil
+	if CountsOnResolve(link, opts) {
+		// Concurrent redirects may all have seen the last click left; the
+		// store hands it to one of them
+		err := s.db.IncrementClicks(ctx, shortCode)
+		if errors.Is(err, database.ErrClickLimitReached) {
+			return nil, &GoneError{Reason: "click limit reached", FallbackURL: link.FallbackURL}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to increment click counter: %w", err)
+		}
+	}
+
+	return link, nil
 }
 
-func (s *LinkService) GetUserLinks(userID string) ([]*models.Link, error) {
-	return s.db.GetUserLinks(userID)
+// CountsOnResolve reports whether ResolveLink counted the click itself.
+// Human clicks on links with a click budget are written synchronously,
+// and the store refuses any beyond the budget, so it is enforced exactly.
+func CountsOnResolve(link *models.Link, opts ResolveOptions) bool {
+	return link.MaxClicks > 0 && !opts.Bot
 }
 
-func (s *LinkService) DeleteLink(code, userID string) error {
+// UnlockLink checks the password of a protected link and returns the link
+func (s *LinkService) UnlockLink(ctx context.Context, shortCode, password string) (*models.Link, error) {
+	link, err := s.getAvailableLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if !link.IsProtected() {
+		return link, nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidPassword
+	}
+
+	return link, nil
+}
+
+// getAvailableLink fetches a link and refuses it if expired or exhausted
+func (s *LinkService) getAvailableLink(ctx context.Context, shortCode string) (*models.Link, error) {
+	link, err := s.db.GetLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.IsExpired(time.Now()) {
+		return nil, &GoneError{Reason: "expired", FallbackURL: link.FallbackURL}
+	}
+	if link.IsExhausted() {
+		return nil, &GoneError{Reason: "click limit reached", FallbackURL: link.FallbackURL}
+	}
+
+	return link, nil
+}
+
+// GetUserLink returns a link only if it belongs to userID
+func (s *LinkService) GetUserLink(ctx context.Context, code, userID string) (*models.Link, error) {
+	link, err := s.db.GetLink(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.UserID != userID {
+		return nil, ErrNotLinkOwner
+	}
+
+	return link, nil
+}
+
+// UpdateLink applies a partial update to a link owned by userID. Changing
+// the alias renames the link, keeping its clicks and settings.
+func (s *LinkService) UpdateLink(ctx context.Context, code, userID string, req models.UpdateLinkRequest) (*models.Link, error) {
+	existing, err := s.GetUserLink(ctx, code, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work on a copy so a failed update leaves the stored link untouched
+	link := *existing
+	now := time.Now()
+
+	if req.URL != nil {
+		link.URL = *req.URL
+	}
+	if req.Alias != nil && *req.Alias != code {
+		if err := utils.ValidateAlias(*req.Alias); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAlias, err)
+		}
+		link.Code = *req.Alias
+	}
+	if req.ExpiresAt.Set {
+		if req.ExpiresAt.Value != nil && !req.ExpiresAt.Value.After(now) {
+			return nil, ErrInvalidExpiry
+		}
+		link.ExpiresAt = req.ExpiresAt.Value
+	}
+	if req.MaxClicks != nil {
+		link.MaxClicks = *req.MaxClicks
+	}
+	if req.FallbackURL != nil {
+		link.FallbackURL = *req.FallbackURL
+	}
+	if req.Password != nil {
+		link.PasswordHash = ""
+		if *req.Password != "" {
+			passwordHash, err := hashLinkPassword(*req.Password)
+			if err != nil {
+				return nil, err
+			}
+			link.PasswordHash = passwordHash
+		}
+	}
+	if req.Title != nil {
+		link.Title = *req.Title
+	}
+	if req.Tags != nil {
+		link.Tags = *req.Tags
+	}
+	link.UpdatedAt = now
+
+	renamed := link.Code != code && s.history != nil
+	if renamed {
+		// Clicks still buffered would otherwise be written under the old
+		// code after the rename
+		if err := s.history.Flush(ctx); err != nil {
+			return nil, fmt.Errorf("failed to flush clicks: %w", err)
+		}
+	}
+
+	if err := s.db.UpdateLink(ctx, code, &link); err != nil {
+		if errors.Is(err, database.ErrCodeTaken) {
+			return nil, ErrAliasUnavailable
+		}
+		return nil, err
+	}
+
+	// SQL stores already moved the events with the link; others keep them
+	// apart and move them now
+	if renamed {
+		if err := s.history.ReassignClicks(ctx, code, link.Code); err != nil {
+			return nil, fmt.Errorf("failed to move click history: %w", err)
+		}
+	}
+
+	return &link, nil
+}
+
+// ListLinks returns one page of userID's links. The page size defaults to
+// DefaultPageSize and is capped at MaxPageSize.
+func (s *LinkService) ListLinks(ctx context.Context, userID string, query database.LinkQuery) (*database.LinkPage, error) {
+	query.UserID = userID
+	switch {
+	case query.Limit <= 0:
+		query.Limit = DefaultPageSize
+	case query.Limit > MaxPageSize:
+		query.Limit = MaxPageSize
+	}
+	return s.db.ListLinks(ctx, query)
+}
+
+func (s *LinkService) DeleteLink(ctx context.Context, code, userID string) error {
 	// Check if link belongs to user
-	link, err := s.db.GetLink(code)
+	link, err := s.db.GetLink(ctx, code)
 	if err != nil {
 		return err
 	}
 
 	if link.UserID != userID {
-		return errors.New("unauthorized")
+		return ErrNotLinkOwner
 	}
 
-	return s.db.DeleteLink(code)
+	return s.db.DeleteLink(ctx, code)
+}
+
+func hashLinkPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hashedPassword), nil
 }
 
 func (s *LinkService) generateQRCode(url string) (string, error) {
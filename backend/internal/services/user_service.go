@@ -1,63 +1,19 @@
 package services
 
 import (
-	"crypto/rand"
-	"ecolink-core/internal/models"
-	"ecolink-core/pkg/database"
-	"encoding/hex"
-	"time"
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"ecolink-core/internal/auth/repository"
 )
 
 type UserService struct {
-	db database.Database
+	users repository.UserRepository
 }
 
-func NewUserService(db database.Database) *UserService {
-	return &UserService{db: db}
+func NewUserService(users repository.UserRepository) *UserService {
+	return &UserService{users: users}
 }
 
-func (s *UserService) CreateOrUpdateUser(googleID, name, email, picture string) (*models.User, error) {
-	// Check if user exists
-	existingUser, err := s.db.GetUserByGoogleID(googleID)
-	if err == nil {
-		// Atualiza dados existentes
-		existingUser.Name = name
-		existingUser.Email = email
-		existingUser.Picture = picture
-		existingUser.UpdatedAt = time.Now()
-
-		if err := s.db.SaveUser(existingUser); err != nil {
-			return nil, err
-		}
-		return existingUser, nil
-	}
-
-	// Create new user
-	userID := generateUserID()
-	user := &models.User{
-		ID:        userID,
-		GoogleID:  googleID,
-		Name:      name,
-		Email:     email,
-		Picture:   picture,
-		Provider:  "google",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-
-	if err := s.db.SaveUser(user); err != nil {
-		return nil, err
-	}
-
-	return user, nil
-}
-
-func (s *UserService) GetUser(id string) (*models.User, error) {
-	return s.db.GetUser(id)
-}
-
-func generateUserID() string {
-	bytes := make([]byte, 16)
-	rand.Read(bytes)
-	return hex.EncodeToString(bytes)
+func (s *UserService) GetUser(ctx context.Context, id string) (*domain.User, error) {
+	return s.users.FindByID(ctx, id)
 }
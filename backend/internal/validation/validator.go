@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"ecolink-core/pkg/utils"
 	"fmt"
 	"regexp"
 	"strings"
@@ -53,7 +54,7 @@ func (v *Validator) getErrorMessage(fe validator.FieldError) string {
 	case "url":
 		return fmt.Sprintf("%s must be a valid URL", fe.Field())
 	case "shortcode":
-		return fmt.Sprintf("%s must be a valid short code (6 alphanumeric characters)", fe.Field())
+		return fmt.Sprintf("%s must be a valid short code (%d-%d letters, digits, '-' or '_')", fe.Field(), utils.MinCodeLength, utils.MaxCodeLength)
 	case "url_safe":
 		return fmt.Sprintf("%s contains unsafe characters", fe.Field())
 	default:
@@ -63,17 +64,10 @@ func (v *Validator) getErrorMessage(fe validator.FieldError) string {
 
 // Custom validators
 
-// validateShortCode ensures short codes are exactly 6 alphanumeric characters
+// validateShortCode accepts generated codes and custom aliases
 func validateShortCode(fl validator.FieldLevel) bool {
-	code := fl.Field().String()
-	if len(code) != 6 {
-		return false
-	}
-	
-	// Only allow alphanumeric characters (prevents path traversal)
-	// Using compiled regex for better performance
-	alphanumericPattern := regexp.MustCompile(`^[a-zA-Z0-9]{6}$`)
-	return alphanumericPattern.MatchString(code)
+	// Only alphanumerics plus inner '-' and '_' (prevents path traversal)
+	return utils.ValidateShortCode(fl.Field().String()) == nil
 }
 
 // validateURLSafe ensures strings don't contain dangerous characters
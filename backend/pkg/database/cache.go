@@ -0,0 +1,204 @@
+package database
+
+import (
+	"container/list"
+	"context"
+	"ecolink-core/internal/models"
+	"errors"
+	"sync"
+	"time"
+)
+
+// CacheConfig bounds the link cache
+type CacheConfig struct {
+	Size        int           // Maximum cached codes, found or not
+	TTL         time.Duration // Lifetime of a cached link
+	NegativeTTL time.Duration // Lifetime of a cached "not found"
+}
+
+// CachedDB is a Database whose link lookups are served from an in-process
+// LRU cache. Writes go straight to the wrapped database and invalidate the
+// affected codes. Links with a click budget are never cached so the budget
+// is checked against fresh counters.
+type CachedDB struct {
+	Database
+	config CacheConfig
+
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List // Front is most recently used
+	generation uint64     // Bumped by every invalidation
+}
+
+type cacheEntry struct {
+	code      string
+	link      *models.Link // nil caches ErrLinkNotFound
+	expiresAt time.Time
+}
+
+// NewCachedDB wraps db with a link cache
+func NewCachedDB(db Database, config CacheConfig) *CachedDB {
+	return &CachedDB{
+		Database: db,
+		config:   config,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Unwrap returns the underlying database
+func (c *CachedDB) Unwrap() Database {
+	return c.Database
+}
+
+func (c *CachedDB) GetLink(ctx context.Context, code string) (*models.Link, error) {
+	if link, found, ok := c.lookup(code); ok {
+		if !found {
+			return nil, ErrLinkNotFound
+		}
+		return link, nil
+	}
+
+	generation := c.currentGeneration()
+	link, err := c.Database.GetLink(ctx, code)
+	switch {
+	case errors.Is(err, ErrLinkNotFound):
+		c.store(code, nil, c.config.NegativeTTL, generation)
+	case err == nil && link.MaxClicks == 0:
+		c.store(code, cloneLink(link), c.config.TTL, generation)
+	}
+	return link, err
+}
+
+func (c *CachedDB) SaveLink(ctx context.Context, link *models.Link) error {
+	err := c.Database.SaveLink(ctx, link)
+	c.invalidate(link.Code)
+	return err
+}
+
+func (c *CachedDB) UpdateLink(ctx context.Context, code string, link *models.Link) error {
+	err := c.Database.UpdateLink(ctx, code, link)
+	c.invalidate(code, link.Code)
+	return err
+}
+
+func (c *CachedDB) DeleteLink(ctx context.Context, code string) error {
+	err := c.Database.DeleteLink(ctx, code)
+	c.invalidate(code)
+	return err
+}
+
+func (c *CachedDB) IncrementClicks(ctx context.Context, code string) error {
+	if err := c.Database.IncrementClicks(ctx, code); err != nil {
+		return err
+	}
+	c.addCachedClicks(code, 1, 0)
+	return nil
+}
+
+func (c *CachedDB) AddClicks(ctx context.Context, code string, humans, bots int) error {
+	if err := c.Database.AddClicks(ctx, code, humans, bots); err != nil {
+		return err
+	}
+	c.addCachedClicks(code, humans, bots)
+	return nil
+}
+
+// addCachedClicks keeps cached counters close to the stored ones without a
+// re-read
+func (c *CachedDB) addCachedClicks(code string, humans, bots int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[code]; ok {
+		if entry := element.Value.(*cacheEntry); entry.link != nil {
+			entry.link.Clicks += humans
+			entry.link.BotClicks += bots
+		}
+	}
+}
+
+// lookup returns a copy of a live cached link; found is false for a cached
+// miss and ok is false when the code is not cached
+func (c *CachedDB) lookup(code string) (link *models.Link, found, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, cached := c.entries[code]
+	if !cached {
+		return nil, false, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(element)
+		return nil, false, false
+	}
+
+	c.order.MoveToFront(element)
+	if entry.link == nil {
+		return nil, false, true
+	}
+	return cloneLink(entry.link), true, true
+}
+
+func (c *CachedDB) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// store caches a lookup result unless a write invalidated entries since the
+// lookup started at generation, in which case the result may be stale
+func (c *CachedDB) store(code string, link *models.Link, ttl time.Duration, generation uint64) {
+	if c.config.Size <= 0 || ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	entry := &cacheEntry{code: code, link: link, expiresAt: time.Now().Add(ttl)}
+	if element, ok := c.entries[code]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[code] = c.order.PushFront(entry)
+	for c.order.Len() > c.config.Size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *CachedDB) invalidate(codes ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for _, code := range codes {
+		if element, ok := c.entries[code]; ok {
+			c.remove(element)
+		}
+	}
+}
+
+func (c *CachedDB) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).code)
+}
+
+// cloneLink copies a link so callers cannot mutate cached state
+func cloneLink(link *models.Link) *models.Link {
+	clone := *link
+	if link.ExpiresAt != nil {
+		expiresAt := *link.ExpiresAt
+		clone.ExpiresAt = &expiresAt
+	}
+	clone.Tags = append([]string(nil), link.Tags...)
+	return &clone
+}
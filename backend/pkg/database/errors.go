@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Adapters return errors that match one of these sentinels with errors.Is
+// so callers can react without knowing the backend in use
+var (
+	// ErrNotFound matches lookups, updates and deletes of a missing record
+	ErrNotFound = errors.New("not found")
+	// ErrConflict matches writes that collide with an existing record
+	ErrConflict = errors.New("conflict")
+	// ErrUnavailable matches transient failures (lost connection, timeout,
+	// overload, cancelled request) that may succeed when retried
+	ErrUnavailable = errors.New("storage unavailable")
+)
+
+var (
+	// ErrCodeTaken is returned by SaveLink when the short code is already in use
+	ErrCodeTaken = fmt.Errorf("short code already in use: %w", ErrConflict)
+	// ErrLinkNotFound is returned when no link has the requested code
+	ErrLinkNotFound = fmt.Errorf("link %w", ErrNotFound)
+	// ErrClickLimitReached is returned by IncrementClicks when the link has
+	// used up its click budget
+	ErrClickLimitReached = fmt.Errorf("click limit reached: %w", ErrConflict)
+	// ErrUserNotFound is returned when no user matches the lookup
+	ErrUserNotFound = fmt.Errorf("user %w", ErrNotFound)
+	// ErrUserExists is returned when creating a user whose ID, email or
+	// social profile is already registered
+	ErrUserExists = fmt.Errorf("user already exists: %w", ErrConflict)
+	// ErrRefreshTokenNotFound is returned when no refresh token has the
+	// requested hash
+	ErrRefreshTokenNotFound = fmt.Errorf("refresh token %w", ErrNotFound)
+	// ErrRefreshTokenUsed is returned when using a refresh token that was
+	// already rotated
+	ErrRefreshTokenUsed = fmt.Errorf("refresh token already used: %w", ErrConflict)
+	// ErrSessionNotFound is returned when no session has the requested ID
+	ErrSessionNotFound = fmt.Errorf("session %w", ErrNotFound)
+	// ErrAPIKeyNotFound is returned when no API key matches the lookup
+	ErrAPIKeyNotFound = fmt.Errorf("API key %w", ErrNotFound)
+	// ErrLoginAttemptsNotFound is returned when no failed login was recorded
+	// under a subject
+	ErrLoginAttemptsNotFound = fmt.Errorf("login attempts %w", ErrNotFound)
+	// ErrEmailTokenNotFound is returned when no email token has the
+	// requested hash and purpose
+	ErrEmailTokenNotFound = fmt.Errorf("email token %w", ErrNotFound)
+	// ErrEmailTokenUsed is returned when using an email token that was
+	// already used or superseded
+	ErrEmailTokenUsed = fmt.Errorf("email token already used: %w", ErrConflict)
+	// ErrMFANotFound is returned when a user has no second factor
+	ErrMFANotFound = fmt.Errorf("MFA %w", ErrNotFound)
+	// ErrTOTPStepUsed is returned when accepting a code of a time step no
+	// later than the last one accepted
+	ErrTOTPStepUsed = fmt.Errorf("TOTP code already used: %w", ErrConflict)
+	// ErrRecoveryCodeNotFound is returned when a user has no unused
+	// recovery code with the requested hash
+	ErrRecoveryCodeNotFound = fmt.Errorf("recovery code %w", ErrNotFound)
+	// ErrInvalidQuery is returned for a LinkQuery with an unknown sort or
+	// status, or a negative limit
+	ErrInvalidQuery = errors.New("invalid link query")
+	// ErrInvalidCursor is returned for a cursor that is malformed or was
+	// issued for another sort order
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// unavailable marks err as transient, keeping it in the chain
+func unavailable(err error) error {
+	return fmt.Errorf("%w: %w", ErrUnavailable, err)
+}
+
+// isTransient reports the backend-independent transient failures: the
+// caller's context ending and the connection to the server breaking
+func isTransient(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.As(err, &netErr)
+}
@@ -7,14 +7,18 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type FirestoreDB struct {
 	client *firestore.Client
-	ctx    context.Context
 }
 
+// NewFirestoreDB connects to projectID and merges users left over from the
+// legacy user store
 func NewFirestoreDB(projectID, credentialsPath string) (*FirestoreDB, error) {
 	ctx := context.Background()
 
@@ -31,137 +35,257 @@ func NewFirestoreDB(projectID, credentialsPath string) (*FirestoreDB, error) {
 		return nil, fmt.Errorf("failed to create firestore client: %v", err)
 	}
 
-	return &FirestoreDB{
-		client: client,
-		ctx:    ctx,
-	}, nil
+	db := &FirestoreDB{client: client}
+	if err := db.mergeLegacyUsers(ctx); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to merge legacy users: %w", err)
+	}
+
+	return db, nil
+}
+
+func (db *FirestoreDB) SaveLink(ctx context.Context, link *models.Link) error {
+	// Create fails atomically if the document already exists
+	_, err := db.client.Collection("links").Doc(link.Code).Create(ctx, linkToData(link))
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrCodeTaken
+	}
+	return firestoreError(err, ErrLinkNotFound)
 }
 
-func (db *FirestoreDB) SaveLink(link *models.Link) error {
-	_, err := db.client.Collection("links").Doc(link.Code).Set(db.ctx, map[string]interface{}{
-		"url":        link.URL,
-		"code":       link.Code,
-		"user_id":    link.UserID,
-		"clicks":     link.Clicks,
-		"created_at": link.CreatedAt,
-		"updated_at": time.Now(),
+func (db *FirestoreDB) UpdateLink(ctx context.Context, code string, link *models.Link) error {
+	links := db.client.Collection("links")
+	oldRef := links.Doc(code)
+	newRef := links.Doc(link.Code)
+
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		oldDoc, err := tx.Get(oldRef)
+		if status.Code(err) == codes.NotFound {
+			return ErrLinkNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		// Keep clicks counted since the caller read the link
+		data := linkToData(link)
+		data["clicks"] = oldDoc.Data()["clicks"]
+		if botClicks, ok := oldDoc.Data()["bot_clicks"]; ok {
+			data["bot_clicks"] = botClicks
+		}
+
+		if link.Code == code {
+			return tx.Set(oldRef, data)
+		}
+
+		if _, err := tx.Get(newRef); err == nil {
+			return ErrCodeTaken
+		} else if status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		if err := tx.Create(newRef, data); err != nil {
+			return err
+		}
+		return tx.Delete(oldRef)
 	})
-	return err
+	return firestoreError(err, ErrLinkNotFound)
 }
 
-func (db *FirestoreDB) GetLink(code string) (*models.Link, error) {
-	doc, err := db.client.Collection("links").Doc(code).Get(db.ctx)
+func (db *FirestoreDB) GetLink(ctx context.Context, code string) (*models.Link, error) {
+	doc, err := db.client.Collection("links").Doc(code).Get(ctx)
 	if err != nil {
+		return nil, firestoreError(err, ErrLinkNotFound)
+	}
+
+	return linkFromData(doc.Data()), nil
+}
+
+// ListLinks filters by user, URL, tag and creation date and orders in
+// Firestore, which needs a composite index per combination. Search and
+// status cannot be expressed as Firestore filters and are applied while
+// reading.
+func (db *FirestoreDB) ListLinks(ctx context.Context, query LinkQuery) (*LinkPage, error) {
+	if err := query.Validate(); err != nil {
 		return nil, err
 	}
+	cursor, _ := query.decodeCursor()
 
-	data := doc.Data()
-	link := &models.Link{
-		URL:       data["url"].(string),
-		Code:      data["code"].(string),
-		UserID:    data["user_id"].(string),
-		Clicks:    int(data["clicks"].(int64)),
-		CreatedAt: data["created_at"].(time.Time),
+	q := db.client.Collection("links").Query
+	if query.UserID != "" {
+		q = q.Where("user_id", "==", query.UserID)
+	}
+	if query.URL != "" {
+		q = q.Where("url", "==", query.URL)
+	}
+	if query.Tag != "" {
+		q = q.Where("tags", "array-contains", query.Tag)
+	}
+	if !query.From.IsZero() {
+		q = q.Where("created_at", ">=", query.From)
+	}
+	if !query.To.IsZero() {
+		q = q.Where("created_at", "<", query.To)
 	}
 
-	return link, nil
-}
+	field, direction := "created_at", firestore.Desc
+	if query.sort() == SortByClicks {
+		field = "clicks"
+	}
+	if query.Ascending {
+		direction = firestore.Asc
+	}
+	q = q.OrderBy(field, direction).OrderBy(firestore.DocumentID, direction)
+
+	if cursor != nil {
+		var position interface{} = cursor.CreatedAt
+		if query.sort() == SortByClicks {
+			position = cursor.Clicks
+		}
+		q = q.StartAfter(position, cursor.Code)
+	}
 
-func (db *FirestoreDB) GetUserLinks(userID string) ([]*models.Link, error) {
-	iter := db.client.Collection("links").Where("user_id", "==", userID).OrderBy("created_at", firestore.Desc).Documents(db.ctx)
+	iter := q.Documents(ctx)
+	defer iter.Stop()
 
+	now := time.Now()
 	var links []*models.Link
-	for {
+	for query.Limit == 0 || len(links) <= query.Limit {
 		doc, err := iter.Next()
-		if err != nil {
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return nil, firestoreError(err, ErrLinkNotFound)
+		}
 
-		data := doc.Data()
-		link := &models.Link{
-			URL:       data["url"].(string),
-			Code:      data["code"].(string),
-			UserID:    data["user_id"].(string),
-			Clicks:    int(data["clicks"].(int64)),
-			CreatedAt: data["created_at"].(time.Time),
+		if link := linkFromData(doc.Data()); query.matches(link, now) {
+			links = append(links, link)
 		}
-		links = append(links, link)
 	}
 
-	return links, nil
-}
-
-func (db *FirestoreDB) IncrementClicks(code string) error {
-	_, err := db.client.Collection("links").Doc(code).Update(db.ctx, []firestore.Update{
-		{Path: "clicks", Value: firestore.Increment(1)},
-		{Path: "updated_at", Value: time.Now()},
-	})
-	return err
-}
-
-func (db *FirestoreDB) DeleteLink(code string) error {
-	_, err := db.client.Collection("links").Doc(code).Delete(db.ctx)
-	return err
+	return query.paginate(links, nil), nil
 }
 
-func (db *FirestoreDB) SaveUser(user *models.User) error {
-	_, err := db.client.Collection("users").Doc(user.ID).Set(db.ctx, map[string]interface{}{
-		"id":         user.ID,
-		"google_id":  user.GoogleID,
-		"name":       user.Name,
-		"email":      user.Email,
-		"picture":    user.Picture,
-		"provider":   user.Provider,
-		"created_at": user.CreatedAt,
-		"updated_at": user.UpdatedAt,
-	})
-	return err
+// linkToData maps a Link to its links document
+func linkToData(link *models.Link) map[string]interface{} {
+	return map[string]interface{}{
+		"url":           link.URL,
+		"code":          link.Code,
+		"user_id":       link.UserID,
+		"clicks":        link.Clicks,
+		"bot_clicks":    link.BotClicks,
+		"created_at":    link.CreatedAt,
+		"updated_at":    time.Now(),
+		"expires_at":    link.ExpiresAt,
+		"max_clicks":    link.MaxClicks,
+		"fallback_url":  link.FallbackURL,
+		"password_hash": link.PasswordHash,
+		"title":         link.Title,
+		"tags":          link.Tags,
+	}
 }
 
-func (db *FirestoreDB) GetUser(id string) (*models.User, error) {
-	doc, err := db.client.Collection("users").Doc(id).Get(db.ctx)
-	if err != nil {
-		return nil, err
+// linkFromData maps a links document to a Link. Lifecycle, password and
+// metadata fields are optional since older documents lack them.
+func linkFromData(data map[string]interface{}) *models.Link {
+	link := &models.Link{
+		URL:       data["url"].(string),
+		Code:      data["code"].(string),
+		UserID:    data["user_id"].(string),
+		Clicks:    int(data["clicks"].(int64)),
+		CreatedAt: data["created_at"].(time.Time),
 	}
 
-	data := doc.Data()
-	user := &models.User{
-		ID:        data["id"].(string),
-		GoogleID:  data["google_id"].(string),
-		Name:      data["name"].(string),
-		Email:     data["email"].(string),
-		Picture:   data["picture"].(string),
-		Provider:  data["provider"].(string),
-		CreatedAt: data["created_at"].(time.Time),
-		UpdatedAt: data["updated_at"].(time.Time),
+	if botClicks, ok := data["bot_clicks"].(int64); ok {
+		link.BotClicks = int(botClicks)
+	}
+	if expiresAt, ok := data["expires_at"].(time.Time); ok {
+		link.ExpiresAt = &expiresAt
+	}
+	if maxClicks, ok := data["max_clicks"].(int64); ok {
+		link.MaxClicks = int(maxClicks)
+	}
+	if fallbackURL, ok := data["fallback_url"].(string); ok {
+		link.FallbackURL = fallbackURL
+	}
+	if passwordHash, ok := data["password_hash"].(string); ok {
+		link.PasswordHash = passwordHash
+	}
+	if title, ok := data["title"].(string); ok {
+		link.Title = title
+	}
+	if tags, ok := data["tags"].([]interface{}); ok {
+		for _, tag := range tags {
+			if tag, ok := tag.(string); ok {
+				link.Tags = append(link.Tags, tag)
+			}
+		}
+	}
+	if updatedAt, ok := data["updated_at"].(time.Time); ok {
+		link.UpdatedAt = updatedAt
 	}
 
-	return user, nil
+	return link
 }
 
-func (db *FirestoreDB) GetUserByGoogleID(googleID string) (*models.User, error) {
-	iter := db.client.Collection("users").Where("google_id", "==", googleID).Limit(1).Documents(db.ctx)
+func (db *FirestoreDB) IncrementClicks(ctx context.Context, code string) error {
+	ref := db.client.Collection("links").Doc(code)
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		if linkFromData(doc.Data()).IsExhausted() {
+			return ErrClickLimitReached
+		}
+		return tx.Update(ref, []firestore.Update{
+			{Path: "clicks", Value: firestore.Increment(1)},
+			{Path: "updated_at", Value: time.Now()},
+		})
+	})
+	return firestoreError(err, ErrLinkNotFound)
+}
 
-	doc, err := iter.Next()
-	if err != nil {
-		return nil, err
-	}
+func (db *FirestoreDB) AddClicks(ctx context.Context, code string, humans, bots int) error {
+	_, err := db.client.Collection("links").Doc(code).Update(ctx, []firestore.Update{
+		{Path: "clicks", Value: firestore.Increment(humans)},
+		{Path: "bot_clicks", Value: firestore.Increment(bots)},
+		{Path: "updated_at", Value: time.Now()},
+	})
+	return firestoreError(err, ErrLinkNotFound)
+}
 
-	data := doc.Data()
-	user := &models.User{
-		ID:        data["id"].(string),
-		GoogleID:  data["google_id"].(string),
-		Name:      data["name"].(string),
-		Email:     data["email"].(string),
-		Picture:   data["picture"].(string),
-		Provider:  data["provider"].(string),
-		CreatedAt: data["created_at"].(time.Time),
-		UpdatedAt: data["updated_at"].(time.Time),
-	}
+func (db *FirestoreDB) DeleteLink(ctx context.Context, code string) error {
+	// Delete succeeds on a missing document unless it must exist
+	_, err := db.client.Collection("links").Doc(code).Delete(ctx, firestore.Exists)
+	return firestoreError(err, ErrLinkNotFound)
+}
 
-	return user, nil
+// Client exposes the underlying client so other repositories can share it
+func (db *FirestoreDB) Client() *firestore.Client {
+	return db.client
 }
 
 func (db *FirestoreDB) Close() error {
 	return db.client.Close()
 }
+
+// firestoreError maps gRPC status codes onto the storage sentinels, using
+// notFound for a missing document
+func firestoreError(err error, notFound error) error {
+	switch status.Code(err) {
+	case codes.OK:
+		return err
+	case codes.NotFound:
+		return notFound
+	case codes.AlreadyExists:
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Canceled:
+		return unavailable(err)
+	}
+	if isTransient(err) {
+		return unavailable(err)
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreDB also implements the auth module's repository.APIKeyRepository,
+// keying auth_api_keys documents by key ID. Keys are random, so their hashes
+// are unique without an index enforcing it.
+
+func (db *FirestoreDB) CreateAPIKey(ctx context.Context, key *domain.APIKey) error {
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now().UTC()
+	}
+
+	data := map[string]interface{}{
+		"user_id":    key.UserID,
+		"name":       key.Name,
+		"prefix":     key.Prefix,
+		"key_hash":   key.KeyHash,
+		"scopes":     key.Scopes,
+		"created_at": key.CreatedAt,
+	}
+	if key.ExpiresAt != nil {
+		data["expires_at"] = *key.ExpiresAt
+	}
+	if key.LastUsedAt != nil {
+		data["last_used_at"] = *key.LastUsedAt
+	}
+
+	_, err := db.apiKeyRef(key.ID).Create(ctx, data)
+	return firestoreError(err, ErrAPIKeyNotFound)
+}
+
+func (db *FirestoreDB) FindAPIKeyByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	iter := db.client.Collection("auth_api_keys").Where("key_hash", "==", keyHash).Limit(1).Documents(ctx)
+	defer iter.Stop()
+
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, firestoreError(err, ErrAPIKeyNotFound)
+	}
+	return apiKeyFromDoc(doc), nil
+}
+
+func (db *FirestoreDB) ListUserAPIKeys(ctx context.Context, userID string) ([]*domain.APIKey, error) {
+	// A user has few keys; sorting them in process needs no composite index
+	iter := db.client.Collection("auth_api_keys").Where("user_id", "==", userID).Documents(ctx)
+	defer iter.Stop()
+
+	var keys []*domain.APIKey
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, firestoreError(err, ErrAPIKeyNotFound)
+		}
+		keys = append(keys, apiKeyFromDoc(doc))
+	}
+	sortAPIKeys(keys)
+	return keys, nil
+}
+
+func (db *FirestoreDB) TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error {
+	_, err := db.apiKeyRef(keyID).Update(ctx, []firestore.Update{{Path: "last_used_at", Value: usedAt}})
+	return firestoreError(err, ErrAPIKeyNotFound)
+}
+
+func (db *FirestoreDB) DeleteAPIKey(ctx context.Context, userID, keyID string) error {
+	ref := db.apiKeyRef(keyID)
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrAPIKeyNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if apiKeyFromDoc(doc).UserID != userID {
+			return ErrAPIKeyNotFound
+		}
+		return tx.Delete(ref)
+	})
+	return firestoreError(err, ErrAPIKeyNotFound)
+}
+
+func (db *FirestoreDB) DeleteUserAPIKeys(ctx context.Context, userID string) error {
+	iter := db.client.Collection("auth_api_keys").Where("user_id", "==", userID).Documents(ctx)
+	defer iter.Stop()
+
+	bw := db.client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bw.End()
+			return firestoreError(err, ErrAPIKeyNotFound)
+		}
+
+		job, err := bw.Delete(doc.Ref)
+		if err != nil {
+			bw.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return firestoreError(err, ErrAPIKeyNotFound)
+		}
+	}
+	return nil
+}
+
+func (db *FirestoreDB) apiKeyRef(keyID string) *firestore.DocumentRef {
+	return db.client.Collection("auth_api_keys").Doc(keyID)
+}
+
+func apiKeyFromDoc(doc *firestore.DocumentSnapshot) *domain.APIKey {
+	data := doc.Data()
+	key := &domain.APIKey{ID: doc.Ref.ID}
+	key.UserID, _ = data["user_id"].(string)
+	key.Name, _ = data["name"].(string)
+	key.Prefix, _ = data["prefix"].(string)
+	key.KeyHash, _ = data["key_hash"].(string)
+	key.CreatedAt, _ = data["created_at"].(time.Time)
+	if scopes, ok := data["scopes"].([]interface{}); ok {
+		for _, scope := range scopes {
+			if scope, ok := scope.(string); ok {
+				key.Scopes = append(key.Scopes, scope)
+			}
+		}
+	}
+	if expiresAt, ok := data["expires_at"].(time.Time); ok {
+		key.ExpiresAt = &expiresAt
+	}
+	if lastUsedAt, ok := data["last_used_at"].(time.Time); ok {
+		key.LastUsedAt = &lastUsedAt
+	}
+	return key
+}
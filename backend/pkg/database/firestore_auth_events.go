@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// FirestoreDB also implements the auth module's repository.AuthEventRepository
+// on auth_events. Listing a user's events needs a composite index on
+// user_id and created_at (descending).
+
+func (db *FirestoreDB) RecordAuthEvent(ctx context.Context, event *domain.AuthEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	_, err := db.client.Collection("auth_events").Doc(event.ID).Create(ctx, map[string]interface{}{
+		"type":       event.Type,
+		"user_id":    event.UserID,
+		"email":      event.Email,
+		"ip_prefix":  event.IPPrefix,
+		"user_agent": event.UserAgent,
+		"created_at": event.CreatedAt,
+	})
+	return firestoreError(err, ErrNotFound)
+}
+
+func (db *FirestoreDB) ListUserAuthEvents(ctx context.Context, userID string, limit int) ([]*domain.AuthEvent, error) {
+	iter := db.client.Collection("auth_events").
+		Where("user_id", "==", userID).
+		OrderBy("created_at", firestore.Desc).
+		Limit(limit).
+		Documents(ctx)
+	defer iter.Stop()
+
+	var events []*domain.AuthEvent
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, firestoreError(err, ErrNotFound)
+		}
+
+		data := doc.Data()
+		event := &domain.AuthEvent{ID: doc.Ref.ID}
+		event.Type, _ = data["type"].(string)
+		event.UserID, _ = data["user_id"].(string)
+		event.Email, _ = data["email"].(string)
+		event.IPPrefix, _ = data["ip_prefix"].(string)
+		event.UserAgent, _ = data["user_agent"].(string)
+		event.CreatedAt, _ = data["created_at"].(time.Time)
+		events = append(events, event)
+	}
+	return events, nil
+}
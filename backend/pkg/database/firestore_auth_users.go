@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"net/url"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreDB also implements the auth module's repository.UserRepository.
+// Firestore has no unique indexes, so emails and social profiles are
+// claimed with one document each (auth_emails, auth_social_profiles),
+// created in the same transaction as the user.
+
+func (db *FirestoreDB) FindByEmail(ctx context.Context, email string) (*domain.User, *domain.Credential, error) {
+	userID, err := db.claimedBy(ctx, db.emailRef(email))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc, err := db.client.Collection("auth_users").Doc(userID).Get(ctx)
+	if err != nil {
+		return nil, nil, firestoreError(err, ErrUserNotFound)
+	}
+
+	user := authUserFromData(doc.Data())
+	// Users who only signed in with a social provider have no credential
+	var cred *domain.Credential
+	if passwordHash, ok := doc.Data()["password_hash"].(string); ok && passwordHash != "" {
+		cred = &domain.Credential{UserID: user.ID, PasswordHash: passwordHash}
+	}
+	return user, cred, nil
+}
+
+func (db *FirestoreDB) FindByProviderID(ctx context.Context, provider, providerID string) (*domain.User, error) {
+	userID, err := db.claimedBy(ctx, db.socialRef(provider, providerID))
+	if err != nil {
+		return nil, err
+	}
+	return db.FindByID(ctx, userID)
+}
+
+func (db *FirestoreDB) FindByID(ctx context.Context, userID string) (*domain.User, error) {
+	doc, err := db.client.Collection("auth_users").Doc(userID).Get(ctx)
+	if err != nil {
+		return nil, firestoreError(err, ErrUserNotFound)
+	}
+	return authUserFromData(doc.Data()), nil
+}
+
+func (db *FirestoreDB) CreateUser(ctx context.Context, user *domain.User, cred *domain.Credential) error {
+	return db.createAuthUser(ctx, user, cred, nil)
+}
+
+func (db *FirestoreDB) CreateUserFromSocial(ctx context.Context, user *domain.User, social *domain.SocialProfile) (*domain.User, error) {
+	if err := db.createAuthUser(ctx, user, nil, social); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (db *FirestoreDB) UpdateUser(ctx context.Context, user *domain.User) error {
+	userRef := db.client.Collection("auth_users").Doc(user.ID)
+	user.UpdatedAt = time.Now().UTC()
+
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(userRef)
+		if status.Code(err) == codes.NotFound {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		// Move the email claim along with a changed email
+		if previous, _ := doc.Data()["email"].(string); previous != user.Email {
+			if err := tx.Create(db.emailRef(user.Email), map[string]interface{}{"user_id": user.ID}); err != nil {
+				return err
+			}
+			if err := tx.Delete(db.emailRef(previous)); err != nil {
+				return err
+			}
+		}
+
+		return tx.Update(userRef, []firestore.Update{
+			{Path: "email", Value: user.Email},
+			{Path: "name", Value: user.Name},
+			{Path: "picture", Value: user.Picture},
+			{Path: "updated_at", Value: user.UpdatedAt},
+		})
+	})
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrUserExists
+	}
+	return firestoreError(err, ErrUserNotFound)
+}
+
+func (db *FirestoreDB) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	_, err := db.client.Collection("auth_users").Doc(userID).Update(ctx, []firestore.Update{
+		{Path: "password_hash", Value: passwordHash},
+		{Path: "updated_at", Value: time.Now().UTC()},
+	})
+	return firestoreError(err, ErrUserNotFound)
+}
+
+func (db *FirestoreDB) MarkEmailVerified(ctx context.Context, userID string, verifiedAt time.Time) error {
+	userRef := db.client.Collection("auth_users").Doc(userID)
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(userRef)
+		if err != nil {
+			return err
+		}
+		if authUserFromData(doc.Data()).EmailVerifiedAt != nil {
+			return nil
+		}
+		return tx.Update(userRef, []firestore.Update{{Path: "email_verified_at", Value: verifiedAt}})
+	})
+	return firestoreError(err, ErrUserNotFound)
+}
+
+// createAuthUser stores a new user with its email claim and, when given,
+// its credential and social profile claim
+func (db *FirestoreDB) createAuthUser(ctx context.Context, user *domain.User, cred *domain.Credential, social *domain.SocialProfile) error {
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now().UTC()
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = user.CreatedAt
+	}
+
+	data := map[string]interface{}{
+		"id":         user.ID,
+		"email":      user.Email,
+		"name":       user.Name,
+		"picture":    user.Picture,
+		"created_at": user.CreatedAt,
+		"updated_at": user.UpdatedAt,
+	}
+	if cred != nil {
+		data["password_hash"] = cred.PasswordHash
+	}
+	if user.EmailVerifiedAt != nil {
+		data["email_verified_at"] = *user.EmailVerifiedAt
+	}
+
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Create(db.client.Collection("auth_users").Doc(user.ID), data); err != nil {
+			return err
+		}
+		if err := tx.Create(db.emailRef(user.Email), map[string]interface{}{"user_id": user.ID}); err != nil {
+			return err
+		}
+		if social == nil {
+			return nil
+		}
+		return tx.Create(db.socialRef(social.Provider, social.ProviderID), map[string]interface{}{
+			"provider":    social.Provider,
+			"provider_id": social.ProviderID,
+			"user_id":     user.ID,
+		})
+	})
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrUserExists
+	}
+	return firestoreError(err, ErrUserNotFound)
+}
+
+// claimedBy returns the user holding an email or social profile claim
+func (db *FirestoreDB) claimedBy(ctx context.Context, ref *firestore.DocumentRef) (string, error) {
+	doc, err := ref.Get(ctx)
+	if err != nil {
+		return "", firestoreError(err, ErrUserNotFound)
+	}
+	userID, _ := doc.Data()["user_id"].(string)
+	return userID, nil
+}
+
+// emailRef returns the claim of an email. Document IDs cannot contain
+// slashes, so claim keys are escaped.
+func (db *FirestoreDB) emailRef(email string) *firestore.DocumentRef {
+	return db.client.Collection("auth_emails").Doc(url.PathEscape(email))
+}
+
+// socialRef returns the claim of a provider account
+func (db *FirestoreDB) socialRef(provider, providerID string) *firestore.DocumentRef {
+	return db.client.Collection("auth_social_profiles").Doc(url.PathEscape(provider) + ":" + url.PathEscape(providerID))
+}
+
+func authUserFromData(data map[string]interface{}) *domain.User {
+	user := &domain.User{}
+	user.ID, _ = data["id"].(string)
+	user.Email, _ = data["email"].(string)
+	user.Name, _ = data["name"].(string)
+	user.Picture, _ = data["picture"].(string)
+	user.CreatedAt, _ = data["created_at"].(time.Time)
+	user.UpdatedAt, _ = data["updated_at"].(time.Time)
+	if verifiedAt, ok := data["email_verified_at"].(time.Time); ok {
+		user.EmailVerifiedAt = &verifiedAt
+	}
+	return user
+}
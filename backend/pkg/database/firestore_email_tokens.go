@@ -0,0 +1,168 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreDB also implements the auth module's
+// repository.EmailTokenRepository, keying auth_email_tokens documents by
+// token hash; a TTL policy on expires_at can delete them once they expire
+
+func (db *FirestoreDB) CreateEmailToken(ctx context.Context, token *domain.EmailToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now().UTC()
+	}
+
+	data := map[string]interface{}{
+		"user_id":    token.UserID,
+		"email":      token.Email,
+		"purpose":    token.Purpose,
+		"created_at": token.CreatedAt,
+		"expires_at": token.ExpiresAt,
+	}
+	if token.IsUsed() {
+		data["used_at"] = token.UsedAt
+	}
+
+	_, err := db.emailTokenRef(token.TokenHash).Create(ctx, data)
+	return firestoreError(err, ErrEmailTokenNotFound)
+}
+
+func (db *FirestoreDB) UseEmailToken(ctx context.Context, tokenHash, purpose string, usedAt time.Time) (*domain.EmailToken, error) {
+	ref := db.emailTokenRef(tokenHash)
+	var token *domain.EmailToken
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrEmailTokenNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		token = emailTokenFromDoc(doc)
+		if token.Purpose != purpose {
+			return ErrEmailTokenNotFound
+		}
+		if token.IsUsed() {
+			return ErrEmailTokenUsed
+		}
+		token.UsedAt = usedAt
+		return tx.Update(ref, []firestore.Update{{Path: "used_at", Value: usedAt}})
+	})
+	if err != nil {
+		return nil, firestoreError(err, ErrEmailTokenNotFound)
+	}
+	return token, nil
+}
+
+func (db *FirestoreDB) FindEmailToken(ctx context.Context, tokenHash, purpose string) (*domain.EmailToken, error) {
+	doc, err := db.emailTokenRef(tokenHash).Get(ctx)
+	if err != nil {
+		return nil, firestoreError(err, ErrEmailTokenNotFound)
+	}
+	token := emailTokenFromDoc(doc)
+	if token.Purpose != purpose {
+		return nil, ErrEmailTokenNotFound
+	}
+	return token, nil
+}
+
+func (db *FirestoreDB) ResetPasswordWithToken(ctx context.Context, tokenHash, passwordHash string, usedAt time.Time) error {
+	ref := db.emailTokenRef(tokenHash)
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrEmailTokenNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		token := emailTokenFromDoc(doc)
+		if token.Purpose != domain.EmailTokenResetPassword {
+			return ErrEmailTokenNotFound
+		}
+		if token.IsUsed() {
+			return ErrEmailTokenUsed
+		}
+
+		userRef := db.client.Collection("auth_users").Doc(token.UserID)
+		if _, err := tx.Get(userRef); status.Code(err) == codes.NotFound {
+			return ErrUserNotFound
+		} else if err != nil {
+			return err
+		}
+
+		if err := tx.Update(ref, []firestore.Update{{Path: "used_at", Value: usedAt}}); err != nil {
+			return err
+		}
+		return tx.Update(userRef, []firestore.Update{
+			{Path: "password_hash", Value: passwordHash},
+			{Path: "updated_at", Value: time.Now().UTC()},
+		})
+	})
+	return firestoreError(err, ErrEmailTokenNotFound)
+}
+
+func (db *FirestoreDB) InvalidateEmailTokens(ctx context.Context, userID, purpose string, usedAt time.Time) error {
+	iter := db.client.Collection("auth_email_tokens").
+		Where("user_id", "==", userID).
+		Where("purpose", "==", purpose).
+		Documents(ctx)
+	defer iter.Stop()
+
+	bw := db.client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bw.End()
+			return firestoreError(err, ErrEmailTokenNotFound)
+		}
+		if emailTokenFromDoc(doc).IsUsed() {
+			continue
+		}
+
+		job, err := bw.Update(doc.Ref, []firestore.Update{{Path: "used_at", Value: usedAt}})
+		if err != nil {
+			bw.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return firestoreError(err, ErrEmailTokenNotFound)
+		}
+	}
+	return nil
+}
+
+func (db *FirestoreDB) emailTokenRef(tokenHash string) *firestore.DocumentRef {
+	return db.client.Collection("auth_email_tokens").Doc(tokenHash)
+}
+
+func emailTokenFromDoc(doc *firestore.DocumentSnapshot) *domain.EmailToken {
+	data := doc.Data()
+	token := &domain.EmailToken{TokenHash: doc.Ref.ID}
+	token.UserID, _ = data["user_id"].(string)
+	token.Email, _ = data["email"].(string)
+	token.Purpose, _ = data["purpose"].(string)
+	token.CreatedAt, _ = data["created_at"].(time.Time)
+	token.ExpiresAt, _ = data["expires_at"].(time.Time)
+	token.UsedAt, _ = data["used_at"].(time.Time)
+	return token
+}
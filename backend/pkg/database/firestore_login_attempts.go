@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"net/url"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreDB also implements the auth module's
+// repository.LoginAttemptRepository. Counters are auth_login_attempts
+// documents keyed by the escaped subject; a TTL policy on last_failure_at
+// can delete the old ones.
+
+func (db *FirestoreDB) FindLoginAttempts(ctx context.Context, subject string) (*domain.LoginAttempts, error) {
+	doc, err := db.loginAttemptsRef(subject).Get(ctx)
+	if err != nil {
+		return nil, firestoreError(err, ErrLoginAttemptsNotFound)
+	}
+	return loginAttemptsFromDoc(subject, doc), nil
+}
+
+func (db *FirestoreDB) RecordLoginFailure(ctx context.Context, subject string, failedAt, resetBefore time.Time) (*domain.LoginAttempts, error) {
+	ref := db.loginAttemptsRef(subject)
+	var attempts *domain.LoginAttempts
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		attempts = &domain.LoginAttempts{Subject: subject}
+		doc, err := tx.Get(ref)
+		if err == nil {
+			attempts = loginAttemptsFromDoc(subject, doc)
+		} else if status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		if attempts.LastFailureAt.Before(resetBefore) {
+			attempts.Failures = 0
+		}
+		attempts.Failures++
+		attempts.LastFailureAt = failedAt
+		return tx.Set(ref, map[string]interface{}{
+			"failures":        attempts.Failures,
+			"last_failure_at": failedAt,
+		}, firestore.MergeAll)
+	})
+	if err != nil {
+		return nil, firestoreError(err, ErrLoginAttemptsNotFound)
+	}
+	return attempts, nil
+}
+
+func (db *FirestoreDB) LockLogin(ctx context.Context, subject string, until time.Time) error {
+	_, err := db.loginAttemptsRef(subject).Update(ctx, []firestore.Update{{Path: "locked_until", Value: until}})
+	return firestoreError(err, ErrLoginAttemptsNotFound)
+}
+
+func (db *FirestoreDB) ClearLoginAttempts(ctx context.Context, subject string) error {
+	_, err := db.loginAttemptsRef(subject).Delete(ctx)
+	return firestoreError(err, ErrLoginAttemptsNotFound)
+}
+
+// loginAttemptsRef escapes subject, as emails may contain slashes
+func (db *FirestoreDB) loginAttemptsRef(subject string) *firestore.DocumentRef {
+	return db.client.Collection("auth_login_attempts").Doc(url.PathEscape(subject))
+}
+
+func loginAttemptsFromDoc(subject string, doc *firestore.DocumentSnapshot) *domain.LoginAttempts {
+	data := doc.Data()
+	attempts := &domain.LoginAttempts{Subject: subject}
+	if failures, ok := data["failures"].(int64); ok {
+		attempts.Failures = int(failures)
+	}
+	attempts.LastFailureAt, _ = data["last_failure_at"].(time.Time)
+	attempts.LockedUntil, _ = data["locked_until"].(time.Time)
+	return attempts
+}
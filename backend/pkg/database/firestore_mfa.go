@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreDB also implements the auth module's repository.MFARepository,
+// keying auth_mfa documents by user ID. The hashes of the unused recovery
+// codes live in the document, so spending one is a single transaction.
+
+func (db *FirestoreDB) FindMFA(ctx context.Context, userID string) (*domain.MFA, error) {
+	doc, err := db.mfaRef(userID).Get(ctx)
+	if err != nil {
+		return nil, firestoreError(err, ErrMFANotFound)
+	}
+	return mfaFromDoc(doc), nil
+}
+
+func (db *FirestoreDB) SaveMFA(ctx context.Context, mfa *domain.MFA) error {
+	if mfa.CreatedAt.IsZero() {
+		mfa.CreatedAt = time.Now().UTC()
+	}
+
+	data := map[string]interface{}{
+		"secret":         mfa.Secret,
+		"created_at":     mfa.CreatedAt,
+		"last_step":      mfa.LastStep,
+		"recovery_codes": []string{},
+	}
+	if mfa.IsEnabled() {
+		data["enabled_at"] = mfa.EnabledAt
+	}
+
+	_, err := db.mfaRef(mfa.UserID).Set(ctx, data)
+	return firestoreError(err, ErrMFANotFound)
+}
+
+func (db *FirestoreDB) EnableMFA(ctx context.Context, userID string, enabledAt time.Time, codeHashes []string) error {
+	_, err := db.mfaRef(userID).Update(ctx, []firestore.Update{
+		{Path: "enabled_at", Value: enabledAt},
+		{Path: "recovery_codes", Value: codeHashes},
+	})
+	return firestoreError(err, ErrMFANotFound)
+}
+
+func (db *FirestoreDB) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	ref := db.mfaRef(userID)
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		if step <= mfaFromDoc(doc).LastStep {
+			return ErrTOTPStepUsed
+		}
+		return tx.Update(ref, []firestore.Update{{Path: "last_step", Value: step}})
+	})
+	return firestoreError(err, ErrMFANotFound)
+}
+
+func (db *FirestoreDB) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	_, err := db.mfaRef(userID).Update(ctx, []firestore.Update{{Path: "recovery_codes", Value: codeHashes}})
+	return firestoreError(err, ErrMFANotFound)
+}
+
+func (db *FirestoreDB) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	ref := db.mfaRef(userID)
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+
+		hashes := recoveryCodesFromDoc(doc)
+		i := slices.Index(hashes, codeHash)
+		if i < 0 {
+			return ErrRecoveryCodeNotFound
+		}
+		return tx.Update(ref, []firestore.Update{{Path: "recovery_codes", Value: slices.Delete(hashes, i, i+1)}})
+	})
+	return firestoreError(err, ErrRecoveryCodeNotFound)
+}
+
+func (db *FirestoreDB) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	doc, err := db.mfaRef(userID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, firestoreError(err, ErrMFANotFound)
+	}
+	return len(recoveryCodesFromDoc(doc)), nil
+}
+
+func (db *FirestoreDB) DeleteMFA(ctx context.Context, userID string) error {
+	// Delete succeeds for missing documents unless it must exist
+	_, err := db.mfaRef(userID).Delete(ctx, firestore.Exists)
+	return firestoreError(err, ErrMFANotFound)
+}
+
+func (db *FirestoreDB) mfaRef(userID string) *firestore.DocumentRef {
+	return db.client.Collection("auth_mfa").Doc(userID)
+}
+
+func mfaFromDoc(doc *firestore.DocumentSnapshot) *domain.MFA {
+	data := doc.Data()
+	mfa := &domain.MFA{UserID: doc.Ref.ID}
+	mfa.Secret, _ = data["secret"].([]byte)
+	mfa.CreatedAt, _ = data["created_at"].(time.Time)
+	mfa.EnabledAt, _ = data["enabled_at"].(time.Time)
+	mfa.LastStep, _ = data["last_step"].(int64)
+	return mfa
+}
+
+func recoveryCodesFromDoc(doc *firestore.DocumentSnapshot) []string {
+	values, _ := doc.Data()["recovery_codes"].([]interface{})
+	hashes := make([]string, 0, len(values))
+	for _, value := range values {
+		if hash, ok := value.(string); ok {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
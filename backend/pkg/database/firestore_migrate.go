@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// legacyEmailDomain makes up the addresses of legacy users whose email
+// cannot be kept, like migration 0005 of the SQL stores. The .invalid TLD
+// is reserved, so no real address collides with one.
+const legacyEmailDomain = "legacy.invalid"
+
+// mergeLegacyUsers folds the legacy "users" collection into the auth
+// accounts, like migration 0005 of the SQL stores. Each legacy user is
+// deleted once merged, so the migration resumes where it stopped and is a
+// no-op after the first complete run.
+func (db *FirestoreDB) mergeLegacyUsers(ctx context.Context) error {
+	iter := db.client.Collection("users").Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return firestoreError(err, ErrUserNotFound)
+		}
+		if err := db.mergeLegacyUser(ctx, doc); err != nil {
+			return fmt.Errorf("failed to merge legacy user %s: %w", doc.Ref.ID, err)
+		}
+	}
+}
+
+// mergeLegacyUser moves one legacy user to the account with the same ID,
+// or to the one holding its email if that address was verified, creating
+// an account under the legacy ID otherwise.
+func (db *FirestoreDB) mergeLegacyUser(ctx context.Context, doc *firestore.DocumentSnapshot) error {
+	data := doc.Data()
+	legacyID := doc.Ref.ID
+	email, _ := data["email"].(string)
+	googleID, _ := data["google_id"].(string)
+
+	accountID, err := db.legacyAccount(ctx, legacyID, email)
+	if errors.Is(err, ErrNotFound) {
+		accountID, err = legacyID, db.copyLegacyUser(ctx, legacyID, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	if googleID != "" {
+		_, err := db.socialRef("google", googleID).Create(ctx, map[string]interface{}{
+			"provider":    "google",
+			"provider_id": googleID,
+			"user_id":     accountID,
+		})
+		if err != nil && status.Code(err) != codes.AlreadyExists {
+			return firestoreError(err, ErrUserNotFound)
+		}
+	}
+
+	if accountID != legacyID {
+		if err := db.reassignLinks(ctx, legacyID, accountID); err != nil {
+			return err
+		}
+	}
+
+	_, err = doc.Ref.Delete(ctx)
+	return firestoreError(err, ErrUserNotFound)
+}
+
+// legacyAccount returns the account a legacy user merges into. Local
+// sign-ups were not verified before, so anyone could have registered a
+// stranger's email: an account holding the legacy email only qualifies
+// once the address was verified, by link or by signing in with Google.
+func (db *FirestoreDB) legacyAccount(ctx context.Context, legacyID, email string) (string, error) {
+	if _, err := db.FindByID(ctx, legacyID); !errors.Is(err, ErrNotFound) {
+		return legacyID, err
+	}
+	if email == "" {
+		return "", ErrUserNotFound
+	}
+
+	accountID, err := db.claimedBy(ctx, db.emailRef(email))
+	if err != nil {
+		return "", err
+	}
+	user, err := db.FindByID(ctx, accountID)
+	if err != nil {
+		return "", err
+	}
+	if user.EmailVerifiedAt != nil {
+		return accountID, nil
+	}
+
+	profiles, err := db.client.Collection("auth_social_profiles").Where("user_id", "==", accountID).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return "", firestoreError(err, ErrUserNotFound)
+	}
+	if len(profiles) == 0 {
+		return "", ErrUserNotFound
+	}
+	return accountID, nil
+}
+
+// copyLegacyUser creates the account of a legacy user under its ID. It keeps
+// its email unless another account holds it or it has none; the account
+// then gets a placeholder address and still signs in with Google.
+func (db *FirestoreDB) copyLegacyUser(ctx context.Context, legacyID string, data map[string]interface{}) error {
+	user := authUserFromData(data)
+	user.ID = legacyID
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now().UTC()
+	}
+
+	if user.Email != "" {
+		if err := db.createAuthUser(ctx, user, nil, nil); !errors.Is(err, ErrUserExists) {
+			return err
+		}
+	}
+	user.Email = legacyID + "@" + legacyEmailDomain
+	return db.createAuthUser(ctx, user, nil, nil)
+}
+
+// reassignLinks hands every link of one user to another
+func (db *FirestoreDB) reassignLinks(ctx context.Context, fromUserID, toUserID string) error {
+	iter := db.client.Collection("links").Where("user_id", "==", fromUserID).Documents(ctx)
+	defer iter.Stop()
+
+	bw := db.client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bw.End()
+			return firestoreError(err, ErrLinkNotFound)
+		}
+
+		job, err := bw.Update(doc.Ref, []firestore.Update{{Path: "user_id", Value: toUserID}})
+		if err != nil {
+			bw.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return firestoreError(err, ErrLinkNotFound)
+		}
+	}
+	return nil
+}
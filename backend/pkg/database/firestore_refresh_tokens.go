@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreDB also implements the auth module's
+// repository.RefreshTokenRepository, keying auth_refresh_tokens documents
+// by token hash
+
+func (db *FirestoreDB) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now().UTC()
+	}
+
+	data := map[string]interface{}{
+		"family_id":  token.FamilyID,
+		"user_id":    token.UserID,
+		"created_at": token.CreatedAt,
+		"expires_at": token.ExpiresAt,
+	}
+	if token.IsUsed() {
+		data["used_at"] = token.UsedAt
+	}
+	if token.IsRevoked() {
+		data["revoked_at"] = token.RevokedAt
+	}
+
+	_, err := db.refreshRef(token.TokenHash).Create(ctx, data)
+	return firestoreError(err, ErrRefreshTokenNotFound)
+}
+
+func (db *FirestoreDB) FindRefreshToken(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	doc, err := db.refreshRef(tokenHash).Get(ctx)
+	if err != nil {
+		return nil, firestoreError(err, ErrRefreshTokenNotFound)
+	}
+	return refreshTokenFromDoc(doc), nil
+}
+
+func (db *FirestoreDB) UseRefreshToken(ctx context.Context, tokenHash string, usedAt time.Time) error {
+	ref := db.refreshRef(tokenHash)
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrRefreshTokenNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if refreshTokenFromDoc(doc).IsUsed() {
+			return ErrRefreshTokenUsed
+		}
+		return tx.Update(ref, []firestore.Update{{Path: "used_at", Value: usedAt}})
+	})
+	return firestoreError(err, ErrRefreshTokenNotFound)
+}
+
+func (db *FirestoreDB) RevokeRefreshTokenFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	return db.revokeRefreshTokens(ctx, "family_id", familyID, revokedAt)
+}
+
+func (db *FirestoreDB) RevokeUserRefreshTokens(ctx context.Context, userID string, revokedAt time.Time) error {
+	return db.revokeRefreshTokens(ctx, "user_id", userID, revokedAt)
+}
+
+// revokeRefreshTokens revokes the tokens whose field equals value
+func (db *FirestoreDB) revokeRefreshTokens(ctx context.Context, field, value string, revokedAt time.Time) error {
+	iter := db.client.Collection("auth_refresh_tokens").Where(field, "==", value).Documents(ctx)
+	defer iter.Stop()
+
+	bw := db.client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bw.End()
+			return firestoreError(err, ErrRefreshTokenNotFound)
+		}
+		if refreshTokenFromDoc(doc).IsRevoked() {
+			continue
+		}
+
+		job, err := bw.Update(doc.Ref, []firestore.Update{{Path: "revoked_at", Value: revokedAt}})
+		if err != nil {
+			bw.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return firestoreError(err, ErrRefreshTokenNotFound)
+		}
+	}
+	return nil
+}
+
+func (db *FirestoreDB) refreshRef(tokenHash string) *firestore.DocumentRef {
+	return db.client.Collection("auth_refresh_tokens").Doc(tokenHash)
+}
+
+func refreshTokenFromDoc(doc *firestore.DocumentSnapshot) *domain.RefreshToken {
+	data := doc.Data()
+	token := &domain.RefreshToken{TokenHash: doc.Ref.ID}
+	token.FamilyID, _ = data["family_id"].(string)
+	token.UserID, _ = data["user_id"].(string)
+	token.CreatedAt, _ = data["created_at"].(time.Time)
+	token.ExpiresAt, _ = data["expires_at"].(time.Time)
+	token.UsedAt, _ = data["used_at"].(time.Time)
+	token.RevokedAt, _ = data["revoked_at"].(time.Time)
+	return token
+}
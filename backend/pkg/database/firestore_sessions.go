@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreDB also implements the auth module's repository.SessionRepository,
+// keying auth_sessions documents by session ID
+
+func (db *FirestoreDB) CreateSession(ctx context.Context, session *domain.Session) error {
+	data := map[string]interface{}{
+		"user_id":      session.UserID,
+		"user_agent":   session.UserAgent,
+		"ip_prefix":    session.IPPrefix,
+		"created_at":   session.CreatedAt,
+		"last_seen_at": session.LastSeenAt,
+		"expires_at":   session.ExpiresAt,
+	}
+	if !session.RevokedAt.IsZero() {
+		data["revoked_at"] = session.RevokedAt
+	}
+
+	_, err := db.sessionRef(session.ID).Create(ctx, data)
+	return firestoreError(err, ErrSessionNotFound)
+}
+
+func (db *FirestoreDB) FindSession(ctx context.Context, sessionID string) (*domain.Session, error) {
+	doc, err := db.sessionRef(sessionID).Get(ctx)
+	if err != nil {
+		return nil, firestoreError(err, ErrSessionNotFound)
+	}
+	return sessionFromDoc(doc), nil
+}
+
+func (db *FirestoreDB) ListUserSessions(ctx context.Context, userID string, now time.Time) ([]*domain.Session, error) {
+	// A user has few sessions; filtering and sorting them in process needs
+	// no composite index
+	iter := db.client.Collection("auth_sessions").Where("user_id", "==", userID).Documents(ctx)
+	defer iter.Stop()
+
+	var sessions []*domain.Session
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, firestoreError(err, ErrSessionNotFound)
+		}
+		if session := sessionFromDoc(doc); session.IsActive(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+func (db *FirestoreDB) UpdateSession(ctx context.Context, session *domain.Session) error {
+	_, err := db.sessionRef(session.ID).Update(ctx, []firestore.Update{
+		{Path: "user_agent", Value: session.UserAgent},
+		{Path: "ip_prefix", Value: session.IPPrefix},
+		{Path: "last_seen_at", Value: session.LastSeenAt},
+		{Path: "expires_at", Value: session.ExpiresAt},
+	})
+	return firestoreError(err, ErrSessionNotFound)
+}
+
+func (db *FirestoreDB) TouchSession(ctx context.Context, sessionID string, seenAt time.Time) error {
+	_, err := db.sessionRef(sessionID).Update(ctx, []firestore.Update{{Path: "last_seen_at", Value: seenAt}})
+	return firestoreError(err, ErrSessionNotFound)
+}
+
+func (db *FirestoreDB) RevokeSession(ctx context.Context, sessionID string, revokedAt time.Time) error {
+	ref := db.sessionRef(sessionID)
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrSessionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !sessionFromDoc(doc).RevokedAt.IsZero() {
+			return nil
+		}
+		return tx.Update(ref, []firestore.Update{{Path: "revoked_at", Value: revokedAt}})
+	})
+	return firestoreError(err, ErrSessionNotFound)
+}
+
+func (db *FirestoreDB) RevokeUserSessions(ctx context.Context, userID string, revokedAt time.Time) error {
+	iter := db.client.Collection("auth_sessions").Where("user_id", "==", userID).Documents(ctx)
+	defer iter.Stop()
+
+	bw := db.client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bw.End()
+			return firestoreError(err, ErrSessionNotFound)
+		}
+		if !sessionFromDoc(doc).RevokedAt.IsZero() {
+			continue
+		}
+
+		job, err := bw.Update(doc.Ref, []firestore.Update{{Path: "revoked_at", Value: revokedAt}})
+		if err != nil {
+			bw.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return firestoreError(err, ErrSessionNotFound)
+		}
+	}
+	return nil
+}
+
+func (db *FirestoreDB) sessionRef(sessionID string) *firestore.DocumentRef {
+	return db.client.Collection("auth_sessions").Doc(sessionID)
+}
+
+func sessionFromDoc(doc *firestore.DocumentSnapshot) *domain.Session {
+	data := doc.Data()
+	session := &domain.Session{ID: doc.Ref.ID}
+	session.UserID, _ = data["user_id"].(string)
+	session.UserAgent, _ = data["user_agent"].(string)
+	session.IPPrefix, _ = data["ip_prefix"].(string)
+	session.CreatedAt, _ = data["created_at"].(time.Time)
+	session.LastSeenAt, _ = data["last_seen_at"].(time.Time)
+	session.ExpiresAt, _ = data["expires_at"].(time.Time)
+	session.RevokedAt, _ = data["revoked_at"].(time.Time)
+	return session
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreDB also implements the auth module's
+// repository.TokenRevocationRepository. Revoked tokens are documents of
+// auth_revoked_tokens keyed by jti; a TTL policy on expires_at can delete
+// them once they expire.
+
+func (db *FirestoreDB) RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	_, err := db.client.Collection("auth_revoked_tokens").Doc(jti).Set(ctx, map[string]interface{}{
+		"user_id":    userID,
+		"expires_at": expiresAt,
+	})
+	return firestoreError(err, ErrNotFound)
+}
+
+func (db *FirestoreDB) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	_, err := db.client.Collection("auth_revoked_tokens").Doc(jti).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, firestoreError(err, ErrNotFound)
+	}
+	return true, nil
+}
@@ -1,14 +1,43 @@
 package database
 
-import "ecolink-core/internal/models"
+import (
+	"context"
+	"ecolink-core/internal/auth/repository"
+	"ecolink-core/internal/models"
+)
 
+// Database is the storage used by the link and user services. Every method
+// honours ctx cancellation and deadlines, and errors match ErrNotFound,
+// ErrConflict or ErrUnavailable where one applies.
 type Database interface {
-	SaveLink(link *models.Link) error
-	GetLink(code string) (*models.Link, error)
-	GetUserLinks(userID string) ([]*models.Link, error)
-	IncrementClicks(code string) error
-	DeleteLink(code string) error
-	SaveUser(user *models.User) error
-	GetUser(id string) (*models.User, error)
-	GetUserByGoogleID(googleID string) (*models.User, error)
+	// SaveLink stores a new link, failing with ErrCodeTaken if the code exists
+	SaveLink(ctx context.Context, link *models.Link) error
+	GetLink(ctx context.Context, code string) (*models.Link, error)
+	// ListLinks returns one page of the links matching query, failing with
+	// ErrInvalidQuery or ErrInvalidCursor if it cannot be interpreted
+	ListLinks(ctx context.Context, query LinkQuery) (*LinkPage, error)
+	// UpdateLink replaces the link stored under code. If link.Code differs
+	// from code the link is renamed, failing with ErrCodeTaken if the new
+	// code is in use. SQL stores, which keep click events next to links,
+	// move the link's events to the new code in the same transaction.
+	UpdateLink(ctx context.Context, code string, link *models.Link) error
+	// IncrementClicks counts one human click against the link's budget,
+	// failing with ErrClickLimitReached once MaxClicks clicks were counted.
+	// The check and the write are atomic.
+	IncrementClicks(ctx context.Context, code string) error
+	// AddClicks adds a batch of human and automated (unfurlers, monitors,
+	// prefetches) clicks to a link's counters in one write
+	AddClicks(ctx context.Context, code string, humans, bots int) error
+	DeleteLink(ctx context.Context, code string) error
+
+	// Every store also holds the user accounts, their sessions, API keys,
+	// emailed tokens, second factors and login history, so links and their
+	// owners live in the same place
+	repository.UserRepository
+	repository.TokenRepository
+	repository.APIKeyRepository
+	repository.EmailTokenRepository
+	repository.MFARepository
+	repository.LoginAttemptRepository
+	repository.AuthEventRepository
 }
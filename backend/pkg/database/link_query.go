@@ -0,0 +1,201 @@
+package database
+
+import (
+	"cmp"
+	"ecolink-core/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+)
+
+// LinkSort is the field a link listing is ordered by. Ties are broken by
+// code so every order is total and cursors stay stable.
+type LinkSort string
+
+const (
+	SortByCreated LinkSort = "created"
+	SortByClicks  LinkSort = "clicks"
+)
+
+// LinkStatus filters links by whether they can still be followed
+type LinkStatus string
+
+const (
+	StatusActive    LinkStatus = "active"    // Neither expired nor exhausted
+	StatusExpired   LinkStatus = "expired"   // Past its expiration date
+	StatusExhausted LinkStatus = "exhausted" // Click budget used up
+)
+
+// LinkQuery selects one page of a user's links. Zero values leave a filter
+// out.
+type LinkQuery struct {
+	UserID string
+	URL    string // Exact destination
+	Search string // Case-insensitive substring of the URL, code or title
+	Tag    string
+	Status LinkStatus
+	From   time.Time // Created at or after
+	To     time.Time // Created before
+
+	Sort      LinkSort // Defaults to SortByCreated
+	Ascending bool     // Newest or most clicked first unless set
+	Limit     int      // Maximum links per page, 0 for all
+	Cursor    string   // NextCursor of the previous page
+}
+
+// LinkPage is one page of a listing. NextCursor is empty on the last page.
+type LinkPage struct {
+	Links      []*models.Link
+	NextCursor string
+}
+
+// linkCursor is the position of the last link of a page, encoded opaquely
+// for clients. It records the sort so a cursor cannot be replayed against
+// another order.
+type linkCursor struct {
+	Sort      LinkSort  `json:"s"`
+	Ascending bool      `json:"a,omitempty"`
+	CreatedAt time.Time `json:"t,omitempty"`
+	Clicks    int       `json:"n,omitempty"`
+	Code      string    `json:"c"`
+}
+
+func (q LinkQuery) sort() LinkSort {
+	if q.Sort == "" {
+		return SortByCreated
+	}
+	return q.Sort
+}
+
+// cursorAt returns the cursor resuming after link
+func (q LinkQuery) cursorAt(link *models.Link) linkCursor {
+	return linkCursor{
+		Sort:      q.sort(),
+		Ascending: q.Ascending,
+		CreatedAt: link.CreatedAt.UTC(),
+		Clicks:    link.Clicks,
+		Code:      link.Code,
+	}
+}
+
+func (q LinkQuery) encodeCursor(link *models.Link) string {
+	data, _ := json.Marshal(q.cursorAt(link))
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the position to resume after, or nil for the first
+// page
+func (q LinkQuery) decodeCursor() (*linkCursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor linkCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Code == "" {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != q.sort() || cursor.Ascending != q.Ascending {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// Validate checks the fields adapters cannot interpret
+func (q LinkQuery) Validate() error {
+	switch q.sort() {
+	case SortByCreated, SortByClicks:
+	default:
+		return ErrInvalidQuery
+	}
+	switch q.Status {
+	case "", StatusActive, StatusExpired, StatusExhausted:
+	default:
+		return ErrInvalidQuery
+	}
+	if q.Limit < 0 {
+		return ErrInvalidQuery
+	}
+	_, err := q.decodeCursor()
+	return err
+}
+
+// compare orders two positions the way the query sorts them
+func (q LinkQuery) compare(a, b linkCursor) int {
+	result := 0
+	switch q.sort() {
+	case SortByClicks:
+		result = cmp.Compare(a.Clicks, b.Clicks)
+	default:
+		result = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if result == 0 {
+		result = strings.Compare(a.Code, b.Code)
+	}
+	if !q.Ascending {
+		result = -result
+	}
+	return result
+}
+
+// matches applies the filters to a link in process, for stores that cannot
+// express them natively
+func (q LinkQuery) matches(link *models.Link, now time.Time) bool {
+	if q.UserID != "" && link.UserID != q.UserID {
+		return false
+	}
+	if q.URL != "" && link.URL != q.URL {
+		return false
+	}
+	if q.Search != "" {
+		search := strings.ToLower(q.Search)
+		if !strings.Contains(strings.ToLower(link.URL), search) &&
+			!strings.Contains(strings.ToLower(link.Code), search) &&
+			!strings.Contains(strings.ToLower(link.Title), search) {
+			return false
+		}
+	}
+	if q.Tag != "" && !slices.Contains(link.Tags, q.Tag) {
+		return false
+	}
+	if !q.From.IsZero() && link.CreatedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !link.CreatedAt.Before(q.To) {
+		return false
+	}
+
+	switch q.Status {
+	case StatusActive:
+		return !link.IsExpired(now) && !link.IsExhausted()
+	case StatusExpired:
+		return link.IsExpired(now)
+	case StatusExhausted:
+		return link.IsExhausted()
+	}
+	return true
+}
+
+// paginate cuts a page out of links already filtered and sorted by q,
+// starting after the cursor
+func (q LinkQuery) paginate(links []*models.Link, cursor *linkCursor) *LinkPage {
+	if cursor != nil {
+		start := 0
+		for start < len(links) && q.compare(q.cursorAt(links[start]), *cursor) <= 0 {
+			start++
+		}
+		links = links[start:]
+	}
+
+	page := &LinkPage{Links: links}
+	if q.Limit > 0 && len(links) > q.Limit {
+		page.Links = links[:q.Limit]
+		page.NextCursor = q.encodeCursor(page.Links[q.Limit-1])
+	}
+	return page
+}
@@ -1,105 +1,151 @@
 package database
 
 import (
+	"context"
+	"ecolink-core/internal/auth/domain"
 	"ecolink-core/internal/models"
-	"errors"
+	"sort"
 	"sync"
+	"time"
 )
 
 type MemoryDB struct {
-	links map[string]*models.Link
-	users map[string]*models.User
-	mutex sync.RWMutex
+	links       map[string]*models.Link
+	users       map[string]*domain.User
+	credentials map[string]*domain.Credential
+	socials     map[string]*domain.SocialProfile // Keyed by provider and provider ID
+	refresh     map[string]*domain.RefreshToken  // Keyed by token hash
+	revoked     map[string]time.Time             // Expiry of revoked tokens, keyed by jti
+	sessions    map[string]*domain.Session
+	apiKeys     map[string]*domain.APIKey
+	emailTokens map[string]*domain.EmailToken    // Keyed by token hash
+	mfa         map[string]*domain.MFA           // Keyed by user ID
+	recovery    map[string]map[string]bool       // Unused recovery code hashes, keyed by user ID
+	attempts    map[string]*domain.LoginAttempts // Keyed by subject
+	events      []*domain.AuthEvent              // In recording order
+	mutex       sync.RWMutex
 }
 
 func NewMemoryDB() *MemoryDB {
 	return &MemoryDB{
-		links: make(map[string]*models.Link),
-		users: make(map[string]*models.User),
+		links:       make(map[string]*models.Link),
+		users:       make(map[string]*domain.User),
+		credentials: make(map[string]*domain.Credential),
+		socials:     make(map[string]*domain.SocialProfile),
+		refresh:     make(map[string]*domain.RefreshToken),
+		revoked:     make(map[string]time.Time),
+		sessions:    make(map[string]*domain.Session),
+		apiKeys:     make(map[string]*domain.APIKey),
+		emailTokens: make(map[string]*domain.EmailToken),
+		mfa:         make(map[string]*domain.MFA),
+		recovery:    make(map[string]map[string]bool),
+		attempts:    make(map[string]*domain.LoginAttempts),
 	}
 }
 
-func (db *MemoryDB) SaveLink(link *models.Link) error {
+func (db *MemoryDB) SaveLink(ctx context.Context, link *models.Link) error {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
+	if _, exists := db.links[link.Code]; exists {
+		return ErrCodeTaken
+	}
 	db.links[link.Code] = link
 	return nil
 }
 
-func (db *MemoryDB) GetLink(code string) (*models.Link, error) {
+func (db *MemoryDB) GetLink(ctx context.Context, code string) (*models.Link, error) {
 	db.mutex.RLock()
 	defer db.mutex.RUnlock()
 
 	link, exists := db.links[code]
 	if !exists {
-		return nil, errors.New("link not found")
+		return nil, ErrLinkNotFound
 	}
-	return link, nil
+	// A copy, so counting a click never changes a link a caller holds
+	return cloneLink(link), nil
 }
 
-func (db *MemoryDB) GetUserLinks(userID string) ([]*models.Link, error) {
+func (db *MemoryDB) ListLinks(ctx context.Context, query LinkQuery) (*LinkPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	cursor, _ := query.decodeCursor()
+
 	db.mutex.RLock()
 	defer db.mutex.RUnlock()
 
-	var userLinks []*models.Link
+	now := time.Now()
+	var links []*models.Link
 	for _, link := range db.links {
-		if link.UserID == userID {
-			userLinks = append(userLinks, link)
+		if query.matches(link, now) {
+			links = append(links, cloneLink(link))
 		}
 	}
-	return userLinks, nil
+
+	sort.Slice(links, func(i, j int) bool {
+		return query.compare(query.cursorAt(links[i]), query.cursorAt(links[j])) < 0
+	})
+	return query.paginate(links, cursor), nil
 }
 
-func (db *MemoryDB) IncrementClicks(code string) error {
+func (db *MemoryDB) UpdateLink(ctx context.Context, code string, link *models.Link) error {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
-	if link, exists := db.links[code]; exists {
-		link.Clicks++
-		return nil
+	existing, exists := db.links[code]
+	if !exists {
+		return ErrLinkNotFound
 	}
-	return errors.New("link not found")
-}
-
-func (db *MemoryDB) DeleteLink(code string) error {
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
 
-	if _, exists := db.links[code]; exists {
+	if link.Code != code {
+		if _, taken := db.links[link.Code]; taken {
+			return ErrCodeTaken
+		}
 		delete(db.links, code)
-		return nil
 	}
-	return errors.New("link not found")
+
+	// Keep clicks counted since the caller read the link
+	link.Clicks = existing.Clicks
+	link.BotClicks = existing.BotClicks
+	db.links[link.Code] = link
+	return nil
 }
 
-func (db *MemoryDB) SaveUser(user *models.User) error {
+func (db *MemoryDB) IncrementClicks(ctx context.Context, code string) error {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
-	db.users[user.ID] = user
+	link, exists := db.links[code]
+	if !exists {
+		return ErrLinkNotFound
+	}
+	if link.IsExhausted() {
+		return ErrClickLimitReached
+	}
+	link.Clicks++
 	return nil
 }
 
-func (db *MemoryDB) GetUser(id string) (*models.User, error) {
-	db.mutex.RLock()
-	defer db.mutex.RUnlock()
+func (db *MemoryDB) AddClicks(ctx context.Context, code string, humans, bots int) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
 
-	user, exists := db.users[id]
-	if !exists {
-		return nil, errors.New("user not found")
+	if link, exists := db.links[code]; exists {
+		link.Clicks += humans
+		link.BotClicks += bots
+		return nil
 	}
-	return user, nil
+	return ErrLinkNotFound
 }
 
-func (db *MemoryDB) GetUserByGoogleID(googleID string) (*models.User, error) {
-	db.mutex.RLock()
-	defer db.mutex.RUnlock()
+func (db *MemoryDB) DeleteLink(ctx context.Context, code string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
 
-	for _, user := range db.users {
-		if user.GoogleID == googleID {
-			return user, nil
-		}
+	if _, exists := db.links[code]; exists {
+		delete(db.links, code)
+		return nil
 	}
-	return nil, errors.New("user not found")
+	return ErrLinkNotFound
 }
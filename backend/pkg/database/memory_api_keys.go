@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MemoryDB also implements the auth module's repository.APIKeyRepository
+
+func (db *MemoryDB) CreateAPIKey(ctx context.Context, key *domain.APIKey) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.apiKeys[key.ID]; exists {
+		return ErrConflict
+	}
+	for _, stored := range db.apiKeys {
+		if stored.KeyHash == key.KeyHash {
+			return ErrConflict
+		}
+	}
+	db.apiKeys[key.ID] = copyAPIKey(key)
+	return nil
+}
+
+func (db *MemoryDB) FindAPIKeyByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	for _, key := range db.apiKeys {
+		if key.KeyHash == keyHash {
+			return copyAPIKey(key), nil
+		}
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+func (db *MemoryDB) ListUserAPIKeys(ctx context.Context, userID string) ([]*domain.APIKey, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	var keys []*domain.APIKey
+	for _, key := range db.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, copyAPIKey(key))
+		}
+	}
+	sortAPIKeys(keys)
+	return keys, nil
+}
+
+func (db *MemoryDB) TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	key, exists := db.apiKeys[keyID]
+	if !exists {
+		return ErrAPIKeyNotFound
+	}
+	key.LastUsedAt = &usedAt
+	return nil
+}
+
+func (db *MemoryDB) DeleteAPIKey(ctx context.Context, userID, keyID string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	key, exists := db.apiKeys[keyID]
+	if !exists || key.UserID != userID {
+		return ErrAPIKeyNotFound
+	}
+	delete(db.apiKeys, keyID)
+	return nil
+}
+
+func (db *MemoryDB) DeleteUserAPIKeys(ctx context.Context, userID string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for id, key := range db.apiKeys {
+		if key.UserID == userID {
+			delete(db.apiKeys, id)
+		}
+	}
+	return nil
+}
+
+// copyAPIKey returns a copy of key sharing no memory with it
+func copyAPIKey(key *domain.APIKey) *domain.APIKey {
+	copied := *key
+	copied.Scopes = slices.Clone(key.Scopes)
+	if key.ExpiresAt != nil {
+		expiresAt := *key.ExpiresAt
+		copied.ExpiresAt = &expiresAt
+	}
+	if key.LastUsedAt != nil {
+		lastUsedAt := *key.LastUsedAt
+		copied.LastUsedAt = &lastUsedAt
+	}
+	return &copied
+}
+
+// sortAPIKeys orders keys newest first, by ID on ties, for the stores that
+// cannot sort natively
+func sortAPIKeys(keys []*domain.APIKey) {
+	slices.SortFunc(keys, func(a, b *domain.APIKey) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+}
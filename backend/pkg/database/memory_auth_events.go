@@ -0,0 +1,31 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+)
+
+// MemoryDB also implements the auth module's repository.AuthEventRepository
+
+func (db *MemoryDB) RecordAuthEvent(ctx context.Context, event *domain.AuthEvent) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	stored := *event
+	db.events = append(db.events, &stored)
+	return nil
+}
+
+func (db *MemoryDB) ListUserAuthEvents(ctx context.Context, userID string, limit int) ([]*domain.AuthEvent, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	var events []*domain.AuthEvent
+	for i := len(db.events) - 1; i >= 0 && len(events) < limit; i-- {
+		if db.events[i].UserID == userID {
+			found := *db.events[i]
+			events = append(events, &found)
+		}
+	}
+	return events, nil
+}
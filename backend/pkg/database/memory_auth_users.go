@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+)
+
+// MemoryDB also implements the auth module's repository.UserRepository,
+// enforcing the same unique emails and social profiles as the other stores
+
+func (db *MemoryDB) FindByEmail(ctx context.Context, email string) (*domain.User, *domain.Credential, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	for _, user := range db.users {
+		if user.Email == email {
+			// Users who only signed in with a social provider have no
+			// credential
+			return user, db.credentials[user.ID], nil
+		}
+	}
+	return nil, nil, ErrUserNotFound
+}
+
+func (db *MemoryDB) FindByProviderID(ctx context.Context, provider, providerID string) (*domain.User, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	social, exists := db.socials[socialKey(provider, providerID)]
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	return db.users[social.UserID], nil
+}
+
+func (db *MemoryDB) FindByID(ctx context.Context, userID string) (*domain.User, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	user, exists := db.users[userID]
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (db *MemoryDB) CreateUser(ctx context.Context, user *domain.User, cred *domain.Credential) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if err := db.insertAuthUser(user); err != nil {
+		return err
+	}
+	if cred != nil {
+		db.credentials[user.ID] = cred
+	}
+	return nil
+}
+
+func (db *MemoryDB) CreateUserFromSocial(ctx context.Context, user *domain.User, social *domain.SocialProfile) (*domain.User, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	key := socialKey(social.Provider, social.ProviderID)
+	if _, taken := db.socials[key]; taken {
+		return nil, ErrUserExists
+	}
+	if err := db.insertAuthUser(user); err != nil {
+		return nil, err
+	}
+	db.socials[key] = social
+	return user, nil
+}
+
+func (db *MemoryDB) UpdateUser(ctx context.Context, user *domain.User) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.users[user.ID]; !exists {
+		return ErrUserNotFound
+	}
+	for _, other := range db.users {
+		if other.ID != user.ID && other.Email == user.Email {
+			return ErrUserExists
+		}
+	}
+	user.UpdatedAt = time.Now().UTC()
+	db.users[user.ID] = user
+	return nil
+}
+
+func (db *MemoryDB) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.users[userID]; !exists {
+		return ErrUserNotFound
+	}
+	db.credentials[userID] = &domain.Credential{UserID: userID, PasswordHash: passwordHash}
+	return nil
+}
+
+func (db *MemoryDB) MarkEmailVerified(ctx context.Context, userID string, verifiedAt time.Time) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	user, exists := db.users[userID]
+	if !exists {
+		return ErrUserNotFound
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	// Replace rather than modify the user, which callers may hold
+	verified := *user
+	verifiedAt = verifiedAt.UTC()
+	verified.EmailVerifiedAt = &verifiedAt
+	db.users[userID] = &verified
+	return nil
+}
+
+// insertAuthUser stores a new user, filling in missing timestamps. The
+// caller holds the write lock.
+func (db *MemoryDB) insertAuthUser(user *domain.User) error {
+	if _, exists := db.users[user.ID]; exists {
+		return ErrUserExists
+	}
+	for _, other := range db.users {
+		if other.Email == user.Email {
+			return ErrUserExists
+		}
+	}
+
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now().UTC()
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = user.CreatedAt
+	}
+	db.users[user.ID] = user
+	return nil
+}
+
+func socialKey(provider, providerID string) string {
+	return provider + "\x00" + providerID
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+)
+
+// MemoryDB also implements the auth module's repository.EmailTokenRepository
+
+func (db *MemoryDB) CreateEmailToken(ctx context.Context, token *domain.EmailToken) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	// Expired tokens are rejected anyway, so their records go
+	now := time.Now()
+	for tokenHash, stored := range db.emailTokens {
+		if stored.IsExpired(now) {
+			delete(db.emailTokens, tokenHash)
+		}
+	}
+
+	if _, exists := db.emailTokens[token.TokenHash]; exists {
+		return ErrConflict
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = now.UTC()
+	}
+	stored := *token
+	db.emailTokens[token.TokenHash] = &stored
+	return nil
+}
+
+func (db *MemoryDB) UseEmailToken(ctx context.Context, tokenHash, purpose string, usedAt time.Time) (*domain.EmailToken, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	token, exists := db.emailTokens[tokenHash]
+	if !exists || token.Purpose != purpose {
+		return nil, ErrEmailTokenNotFound
+	}
+	if token.IsUsed() {
+		return nil, ErrEmailTokenUsed
+	}
+	token.UsedAt = usedAt
+	used := *token
+	return &used, nil
+}
+
+func (db *MemoryDB) FindEmailToken(ctx context.Context, tokenHash, purpose string) (*domain.EmailToken, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	token, exists := db.emailTokens[tokenHash]
+	if !exists || token.Purpose != purpose {
+		return nil, ErrEmailTokenNotFound
+	}
+	found := *token
+	return &found, nil
+}
+
+func (db *MemoryDB) ResetPasswordWithToken(ctx context.Context, tokenHash, passwordHash string, usedAt time.Time) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	token, exists := db.emailTokens[tokenHash]
+	if !exists || token.Purpose != domain.EmailTokenResetPassword {
+		return ErrEmailTokenNotFound
+	}
+	if token.IsUsed() {
+		return ErrEmailTokenUsed
+	}
+	if _, exists := db.users[token.UserID]; !exists {
+		return ErrUserNotFound
+	}
+	db.credentials[token.UserID] = &domain.Credential{UserID: token.UserID, PasswordHash: passwordHash}
+	token.UsedAt = usedAt
+	return nil
+}
+
+func (db *MemoryDB) InvalidateEmailTokens(ctx context.Context, userID, purpose string, usedAt time.Time) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for _, token := range db.emailTokens {
+		if token.UserID == userID && token.Purpose == purpose && !token.IsUsed() {
+			token.UsedAt = usedAt
+		}
+	}
+	return nil
+}
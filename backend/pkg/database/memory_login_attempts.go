@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+)
+
+// MemoryDB also implements the auth module's
+// repository.LoginAttemptRepository
+
+func (db *MemoryDB) FindLoginAttempts(ctx context.Context, subject string) (*domain.LoginAttempts, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	attempts, exists := db.attempts[subject]
+	if !exists {
+		return nil, ErrLoginAttemptsNotFound
+	}
+	found := *attempts
+	return &found, nil
+}
+
+func (db *MemoryDB) RecordLoginFailure(ctx context.Context, subject string, failedAt, resetBefore time.Time) (*domain.LoginAttempts, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	// Counters that would start over anyway go, unless they hold a lockout
+	for stale, attempts := range db.attempts {
+		if attempts.LastFailureAt.Before(resetBefore) && !attempts.IsLocked(failedAt) {
+			delete(db.attempts, stale)
+		}
+	}
+
+	attempts, exists := db.attempts[subject]
+	if !exists {
+		attempts = &domain.LoginAttempts{Subject: subject}
+		db.attempts[subject] = attempts
+	}
+	if attempts.LastFailureAt.Before(resetBefore) {
+		attempts.Failures = 0
+	}
+	attempts.Failures++
+	attempts.LastFailureAt = failedAt
+
+	recorded := *attempts
+	return &recorded, nil
+}
+
+func (db *MemoryDB) LockLogin(ctx context.Context, subject string, until time.Time) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	attempts, exists := db.attempts[subject]
+	if !exists {
+		return ErrLoginAttemptsNotFound
+	}
+	attempts.LockedUntil = until
+	return nil
+}
+
+func (db *MemoryDB) ClearLoginAttempts(ctx context.Context, subject string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	delete(db.attempts, subject)
+	return nil
+}
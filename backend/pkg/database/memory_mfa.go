@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+)
+
+// MemoryDB also implements the auth module's repository.MFARepository
+
+func (db *MemoryDB) FindMFA(ctx context.Context, userID string) (*domain.MFA, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	mfa, exists := db.mfa[userID]
+	if !exists {
+		return nil, ErrMFANotFound
+	}
+	return copyMFA(mfa), nil
+}
+
+func (db *MemoryDB) SaveMFA(ctx context.Context, mfa *domain.MFA) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if mfa.CreatedAt.IsZero() {
+		mfa.CreatedAt = time.Now().UTC()
+	}
+	db.mfa[mfa.UserID] = copyMFA(mfa)
+	delete(db.recovery, mfa.UserID)
+	return nil
+}
+
+func (db *MemoryDB) EnableMFA(ctx context.Context, userID string, enabledAt time.Time, codeHashes []string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	mfa, exists := db.mfa[userID]
+	if !exists {
+		return ErrMFANotFound
+	}
+	mfa.EnabledAt = enabledAt
+	db.recovery[userID] = recoverySet(codeHashes)
+	return nil
+}
+
+func (db *MemoryDB) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	mfa, exists := db.mfa[userID]
+	if !exists {
+		return ErrMFANotFound
+	}
+	if step <= mfa.LastStep {
+		return ErrTOTPStepUsed
+	}
+	mfa.LastStep = step
+	return nil
+}
+
+func (db *MemoryDB) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.mfa[userID]; !exists {
+		return ErrMFANotFound
+	}
+	db.recovery[userID] = recoverySet(codeHashes)
+	return nil
+}
+
+func (db *MemoryDB) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if !db.recovery[userID][codeHash] {
+		return ErrRecoveryCodeNotFound
+	}
+	delete(db.recovery[userID], codeHash)
+	return nil
+}
+
+func (db *MemoryDB) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return len(db.recovery[userID]), nil
+}
+
+func (db *MemoryDB) DeleteMFA(ctx context.Context, userID string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.mfa[userID]; !exists {
+		return ErrMFANotFound
+	}
+	delete(db.mfa, userID)
+	delete(db.recovery, userID)
+	return nil
+}
+
+func copyMFA(mfa *domain.MFA) *domain.MFA {
+	copied := *mfa
+	copied.Secret = append([]byte(nil), mfa.Secret...)
+	return &copied
+}
+
+func recoverySet(codeHashes []string) map[string]bool {
+	set := make(map[string]bool, len(codeHashes))
+	for _, codeHash := range codeHashes {
+		set[codeHash] = true
+	}
+	return set
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"time"
+)
+
+// MemoryDB also implements the auth module's
+// repository.RefreshTokenRepository
+
+func (db *MemoryDB) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.refresh[token.TokenHash]; exists {
+		return ErrConflict
+	}
+	stored := *token
+	db.refresh[token.TokenHash] = &stored
+	return nil
+}
+
+func (db *MemoryDB) FindRefreshToken(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	token, exists := db.refresh[tokenHash]
+	if !exists {
+		return nil, ErrRefreshTokenNotFound
+	}
+	found := *token
+	return &found, nil
+}
+
+func (db *MemoryDB) UseRefreshToken(ctx context.Context, tokenHash string, usedAt time.Time) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	token, exists := db.refresh[tokenHash]
+	if !exists {
+		return ErrRefreshTokenNotFound
+	}
+	if token.IsUsed() {
+		return ErrRefreshTokenUsed
+	}
+	token.UsedAt = usedAt
+	return nil
+}
+
+func (db *MemoryDB) RevokeRefreshTokenFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for _, token := range db.refresh {
+		if token.FamilyID == familyID && !token.IsRevoked() {
+			token.RevokedAt = revokedAt
+		}
+	}
+	return nil
+}
+
+func (db *MemoryDB) RevokeUserRefreshTokens(ctx context.Context, userID string, revokedAt time.Time) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for _, token := range db.refresh {
+		if token.UserID == userID && !token.IsRevoked() {
+			token.RevokedAt = revokedAt
+		}
+	}
+	return nil
+}
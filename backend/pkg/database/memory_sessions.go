@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MemoryDB also implements the auth module's repository.SessionRepository
+
+func (db *MemoryDB) CreateSession(ctx context.Context, session *domain.Session) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.sessions[session.ID]; exists {
+		return ErrConflict
+	}
+	stored := *session
+	db.sessions[session.ID] = &stored
+	return nil
+}
+
+func (db *MemoryDB) FindSession(ctx context.Context, sessionID string) (*domain.Session, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	session, exists := db.sessions[sessionID]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+	found := *session
+	return &found, nil
+}
+
+func (db *MemoryDB) ListUserSessions(ctx context.Context, userID string, now time.Time) ([]*domain.Session, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	var sessions []*domain.Session
+	for _, session := range db.sessions {
+		if session.UserID == userID && session.IsActive(now) {
+			found := *session
+			sessions = append(sessions, &found)
+		}
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+func (db *MemoryDB) UpdateSession(ctx context.Context, session *domain.Session) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	stored, exists := db.sessions[session.ID]
+	if !exists {
+		return ErrSessionNotFound
+	}
+	stored.UserAgent = session.UserAgent
+	stored.IPPrefix = session.IPPrefix
+	stored.LastSeenAt = session.LastSeenAt
+	stored.ExpiresAt = session.ExpiresAt
+	return nil
+}
+
+func (db *MemoryDB) TouchSession(ctx context.Context, sessionID string, seenAt time.Time) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	session, exists := db.sessions[sessionID]
+	if !exists {
+		return ErrSessionNotFound
+	}
+	session.LastSeenAt = seenAt
+	return nil
+}
+
+func (db *MemoryDB) RevokeSession(ctx context.Context, sessionID string, revokedAt time.Time) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	session, exists := db.sessions[sessionID]
+	if !exists {
+		return ErrSessionNotFound
+	}
+	if session.RevokedAt.IsZero() {
+		session.RevokedAt = revokedAt
+	}
+	return nil
+}
+
+func (db *MemoryDB) RevokeUserSessions(ctx context.Context, userID string, revokedAt time.Time) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for _, session := range db.sessions {
+		if session.UserID == userID && session.RevokedAt.IsZero() {
+			session.RevokedAt = revokedAt
+		}
+	}
+	return nil
+}
+
+// sortSessions orders sessions most recently seen first, by ID on ties, for
+// the stores that cannot sort natively
+func sortSessions(sessions []*domain.Session) {
+	slices.SortFunc(sessions, func(a, b *domain.Session) int {
+		if c := b.LastSeenAt.Compare(a.LastSeenAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+}
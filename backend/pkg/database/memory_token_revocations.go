@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// MemoryDB also implements the auth module's
+// repository.TokenRevocationRepository
+
+func (db *MemoryDB) RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	// Tokens past their expiry are rejected anyway, so their records go
+	now := time.Now()
+	for revokedJTI, revokedUntil := range db.revoked {
+		if now.After(revokedUntil) {
+			delete(db.revoked, revokedJTI)
+		}
+	}
+
+	db.revoked[jti] = expiresAt
+	return nil
+}
+
+func (db *MemoryDB) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	_, revoked := db.revoked[jti]
+	return revoked, nil
+}
@@ -0,0 +1,51 @@
+-- The legacy users table duplicated the auth accounts. Fold it into
+-- auth_users: a legacy user joins the account with its ID, or the one
+-- holding its email if Google verified that address (local sign-ups were
+-- not verified then, so anyone could have claimed a stranger's email).
+-- The others are copied over under their own ID, keeping their email
+-- unless another account holds it or they have none; then it becomes
+-- <id>@legacy.invalid and they still sign in with Google. Google logins
+-- become social profiles and links follow their owner when the ID changes.
+INSERT INTO auth_users (id, email, name, picture, created_at, updated_at)
+SELECT u.id, u.email, u.name, u.picture, u.created_at, u.updated_at
+FROM users u
+WHERE u.email <> ''
+  AND NOT EXISTS (SELECT 1 FROM auth_users a WHERE a.id = u.id OR a.email = u.email)
+  AND NOT EXISTS (
+    SELECT 1 FROM users o
+    WHERE o.email = u.email
+      AND (o.created_at < u.created_at OR (o.created_at = u.created_at AND o.id < u.id))
+)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO auth_users (id, email, name, picture, created_at, updated_at)
+SELECT u.id, u.id || '@legacy.invalid', u.name, u.picture, u.created_at, u.updated_at
+FROM users u
+WHERE NOT EXISTS (SELECT 1 FROM auth_users a WHERE a.id = u.id)
+  AND NOT EXISTS (
+    SELECT 1 FROM auth_users a JOIN auth_social_profiles s ON s.user_id = a.id
+    WHERE a.email = u.email AND u.email <> ''
+)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO auth_social_profiles (provider, provider_id, user_id)
+SELECT 'google', u.google_id, a.id
+FROM users u
+JOIN auth_users a ON a.id = u.id
+    OR (a.email = u.email AND NOT EXISTS (SELECT 1 FROM auth_users b WHERE b.id = u.id))
+WHERE u.google_id <> ''
+ON CONFLICT DO NOTHING;
+
+UPDATE links
+SET user_id = (
+    SELECT a.id FROM users u JOIN auth_users a ON a.email = u.email
+    WHERE u.id = links.user_id
+)
+WHERE user_id <> ''
+  AND NOT EXISTS (SELECT 1 FROM auth_users WHERE id = links.user_id)
+  AND EXISTS (
+    SELECT 1 FROM users u JOIN auth_users a ON a.email = u.email
+    WHERE u.id = links.user_id
+);
+
+DROP TABLE users;
@@ -0,0 +1,14 @@
+-- Refresh tokens, stored by the SHA-256 hash of their value. A family
+-- groups the rotations of one login so a reused token revokes them all.
+CREATE TABLE auth_refresh_tokens (
+    token_hash TEXT PRIMARY KEY,
+    family_id  TEXT NOT NULL,
+    user_id    TEXT NOT NULL REFERENCES auth_users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX auth_refresh_tokens_family_id_idx ON auth_refresh_tokens (family_id);
+CREATE INDEX auth_refresh_tokens_user_id_idx ON auth_refresh_tokens (user_id);
@@ -0,0 +1,9 @@
+-- Access tokens revoked before they expire, by jti. Rows are only needed
+-- until expires_at and are pruned as new revocations come in.
+CREATE TABLE auth_revoked_tokens (
+    jti        TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX auth_revoked_tokens_expires_at_idx ON auth_revoked_tokens (expires_at);
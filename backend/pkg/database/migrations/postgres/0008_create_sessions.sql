@@ -0,0 +1,14 @@
+-- One row per login, sharing its ID with the refresh token family and the
+-- sid claim of its access tokens
+CREATE TABLE auth_sessions (
+    id           TEXT PRIMARY KEY,
+    user_id      TEXT NOT NULL REFERENCES auth_users (id) ON DELETE CASCADE,
+    user_agent   TEXT NOT NULL DEFAULT '',
+    ip_prefix    TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX auth_sessions_user_id_idx ON auth_sessions (user_id, last_seen_at DESC);
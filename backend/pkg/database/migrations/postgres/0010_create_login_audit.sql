@@ -0,0 +1,24 @@
+-- Failed login counters, per account email ("email:...") or client address
+-- ("ip:..."); rows go once their failures are old and no lockout holds
+CREATE TABLE auth_login_attempts (
+    subject         TEXT PRIMARY KEY,
+    failures        INTEGER NOT NULL,
+    last_failure_at TIMESTAMPTZ NOT NULL,
+    locked_until    TIMESTAMPTZ
+);
+
+CREATE INDEX auth_login_attempts_last_failure_idx ON auth_login_attempts (last_failure_at);
+
+-- Auth audit trail. Events outlive their account, so user_id is not a
+-- foreign key, and attempts on unknown emails have none.
+CREATE TABLE auth_events (
+    id         TEXT PRIMARY KEY,
+    type       TEXT NOT NULL,
+    user_id    TEXT NOT NULL DEFAULT '',
+    email      TEXT NOT NULL DEFAULT '',
+    ip_prefix  TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX auth_events_user_id_idx ON auth_events (user_id, created_at DESC);
@@ -0,0 +1,21 @@
+-- Single-use tokens sent by email to verify an address or reset a
+-- password. Only a hash of each token is stored; expired rows are pruned
+-- as new tokens come in.
+CREATE TABLE auth_email_tokens (
+    token_hash TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL REFERENCES auth_users (id) ON DELETE CASCADE,
+    email      TEXT NOT NULL,
+    purpose    TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ
+);
+
+CREATE INDEX auth_email_tokens_user_id_idx ON auth_email_tokens (user_id, purpose);
+CREATE INDEX auth_email_tokens_expires_at_idx ON auth_email_tokens (expires_at);
+
+ALTER TABLE auth_users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Google verified the addresses of accounts created through it
+UPDATE auth_users SET email_verified_at = created_at
+WHERE id IN (SELECT user_id FROM auth_social_profiles);
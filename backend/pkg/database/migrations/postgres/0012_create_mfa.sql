@@ -0,0 +1,17 @@
+-- Second factors: the TOTP secret an authenticator app shares with the
+-- server, encrypted by the application, and the step of the last code
+-- accepted so no code works twice
+CREATE TABLE auth_mfa (
+    user_id    TEXT PRIMARY KEY REFERENCES auth_users (id) ON DELETE CASCADE,
+    secret     BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    enabled_at TIMESTAMPTZ,
+    last_step  BIGINT NOT NULL DEFAULT 0
+);
+
+-- Unused recovery codes, stored as hashes; a code is deleted once used
+CREATE TABLE auth_mfa_recovery_codes (
+    user_id   TEXT NOT NULL REFERENCES auth_mfa (user_id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    PRIMARY KEY (user_id, code_hash)
+);
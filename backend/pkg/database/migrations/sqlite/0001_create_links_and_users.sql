@@ -0,0 +1,32 @@
+CREATE TABLE links (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    code          TEXT      NOT NULL,
+    url           TEXT      NOT NULL,
+    user_id       TEXT      NOT NULL DEFAULT '',
+    clicks        INTEGER   NOT NULL DEFAULT 0,
+    bot_clicks    INTEGER   NOT NULL DEFAULT 0,
+    created_at    TIMESTAMP NOT NULL,
+    updated_at    TIMESTAMP NOT NULL,
+    expires_at    TIMESTAMP,
+    max_clicks    INTEGER   NOT NULL DEFAULT 0,
+    fallback_url  TEXT      NOT NULL DEFAULT '',
+    password_hash TEXT      NOT NULL DEFAULT '',
+    title         TEXT      NOT NULL DEFAULT '',
+    tags          TEXT      NOT NULL DEFAULT '[]'
+);
+
+CREATE UNIQUE INDEX links_code_key ON links (code);
+CREATE INDEX links_user_id_created_at_idx ON links (user_id, created_at DESC);
+
+CREATE TABLE users (
+    id         TEXT PRIMARY KEY,
+    google_id  TEXT      NOT NULL DEFAULT '',
+    name       TEXT      NOT NULL DEFAULT '',
+    email      TEXT      NOT NULL DEFAULT '',
+    picture    TEXT      NOT NULL DEFAULT '',
+    provider   TEXT      NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX users_google_id_idx ON users (google_id) WHERE google_id <> '';
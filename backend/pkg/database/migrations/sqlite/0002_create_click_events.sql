@@ -0,0 +1,14 @@
+CREATE TABLE click_events (
+    id         TEXT PRIMARY KEY,
+    code       TEXT      NOT NULL,
+    timestamp  TIMESTAMP NOT NULL,
+    referrer   TEXT      NOT NULL DEFAULT '',
+    user_agent TEXT      NOT NULL DEFAULT '',
+    ip_prefix  TEXT      NOT NULL DEFAULT '',
+    source     TEXT      NOT NULL DEFAULT '',
+    country    TEXT      NOT NULL DEFAULT '',
+    region     TEXT      NOT NULL DEFAULT '',
+    bot        BOOLEAN   NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX click_events_code_timestamp_idx ON click_events (code, timestamp);
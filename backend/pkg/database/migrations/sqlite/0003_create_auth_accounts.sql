@@ -0,0 +1,25 @@
+-- Accounts of the auth module (local credentials and social logins)
+CREATE TABLE auth_users (
+    id         TEXT PRIMARY KEY,
+    email      TEXT NOT NULL,
+    name       TEXT NOT NULL DEFAULT '',
+    picture    TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX auth_users_email_key ON auth_users (email);
+
+CREATE TABLE auth_credentials (
+    user_id       TEXT PRIMARY KEY REFERENCES auth_users (id) ON DELETE CASCADE,
+    password_hash TEXT NOT NULL
+);
+
+CREATE TABLE auth_social_profiles (
+    provider    TEXT NOT NULL,
+    provider_id TEXT NOT NULL,
+    user_id     TEXT NOT NULL REFERENCES auth_users (id) ON DELETE CASCADE,
+    PRIMARY KEY (provider, provider_id)
+);
+
+CREATE INDEX auth_social_profiles_user_id_idx ON auth_social_profiles (user_id);
@@ -0,0 +1,6 @@
+-- Dashboard listings page through a user's links by clicks as well as by
+-- creation date; code breaks ties in both orders
+CREATE INDEX links_user_id_clicks_idx ON links (user_id, clicks DESC, code DESC);
+
+DROP INDEX links_user_id_created_at_idx;
+CREATE INDEX links_user_id_created_at_idx ON links (user_id, created_at DESC, code DESC);
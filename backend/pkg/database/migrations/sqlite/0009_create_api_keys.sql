@@ -0,0 +1,15 @@
+-- Personal API keys. Only a hash of each key is stored; scopes are kept
+-- space-separated, as in OAuth scope strings.
+CREATE TABLE auth_api_keys (
+    id           TEXT PRIMARY KEY,
+    user_id      TEXT NOT NULL REFERENCES auth_users (id) ON DELETE CASCADE,
+    name         TEXT NOT NULL,
+    prefix       TEXT NOT NULL,
+    key_hash     TEXT NOT NULL UNIQUE,
+    scopes       TEXT NOT NULL,
+    created_at   TIMESTAMP NOT NULL,
+    expires_at   TIMESTAMP,
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX auth_api_keys_user_id_idx ON auth_api_keys (user_id, created_at DESC);
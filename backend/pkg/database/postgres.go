@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+// postgresMigrationLock serializes migrations across instances; the key is
+// arbitrary but must stay stable
+const postgresMigrationLock = "SELECT pg_advisory_xact_lock(7301985410)"
+
+// uniqueViolation is the PostgreSQL error code for a duplicate key
+const uniqueViolation = "23505"
+
+type PostgresDB struct {
+	*sqlDB
+}
+
+// NewPostgresDB connects to databaseURL and applies pending migrations
+func NewPostgresDB(databaseURL string) (*PostgresDB, error) {
+	ctx := context.Background()
+
+	db, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres connection: %v", err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %v", err)
+	}
+
+	if err := migrate(ctx, db, postgresMigrations, "migrations/postgres", postgresMigrationLock); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &PostgresDB{&sqlDB{
+		db:                db,
+		isUniqueViolation: isPostgresUniqueViolation,
+		isBusy:            isPostgresBusy,
+		hasTag:            hasPostgresTag,
+	}}, nil
+}
+
+func isPostgresUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// isPostgresBusy reports failures worth retrying: lost or refused
+// connections, exhausted server resources, shutdowns, serialization
+// failures and deadlocks
+func isPostgresBusy(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code[:2] {
+		case "08", "53", "57":
+			return true
+		}
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+
+	var connectErr *pgconn.ConnectError
+	return errors.As(err, &connectErr) || pgconn.Timeout(err)
+}
+
+// hasPostgresTag matches the tags JSONB array containing the tag
+func hasPostgresTag(placeholder string) string {
+	return "tags @> jsonb_build_array(" + placeholder + "::text)"
+}
@@ -0,0 +1,305 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"ecolink-core/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const linkColumns = `code, url, user_id, clicks, bot_clicks, created_at, updated_at,
+	expires_at, max_clicks, fallback_url, password_hash, title, tags`
+
+// sqlDB implements Database over database/sql for the SQL adapters. Queries
+// use $N placeholders, understood by both PostgreSQL and SQLite, and times
+// are stored in UTC so they compare correctly as SQLite text.
+type sqlDB struct {
+	db                *sql.DB
+	isUniqueViolation func(error) bool
+	// isBusy reports backend-specific transient errors such as lock
+	// contention or the server shutting down
+	isBusy func(error) bool
+	// hasTag returns the condition matching links tagged with the value
+	// bound to placeholder
+	hasTag func(placeholder string) string
+}
+
+// classify marks transient failures as ErrUnavailable so callers can
+// retry or answer 503
+func (db *sqlDB) classify(err error) error {
+	if err == nil || errors.Is(err, ErrUnavailable) {
+		return err
+	}
+	if isTransient(err) || db.isBusy(err) {
+		return unavailable(err)
+	}
+	return err
+}
+
+func (db *sqlDB) SaveLink(ctx context.Context, link *models.Link) error {
+	tags, err := json.Marshal(nonNilTags(link.Tags))
+	if err != nil {
+		return err
+	}
+
+	_, err = db.db.ExecContext(ctx, `INSERT INTO links (`+linkColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		link.Code, link.URL, link.UserID, link.Clicks, link.BotClicks, link.CreatedAt.UTC(), now(),
+		utcPtr(link.ExpiresAt), link.MaxClicks, link.FallbackURL, link.PasswordHash, link.Title, string(tags))
+	if db.isUniqueViolation(err) {
+		return ErrCodeTaken
+	}
+	return db.classify(err)
+}
+
+func (db *sqlDB) GetLink(ctx context.Context, code string) (*models.Link, error) {
+	row := db.db.QueryRowContext(ctx, `SELECT `+linkColumns+` FROM links WHERE code = $1`, code)
+
+	link, err := scanLink(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLinkNotFound
+	}
+	return link, db.classify(err)
+}
+
+func (db *sqlDB) ListLinks(ctx context.Context, query LinkQuery) (*LinkPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	cursor, _ := query.decodeCursor()
+
+	var (
+		conditions []string
+		args       []any
+	)
+	// arg binds value and returns its placeholder
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if query.UserID != "" {
+		conditions = append(conditions, "user_id = "+arg(query.UserID))
+	}
+	if query.URL != "" {
+		conditions = append(conditions, "url = "+arg(query.URL))
+	}
+	if query.Search != "" {
+		pattern := arg("%" + escapeLike(strings.ToLower(query.Search)) + "%")
+		conditions = append(conditions, fmt.Sprintf(`(LOWER(url) LIKE %[1]s ESCAPE '\'
+			OR LOWER(code) LIKE %[1]s ESCAPE '\' OR LOWER(title) LIKE %[1]s ESCAPE '\')`, pattern))
+	}
+	if query.Tag != "" {
+		conditions = append(conditions, db.hasTag(arg(query.Tag)))
+	}
+	if !query.From.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(query.From.UTC()))
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(query.To.UTC()))
+	}
+
+	switch query.Status {
+	case StatusActive:
+		conditions = append(conditions, "(expires_at IS NULL OR expires_at > "+arg(now())+")",
+			"(max_clicks = 0 OR clicks < max_clicks)")
+	case StatusExpired:
+		conditions = append(conditions, "expires_at <= "+arg(now()))
+	case StatusExhausted:
+		conditions = append(conditions, "max_clicks > 0 AND clicks >= max_clicks")
+	}
+
+	column, direction, after := "created_at", "DESC", "<"
+	if query.sort() == SortByClicks {
+		column = "clicks"
+	}
+	if query.Ascending {
+		direction, after = "ASC", ">"
+	}
+
+	if cursor != nil {
+		var value any = cursor.CreatedAt
+		if query.sort() == SortByClicks {
+			value = cursor.Clicks
+		}
+		position, code := arg(value), arg(cursor.Code)
+		conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND code %[2]s %[4]s))",
+			column, after, position, code))
+	}
+
+	statement := `SELECT ` + linkColumns + ` FROM links`
+	if len(conditions) > 0 {
+		statement += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	statement += fmt.Sprintf(` ORDER BY %[1]s %[2]s, code %[2]s`, column, direction)
+	if query.Limit > 0 {
+		// One extra row tells whether another page follows
+		statement += ` LIMIT ` + arg(query.Limit+1)
+	}
+
+	rows, err := db.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, db.classify(err)
+	}
+	defer rows.Close()
+
+	var links []*models.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, db.classify(err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, db.classify(err)
+	}
+	return query.paginate(links, nil), nil
+}
+
+func (db *sqlDB) UpdateLink(ctx context.Context, code string, link *models.Link) error {
+	tags, err := json.Marshal(nonNilTags(link.Tags))
+	if err != nil {
+		return err
+	}
+
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		// Counters are left untouched so clicks counted since the caller
+		// read the link are kept
+		result, err := tx.ExecContext(ctx, `UPDATE links SET
+			code = $2, url = $3, user_id = $4, updated_at = $5, expires_at = $6, max_clicks = $7,
+			fallback_url = $8, password_hash = $9, title = $10, tags = $11
+			WHERE code = $1`,
+			code, link.Code, link.URL, link.UserID, now(), utcPtr(link.ExpiresAt), link.MaxClicks,
+			link.FallbackURL, link.PasswordHash, link.Title, string(tags))
+		if db.isUniqueViolation(err) {
+			return ErrCodeTaken
+		}
+		if err := requireAffected(result, err, ErrLinkNotFound); err != nil || link.Code == code {
+			return err
+		}
+
+		// A renamed link takes its click events along
+		_, err = tx.ExecContext(ctx, `UPDATE click_events SET code = $2 WHERE code = $1`, code, link.Code)
+		return err
+	})
+}
+
+func (db *sqlDB) IncrementClicks(ctx context.Context, code string) error {
+	// The max_clicks guard makes the check and the write one atomic step
+	result, err := db.db.ExecContext(ctx, `UPDATE links SET clicks = clicks + 1, updated_at = $2
+		WHERE code = $1 AND (max_clicks = 0 OR clicks < max_clicks)`, code, now())
+	err = requireAffected(result, err, ErrClickLimitReached)
+	if !errors.Is(err, ErrClickLimitReached) {
+		return db.classify(err)
+	}
+
+	// Nothing updated: tell a used-up budget from a missing link
+	if _, err := db.GetLink(ctx, code); err != nil {
+		return err
+	}
+	return ErrClickLimitReached
+}
+
+func (db *sqlDB) AddClicks(ctx context.Context, code string, humans, bots int) error {
+	result, err := db.db.ExecContext(ctx, `UPDATE links SET
+		clicks = clicks + $2, bot_clicks = bot_clicks + $3, updated_at = $4
+		WHERE code = $1`, code, humans, bots, now())
+	return db.classify(requireAffected(result, err, ErrLinkNotFound))
+}
+
+func (db *sqlDB) DeleteLink(ctx context.Context, code string) error {
+	result, err := db.db.ExecContext(ctx, `DELETE FROM links WHERE code = $1`, code)
+	return db.classify(requireAffected(result, err, ErrLinkNotFound))
+}
+
+// DB exposes the connection pool so other repositories can share it
+func (db *sqlDB) DB() *sql.DB {
+	return db.db
+}
+
+func (db *sqlDB) Close() error {
+	return db.db.Close()
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLink(row rowScanner) (*models.Link, error) {
+	var (
+		link      models.Link
+		clicks    int64
+		botClicks int64
+		expiresAt sql.NullTime
+		tags      []byte
+	)
+
+	err := row.Scan(&link.Code, &link.URL, &link.UserID, &clicks, &botClicks, &link.CreatedAt, &link.UpdatedAt,
+		&expiresAt, &link.MaxClicks, &link.FallbackURL, &link.PasswordHash, &link.Title, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	link.Clicks = int(clicks)
+	link.BotClicks = int(botClicks)
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	if err := json.Unmarshal(tags, &link.Tags); err != nil {
+		return nil, fmt.Errorf("invalid tags for link %s: %w", link.Code, err)
+	}
+	if len(link.Tags) == 0 {
+		link.Tags = nil
+	}
+
+	return &link, nil
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// utcPtr converts an optional time to UTC
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// nonNilTags stores missing tags as an empty JSON array rather than null
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// escapeLike escapes the LIKE wildcards in a literal pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// requireAffected turns an update or delete that matched no row into
+// notFound
+func requireAffected(result sql.Result, err error, notFound error) error {
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"ecolink-core/internal/auth/domain"
+	"errors"
+	"strings"
+	"time"
+)
+
+// The SQL adapters also implement the auth module's
+// repository.APIKeyRepository on auth_api_keys
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at`
+
+func (db *sqlDB) CreateAPIKey(ctx context.Context, key *domain.APIKey) error {
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = now()
+	}
+
+	_, err := db.db.ExecContext(ctx, `INSERT INTO auth_api_keys (`+apiKeyColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, strings.Join(key.Scopes, " "),
+		key.CreatedAt.UTC(), optionalTime(key.ExpiresAt), optionalTime(key.LastUsedAt))
+	if db.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return db.classify(err)
+}
+
+func (db *sqlDB) FindAPIKeyByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	key, err := scanAPIKey(db.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM auth_api_keys WHERE key_hash = $1`, keyHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, db.classify(err)
+	}
+	return key, nil
+}
+
+func (db *sqlDB) ListUserAPIKeys(ctx context.Context, userID string) ([]*domain.APIKey, error) {
+	rows, err := db.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM auth_api_keys
+		WHERE user_id = $1 ORDER BY created_at DESC, id`, userID)
+	if err != nil {
+		return nil, db.classify(err)
+	}
+	defer rows.Close()
+
+	var keys []*domain.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, db.classify(err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, db.classify(rows.Err())
+}
+
+func (db *sqlDB) TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error {
+	result, err := db.db.ExecContext(ctx, `UPDATE auth_api_keys SET last_used_at = $2 WHERE id = $1`,
+		keyID, usedAt.UTC())
+	return db.classify(requireAffected(result, err, ErrAPIKeyNotFound))
+}
+
+func (db *sqlDB) DeleteAPIKey(ctx context.Context, userID, keyID string) error {
+	result, err := db.db.ExecContext(ctx, `DELETE FROM auth_api_keys WHERE id = $1 AND user_id = $2`,
+		keyID, userID)
+	return db.classify(requireAffected(result, err, ErrAPIKeyNotFound))
+}
+
+func (db *sqlDB) DeleteUserAPIKeys(ctx context.Context, userID string) error {
+	_, err := db.db.ExecContext(ctx, `DELETE FROM auth_api_keys WHERE user_id = $1`, userID)
+	return db.classify(err)
+}
+
+func scanAPIKey(row rowScanner) (*domain.APIKey, error) {
+	var (
+		key        domain.APIKey
+		scopes     string
+		expiresAt  sql.NullTime
+		lastUsedAt sql.NullTime
+	)
+
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &scopes,
+		&key.CreatedAt, &expiresAt, &lastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	return &key, nil
+}
+
+// optionalTime stores a nil time as NULL
+func optionalTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+)
+
+// The SQL adapters also implement the auth module's
+// repository.AuthEventRepository on auth_events
+
+func (db *sqlDB) RecordAuthEvent(ctx context.Context, event *domain.AuthEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = now()
+	}
+
+	_, err := db.db.ExecContext(ctx, `INSERT INTO auth_events
+		(id, type, user_id, email, ip_prefix, user_agent, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		event.ID, event.Type, event.UserID, event.Email, event.IPPrefix, event.UserAgent, event.CreatedAt.UTC())
+	if db.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return db.classify(err)
+}
+
+func (db *sqlDB) ListUserAuthEvents(ctx context.Context, userID string, limit int) ([]*domain.AuthEvent, error) {
+	rows, err := db.db.QueryContext(ctx, `SELECT id, type, user_id, email, ip_prefix, user_agent, created_at
+		FROM auth_events WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, db.classify(err)
+	}
+	defer rows.Close()
+
+	var events []*domain.AuthEvent
+	for rows.Next() {
+		var event domain.AuthEvent
+		err := rows.Scan(&event.ID, &event.Type, &event.UserID, &event.Email, &event.IPPrefix,
+			&event.UserAgent, &event.CreatedAt)
+		if err != nil {
+			return nil, db.classify(err)
+		}
+		events = append(events, &event)
+	}
+	return events, db.classify(rows.Err())
+}
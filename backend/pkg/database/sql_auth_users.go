@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"ecolink-core/internal/auth/domain"
+	"errors"
+	"time"
+)
+
+// The SQL adapters also implement the auth module's
+// repository.UserRepository on the auth_* tables
+
+const authUserColumns = `u.id, u.email, u.name, u.picture, u.created_at, u.updated_at, u.email_verified_at`
+
+func (db *sqlDB) FindByEmail(ctx context.Context, email string) (*domain.User, *domain.Credential, error) {
+	var passwordHash sql.NullString
+	user, err := scanAuthUser(db.db.QueryRowContext(ctx, `SELECT `+authUserColumns+`, c.password_hash
+		FROM auth_users u LEFT JOIN auth_credentials c ON c.user_id = u.id
+		WHERE u.email = $1`, email), &passwordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, nil, db.classify(err)
+	}
+
+	// Users who only signed in with a social provider have no credential
+	var cred *domain.Credential
+	if passwordHash.Valid {
+		cred = &domain.Credential{UserID: user.ID, PasswordHash: passwordHash.String}
+	}
+	return user, cred, nil
+}
+
+func (db *sqlDB) FindByProviderID(ctx context.Context, provider, providerID string) (*domain.User, error) {
+	return db.findAuthUser(ctx, `SELECT `+authUserColumns+`
+		FROM auth_users u JOIN auth_social_profiles s ON s.user_id = u.id
+		WHERE s.provider = $1 AND s.provider_id = $2`, provider, providerID)
+}
+
+func (db *sqlDB) FindByID(ctx context.Context, userID string) (*domain.User, error) {
+	return db.findAuthUser(ctx, `SELECT `+authUserColumns+` FROM auth_users u WHERE u.id = $1`, userID)
+}
+
+func (db *sqlDB) CreateUser(ctx context.Context, user *domain.User, cred *domain.Credential) error {
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		if err := db.insertAuthUser(ctx, tx, user); err != nil {
+			return err
+		}
+		if cred == nil {
+			return nil
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO auth_credentials (user_id, password_hash) VALUES ($1, $2)`,
+			user.ID, cred.PasswordHash)
+		return err
+	})
+}
+
+func (db *sqlDB) CreateUserFromSocial(ctx context.Context, user *domain.User, social *domain.SocialProfile) (*domain.User, error) {
+	err := db.inTx(ctx, func(tx *sql.Tx) error {
+		if err := db.insertAuthUser(ctx, tx, user); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO auth_social_profiles (provider, provider_id, user_id) VALUES ($1, $2, $3)`,
+			social.Provider, social.ProviderID, user.ID)
+		if db.isUniqueViolation(err) {
+			return ErrUserExists
+		}
+		return err
+	})
+	if err != nil {
+		return nil, db.classify(err)
+	}
+	return user, nil
+}
+
+func (db *sqlDB) UpdateUser(ctx context.Context, user *domain.User) error {
+	user.UpdatedAt = now()
+	result, err := db.db.ExecContext(ctx, `UPDATE auth_users SET email = $2, name = $3, picture = $4, updated_at = $5
+		WHERE id = $1`, user.ID, user.Email, user.Name, user.Picture, user.UpdatedAt)
+	if db.isUniqueViolation(err) {
+		return ErrUserExists
+	}
+	return db.classify(requireAffected(result, err, ErrUserNotFound))
+}
+
+func (db *sqlDB) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		return setPassword(ctx, tx, userID, passwordHash)
+	})
+}
+
+// setPassword stores the credential of userID within tx
+func setPassword(ctx context.Context, tx *sql.Tx, userID, passwordHash string) error {
+	// Touching the user also fails early for a missing one
+	result, err := tx.ExecContext(ctx, `UPDATE auth_users SET updated_at = $2 WHERE id = $1`, userID, now())
+	if err := requireAffected(result, err, ErrUserNotFound); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO auth_credentials (user_id, password_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET password_hash = excluded.password_hash`, userID, passwordHash)
+	return err
+}
+
+func (db *sqlDB) MarkEmailVerified(ctx context.Context, userID string, verifiedAt time.Time) error {
+	result, err := db.db.ExecContext(ctx, `UPDATE auth_users SET email_verified_at = COALESCE(email_verified_at, $2)
+		WHERE id = $1`, userID, verifiedAt.UTC())
+	return db.classify(requireAffected(result, err, ErrUserNotFound))
+}
+
+func (db *sqlDB) findAuthUser(ctx context.Context, query string, args ...any) (*domain.User, error) {
+	user, err := scanAuthUser(db.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, db.classify(err)
+	}
+	return user, nil
+}
+
+// scanAuthUser reads the authUserColumns of a row, followed by extra
+// columns into dest
+func scanAuthUser(row rowScanner, dest ...any) (*domain.User, error) {
+	var (
+		user            domain.User
+		emailVerifiedAt sql.NullTime
+	)
+
+	columns := append([]any{&user.ID, &user.Email, &user.Name, &user.Picture, &user.CreatedAt, &user.UpdatedAt,
+		&emailVerifiedAt}, dest...)
+	if err := row.Scan(columns...); err != nil {
+		return nil, err
+	}
+
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	return &user, nil
+}
+
+// insertAuthUser stores a new user, filling in missing timestamps
+func (db *sqlDB) insertAuthUser(ctx context.Context, tx *sql.Tx, user *domain.User) error {
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now()
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = user.CreatedAt
+	}
+
+	_, err := tx.ExecContext(ctx, `INSERT INTO auth_users (id, email, name, picture, created_at, updated_at, email_verified_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		user.ID, user.Email, user.Name, user.Picture, user.CreatedAt.UTC(), user.UpdatedAt.UTC(),
+		optionalTime(user.EmailVerifiedAt))
+	if db.isUniqueViolation(err) {
+		return ErrUserExists
+	}
+	return err
+}
+
+func (db *sqlDB) inTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	return db.classify(inTx(ctx, db.db, "", fn))
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"ecolink-core/internal/auth/domain"
+	"errors"
+	"time"
+)
+
+// The SQL adapters also implement the auth module's
+// repository.EmailTokenRepository on auth_email_tokens
+
+func (db *sqlDB) CreateEmailToken(ctx context.Context, token *domain.EmailToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = now()
+	}
+
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		// Expired tokens are rejected anyway, so their rows go
+		_, err := tx.ExecContext(ctx, `DELETE FROM auth_email_tokens WHERE expires_at < $1`, now())
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO auth_email_tokens
+			(token_hash, user_id, email, purpose, created_at, expires_at, used_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			token.TokenHash, token.UserID, token.Email, token.Purpose, token.CreatedAt.UTC(), token.ExpiresAt.UTC(),
+			nullTime(token.UsedAt))
+		if db.isUniqueViolation(err) {
+			return ErrConflict
+		}
+		return err
+	})
+}
+
+func (db *sqlDB) UseEmailToken(ctx context.Context, tokenHash, purpose string, usedAt time.Time) (*domain.EmailToken, error) {
+	// The used_at guard makes the check and the write one atomic step
+	token, err := scanEmailToken(db.db.QueryRowContext(ctx, `UPDATE auth_email_tokens SET used_at = $3
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL
+		RETURNING token_hash, user_id, email, purpose, created_at, expires_at, used_at`,
+		tokenHash, purpose, usedAt.UTC()))
+	if err == nil {
+		return token, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, db.classify(err)
+	}
+
+	// Nothing updated: tell a used token from a missing one
+	var exists bool
+	err = db.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM auth_email_tokens
+		WHERE token_hash = $1 AND purpose = $2)`, tokenHash, purpose).Scan(&exists)
+	if err != nil {
+		return nil, db.classify(err)
+	}
+	if !exists {
+		return nil, ErrEmailTokenNotFound
+	}
+	return nil, ErrEmailTokenUsed
+}
+
+func (db *sqlDB) FindEmailToken(ctx context.Context, tokenHash, purpose string) (*domain.EmailToken, error) {
+	token, err := scanEmailToken(db.db.QueryRowContext(ctx, `SELECT
+		token_hash, user_id, email, purpose, created_at, expires_at, used_at
+		FROM auth_email_tokens WHERE token_hash = $1 AND purpose = $2`, tokenHash, purpose))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrEmailTokenNotFound
+	}
+	return token, db.classify(err)
+}
+
+func (db *sqlDB) ResetPasswordWithToken(ctx context.Context, tokenHash, passwordHash string, usedAt time.Time) error {
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		var userID string
+		err := tx.QueryRowContext(ctx, `UPDATE auth_email_tokens SET used_at = $3
+			WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL
+			RETURNING user_id`, tokenHash, domain.EmailTokenResetPassword, usedAt.UTC()).Scan(&userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Nothing updated: tell a used token from a missing one
+			var exists bool
+			err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM auth_email_tokens
+				WHERE token_hash = $1 AND purpose = $2)`, tokenHash, domain.EmailTokenResetPassword).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return ErrEmailTokenNotFound
+			}
+			return ErrEmailTokenUsed
+		}
+		if err != nil {
+			return err
+		}
+		return setPassword(ctx, tx, userID, passwordHash)
+	})
+}
+
+func (db *sqlDB) InvalidateEmailTokens(ctx context.Context, userID, purpose string, usedAt time.Time) error {
+	_, err := db.db.ExecContext(ctx, `UPDATE auth_email_tokens SET used_at = $3
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`, userID, purpose, usedAt.UTC())
+	return db.classify(err)
+}
+
+func scanEmailToken(row rowScanner) (*domain.EmailToken, error) {
+	var (
+		token  domain.EmailToken
+		usedAt sql.NullTime
+	)
+
+	err := row.Scan(&token.TokenHash, &token.UserID, &token.Email, &token.Purpose,
+		&token.CreatedAt, &token.ExpiresAt, &usedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	token.UsedAt = usedAt.Time
+	return &token, nil
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"ecolink-core/internal/auth/domain"
+	"errors"
+	"time"
+)
+
+// The SQL adapters also implement the auth module's
+// repository.LoginAttemptRepository on auth_login_attempts
+
+func (db *sqlDB) FindLoginAttempts(ctx context.Context, subject string) (*domain.LoginAttempts, error) {
+	attempts, err := scanLoginAttempts(db.db.QueryRowContext(ctx, `SELECT subject, failures, last_failure_at, locked_until
+		FROM auth_login_attempts WHERE subject = $1`, subject))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLoginAttemptsNotFound
+	}
+	if err != nil {
+		return nil, db.classify(err)
+	}
+	return attempts, nil
+}
+
+func (db *sqlDB) RecordLoginFailure(ctx context.Context, subject string, failedAt, resetBefore time.Time) (*domain.LoginAttempts, error) {
+	var attempts *domain.LoginAttempts
+	err := db.inTx(ctx, func(tx *sql.Tx) error {
+		// Counters that would start over anyway go, unless they hold a lockout
+		_, err := tx.ExecContext(ctx, `DELETE FROM auth_login_attempts
+			WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until <= $2)`,
+			resetBefore.UTC(), failedAt.UTC())
+		if err != nil {
+			return err
+		}
+
+		// The upsert counts concurrent failures without losing any
+		attempts, err = scanLoginAttempts(tx.QueryRowContext(ctx, `INSERT INTO auth_login_attempts
+			(subject, failures, last_failure_at) VALUES ($1, 1, $2)
+			ON CONFLICT (subject) DO UPDATE SET
+				failures = CASE WHEN auth_login_attempts.last_failure_at < $3 THEN 1
+					ELSE auth_login_attempts.failures + 1 END,
+				last_failure_at = $2
+			RETURNING subject, failures, last_failure_at, locked_until`,
+			subject, failedAt.UTC(), resetBefore.UTC()))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+func (db *sqlDB) LockLogin(ctx context.Context, subject string, until time.Time) error {
+	result, err := db.db.ExecContext(ctx, `UPDATE auth_login_attempts SET locked_until = $2 WHERE subject = $1`,
+		subject, until.UTC())
+	return db.classify(requireAffected(result, err, ErrLoginAttemptsNotFound))
+}
+
+func (db *sqlDB) ClearLoginAttempts(ctx context.Context, subject string) error {
+	_, err := db.db.ExecContext(ctx, `DELETE FROM auth_login_attempts WHERE subject = $1`, subject)
+	return db.classify(err)
+}
+
+func scanLoginAttempts(row rowScanner) (*domain.LoginAttempts, error) {
+	var (
+		attempts    domain.LoginAttempts
+		lockedUntil sql.NullTime
+	)
+
+	if err := row.Scan(&attempts.Subject, &attempts.Failures, &attempts.LastFailureAt, &lockedUntil); err != nil {
+		return nil, err
+	}
+
+	attempts.LockedUntil = lockedUntil.Time
+	return &attempts, nil
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"ecolink-core/internal/auth/domain"
+	"errors"
+	"time"
+)
+
+// The SQL adapters also implement the auth module's
+// repository.MFARepository on auth_mfa and auth_mfa_recovery_codes
+
+func (db *sqlDB) FindMFA(ctx context.Context, userID string) (*domain.MFA, error) {
+	var (
+		mfa       domain.MFA
+		enabledAt sql.NullTime
+	)
+
+	err := db.db.QueryRowContext(ctx, `SELECT user_id, secret, created_at, enabled_at, last_step
+		FROM auth_mfa WHERE user_id = $1`, userID).
+		Scan(&mfa.UserID, &mfa.Secret, &mfa.CreatedAt, &enabledAt, &mfa.LastStep)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFANotFound
+	}
+	if err != nil {
+		return nil, db.classify(err)
+	}
+
+	mfa.EnabledAt = enabledAt.Time
+	return &mfa, nil
+}
+
+func (db *sqlDB) SaveMFA(ctx context.Context, mfa *domain.MFA) error {
+	if mfa.CreatedAt.IsZero() {
+		mfa.CreatedAt = now()
+	}
+
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM auth_mfa_recovery_codes WHERE user_id = $1`, mfa.UserID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO auth_mfa (user_id, secret, created_at, enabled_at, last_step)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id) DO UPDATE SET
+				secret = excluded.secret,
+				created_at = excluded.created_at,
+				enabled_at = excluded.enabled_at,
+				last_step = excluded.last_step`,
+			mfa.UserID, mfa.Secret, mfa.CreatedAt.UTC(), nullTime(mfa.EnabledAt), mfa.LastStep)
+		return err
+	})
+}
+
+func (db *sqlDB) EnableMFA(ctx context.Context, userID string, enabledAt time.Time, codeHashes []string) error {
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `UPDATE auth_mfa SET enabled_at = $2 WHERE user_id = $1`,
+			userID, enabledAt.UTC())
+		if err := requireAffected(result, err, ErrMFANotFound); err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+}
+
+func (db *sqlDB) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	// The last_step guard makes the check and the write one atomic step
+	result, err := db.db.ExecContext(ctx, `UPDATE auth_mfa SET last_step = $2
+		WHERE user_id = $1 AND last_step < $2`, userID, step)
+	err = requireAffected(result, err, ErrTOTPStepUsed)
+	if !errors.Is(err, ErrTOTPStepUsed) {
+		return db.classify(err)
+	}
+
+	// Nothing updated: tell a used step from a missing MFA
+	if _, err := db.FindMFA(ctx, userID); err != nil {
+		return err
+	}
+	return ErrTOTPStepUsed
+}
+
+func (db *sqlDB) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		var exists bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM auth_mfa WHERE user_id = $1)`, userID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrMFANotFound
+		}
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+}
+
+func (db *sqlDB) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	// Used codes are deleted; the delete lets only one caller have a code
+	result, err := db.db.ExecContext(ctx, `DELETE FROM auth_mfa_recovery_codes
+		WHERE user_id = $1 AND code_hash = $2`, userID, codeHash)
+	return db.classify(requireAffected(result, err, ErrRecoveryCodeNotFound))
+}
+
+func (db *sqlDB) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := db.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM auth_mfa_recovery_codes WHERE user_id = $1`, userID).Scan(&count)
+	return count, db.classify(err)
+}
+
+func (db *sqlDB) DeleteMFA(ctx context.Context, userID string) error {
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM auth_mfa_recovery_codes WHERE user_id = $1`, userID)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `DELETE FROM auth_mfa WHERE user_id = $1`, userID)
+		return requireAffected(result, err, ErrMFANotFound)
+	})
+}
+
+// replaceRecoveryCodes swaps the recovery codes of userID within tx
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string, codeHashes []string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM auth_mfa_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		_, err := tx.ExecContext(ctx, `INSERT INTO auth_mfa_recovery_codes (user_id, code_hash)
+			VALUES ($1, $2)`, userID, codeHash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"ecolink-core/internal/auth/domain"
+	"errors"
+	"time"
+)
+
+// The SQL adapters also implement the auth module's
+// repository.RefreshTokenRepository on auth_refresh_tokens
+
+func (db *sqlDB) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = now()
+	}
+
+	_, err := db.db.ExecContext(ctx, `INSERT INTO auth_refresh_tokens
+		(token_hash, family_id, user_id, created_at, expires_at, used_at, revoked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		token.TokenHash, token.FamilyID, token.UserID, token.CreatedAt.UTC(), token.ExpiresAt.UTC(),
+		nullTime(token.UsedAt), nullTime(token.RevokedAt))
+	if db.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return db.classify(err)
+}
+
+func (db *sqlDB) FindRefreshToken(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	var (
+		token     domain.RefreshToken
+		usedAt    sql.NullTime
+		revokedAt sql.NullTime
+	)
+
+	err := db.db.QueryRowContext(ctx, `SELECT token_hash, family_id, user_id, created_at, expires_at, used_at, revoked_at
+		FROM auth_refresh_tokens WHERE token_hash = $1`, tokenHash).
+		Scan(&token.TokenHash, &token.FamilyID, &token.UserID, &token.CreatedAt, &token.ExpiresAt, &usedAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, db.classify(err)
+	}
+
+	token.UsedAt = usedAt.Time
+	token.RevokedAt = revokedAt.Time
+	return &token, nil
+}
+
+func (db *sqlDB) UseRefreshToken(ctx context.Context, tokenHash string, usedAt time.Time) error {
+	// The used_at guard makes the check and the write one atomic step
+	result, err := db.db.ExecContext(ctx, `UPDATE auth_refresh_tokens SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL`, tokenHash, usedAt.UTC())
+	err = requireAffected(result, err, ErrRefreshTokenUsed)
+	if !errors.Is(err, ErrRefreshTokenUsed) {
+		return db.classify(err)
+	}
+
+	// Nothing updated: tell a used token from a missing one
+	if _, err := db.FindRefreshToken(ctx, tokenHash); err != nil {
+		return err
+	}
+	return ErrRefreshTokenUsed
+}
+
+func (db *sqlDB) RevokeRefreshTokenFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	_, err := db.db.ExecContext(ctx, `UPDATE auth_refresh_tokens SET revoked_at = $2
+		WHERE family_id = $1 AND revoked_at IS NULL`, familyID, revokedAt.UTC())
+	return db.classify(err)
+}
+
+func (db *sqlDB) RevokeUserRefreshTokens(ctx context.Context, userID string, revokedAt time.Time) error {
+	_, err := db.db.ExecContext(ctx, `UPDATE auth_refresh_tokens SET revoked_at = $2
+		WHERE user_id = $1 AND revoked_at IS NULL`, userID, revokedAt.UTC())
+	return db.classify(err)
+}
+
+// nullTime stores a zero time as NULL
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
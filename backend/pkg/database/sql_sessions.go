@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"ecolink-core/internal/auth/domain"
+	"errors"
+	"time"
+)
+
+// The SQL adapters also implement the auth module's
+// repository.SessionRepository on auth_sessions
+
+const sessionColumns = `id, user_id, user_agent, ip_prefix, created_at, last_seen_at, expires_at, revoked_at`
+
+func (db *sqlDB) CreateSession(ctx context.Context, session *domain.Session) error {
+	_, err := db.db.ExecContext(ctx, `INSERT INTO auth_sessions (`+sessionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		session.ID, session.UserID, session.UserAgent, session.IPPrefix, session.CreatedAt.UTC(),
+		session.LastSeenAt.UTC(), session.ExpiresAt.UTC(), nullTime(session.RevokedAt))
+	if db.isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return db.classify(err)
+}
+
+func (db *sqlDB) FindSession(ctx context.Context, sessionID string) (*domain.Session, error) {
+	session, err := scanSession(db.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM auth_sessions WHERE id = $1`, sessionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, db.classify(err)
+	}
+	return session, nil
+}
+
+func (db *sqlDB) ListUserSessions(ctx context.Context, userID string, now time.Time) ([]*domain.Session, error) {
+	rows, err := db.db.QueryContext(ctx, `SELECT `+sessionColumns+` FROM auth_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_seen_at DESC, id`, userID, now.UTC())
+	if err != nil {
+		return nil, db.classify(err)
+	}
+	defer rows.Close()
+
+	var sessions []*domain.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, db.classify(err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, db.classify(rows.Err())
+}
+
+func (db *sqlDB) UpdateSession(ctx context.Context, session *domain.Session) error {
+	result, err := db.db.ExecContext(ctx, `UPDATE auth_sessions
+		SET user_agent = $2, ip_prefix = $3, last_seen_at = $4, expires_at = $5 WHERE id = $1`,
+		session.ID, session.UserAgent, session.IPPrefix, session.LastSeenAt.UTC(), session.ExpiresAt.UTC())
+	return db.classify(requireAffected(result, err, ErrSessionNotFound))
+}
+
+func (db *sqlDB) TouchSession(ctx context.Context, sessionID string, seenAt time.Time) error {
+	result, err := db.db.ExecContext(ctx, `UPDATE auth_sessions SET last_seen_at = $2 WHERE id = $1`,
+		sessionID, seenAt.UTC())
+	return db.classify(requireAffected(result, err, ErrSessionNotFound))
+}
+
+func (db *sqlDB) RevokeSession(ctx context.Context, sessionID string, revokedAt time.Time) error {
+	result, err := db.db.ExecContext(ctx, `UPDATE auth_sessions SET revoked_at = COALESCE(revoked_at, $2) WHERE id = $1`,
+		sessionID, revokedAt.UTC())
+	return db.classify(requireAffected(result, err, ErrSessionNotFound))
+}
+
+func (db *sqlDB) RevokeUserSessions(ctx context.Context, userID string, revokedAt time.Time) error {
+	_, err := db.db.ExecContext(ctx, `UPDATE auth_sessions SET revoked_at = $2
+		WHERE user_id = $1 AND revoked_at IS NULL`, userID, revokedAt.UTC())
+	return db.classify(err)
+}
+
+func scanSession(row rowScanner) (*domain.Session, error) {
+	var (
+		session   domain.Session
+		revokedAt sql.NullTime
+	)
+
+	err := row.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPPrefix,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	session.RevokedAt = revokedAt.Time
+	return &session, nil
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// The SQL adapters also implement the auth module's
+// repository.TokenRevocationRepository on auth_revoked_tokens
+
+func (db *sqlDB) RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		// Tokens past their expiry are rejected anyway, so their rows go
+		_, err := tx.ExecContext(ctx, `DELETE FROM auth_revoked_tokens WHERE expires_at < $1`, now())
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO auth_revoked_tokens (jti, user_id, expires_at) VALUES ($1, $2, $3)
+			ON CONFLICT (jti) DO NOTHING`, jti, userID, expiresAt.UTC())
+		return err
+	})
+}
+
+func (db *sqlDB) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := db.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM auth_revoked_tokens WHERE jti = $1)`,
+		jti).Scan(&revoked)
+	if err != nil {
+		return false, db.classify(err)
+	}
+	return revoked, nil
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// SQLiteDB stores everything in a single local file, for single-node
+// installs and CI
+type SQLiteDB struct {
+	*sqlDB
+}
+
+// NewSQLiteDB opens or creates the database file at path and applies
+// pending migrations
+func NewSQLiteDB(path string) (*SQLiteDB, error) {
+	ctx := context.Background()
+
+	// WAL lets readers proceed during writes and busy_timeout makes
+	// concurrent writers wait instead of failing with SQLITE_BUSY
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Set("_time_format", "sqlite")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %v", err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open sqlite database %s: %v", path, err)
+	}
+
+	if err := migrate(ctx, db, sqliteMigrations, "migrations/sqlite", ""); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteDB{&sqlDB{
+		db:                db,
+		isUniqueViolation: isSQLiteUniqueViolation,
+		isBusy:            isSQLiteBusy,
+		hasTag:            hasSQLiteTag,
+	}}, nil
+}
+
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code()
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+// isSQLiteBusy reports lock contention that outlasted busy_timeout
+func isSQLiteBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code() & 0xff
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
+
+// hasSQLiteTag matches the tags JSON array containing the tag
+func hasSQLiteTag(placeholder string) string {
+	return "EXISTS (SELECT 1 FROM json_each(links.tags) WHERE json_each.value = " + placeholder + ")"
+}
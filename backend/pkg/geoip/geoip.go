@@ -0,0 +1,68 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location is the coarse position of an IP address
+type Location struct {
+	Country string // ISO 3166-1 alpha-2 code, e.g. "BR"
+	Region  string // ISO 3166-2 subdivision code without country, e.g. "SP"
+}
+
+// Locator resolves IP addresses to locations
+type Locator interface {
+	Lookup(ip string) (Location, error)
+}
+
+// MMDBLocator reads a local MaxMind-format database (GeoLite2, GeoIP2 or
+// compatible City/Country files). Lookups never touch the network.
+type MMDBLocator struct {
+	reader *maxminddb.Reader
+}
+
+// record maps the subset of City/Country database fields we use
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+}
+
+// OpenMMDB opens the database file at path
+func OpenMMDB(path string) (*MMDBLocator, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	return &MMDBLocator{reader: reader}, nil
+}
+
+// Lookup returns the location of ip, or an empty Location if unknown
+func (l *MMDBLocator) Lookup(ip string) (Location, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return Location{}, fmt.Errorf("invalid IP address: %q", ip)
+	}
+
+	var rec record
+	if err := l.reader.Lookup(parsed, &rec); err != nil {
+		return Location{}, err
+	}
+
+	location := Location{Country: rec.Country.ISOCode}
+	if len(rec.Subdivisions) > 0 {
+		location.Region = rec.Subdivisions[0].ISOCode
+	}
+	return location, nil
+}
+
+// Close releases the database; lookups must have stopped
+func (l *MMDBLocator) Close() error {
+	return l.reader.Close()
+}
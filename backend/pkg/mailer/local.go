@@ -0,0 +1,76 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogMailer writes emails to the standard logger instead of sending them.
+// It is meant for development: the log ends up holding working
+// verification and password reset links.
+type LogMailer struct {
+	from string
+}
+
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	var rendered strings.Builder
+	if err := msg.write(&rendered, m.from); err != nil {
+		return err
+	}
+	log.Printf("📧 Email to %s (not sent, MAIL_DRIVER=log):\n%s", msg.To, rendered.String())
+	return nil
+}
+
+// FileMailer appends emails to a file in mbox format, for tests and local
+// setups that read them back
+type FileMailer struct {
+	path  string
+	from  string
+	mutex sync.Mutex
+}
+
+func NewFileMailer(path, from string) *FileMailer {
+	return &FileMailer{path: path, from: from}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open mail file: %w", err)
+	}
+
+	_, err = fmt.Fprintf(file, "From MAILER-DAEMON %s\r\n", time.Now().UTC().Format(time.ANSIC))
+	if err == nil {
+		err = msg.write(file, m.from)
+	}
+	if err == nil {
+		_, err = file.WriteString("\r\n")
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write mail file: %w", err)
+	}
+	return nil
+}
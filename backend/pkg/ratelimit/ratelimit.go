@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Policy is a token bucket: it holds up to Burst requests and refills at
+// Rate requests per second
+type Policy struct {
+	Name  string // Namespaces the keys of this policy in a shared store
+	Rate  float64
+	Burst int
+}
+
+// Window is how long an empty bucket takes to refill completely
+func (p Policy) Window() time.Duration {
+	return time.Duration(float64(p.Burst) / p.Rate * float64(time.Second))
+}
+
+// Result is the state of a bucket after a request was counted
+type Result struct {
+	Allowed    bool
+	Limit      int           // The policy's burst
+	Remaining  int           // Requests left right now
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next request is allowed; zero if allowed
+}
+
+// Store keeps the buckets. MemoryStore serves a single instance; deployments
+// running several instances implement Store on a shared backend so the
+// limits hold across all of them.
+type Store interface {
+	// Take counts one request against the bucket of key under policy
+	Take(ctx context.Context, policy Policy, key string, now time.Time) (Result, error)
+}
+
+// bucket is a token bucket as of its last update
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	window  time.Duration // Of its policy; once idle this long it is full
+}
+
+// MemoryStore keeps buckets in process. Full buckets carry no state, so
+// they are dropped from time to time to bound memory.
+type MemoryStore struct {
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	mutex     sync.Mutex
+}
+
+// sweepInterval is how often full buckets are dropped
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, policy Policy, key string, now time.Time) (Result, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+
+	id := policy.Name + ":" + key
+	b, exists := s.buckets[id]
+	if !exists {
+		b = &bucket{tokens: float64(policy.Burst), updated: now, window: policy.Window()}
+		s.buckets[id] = b
+	}
+	b.refill(policy, now)
+
+	result := Result{Limit: policy.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = durationFor(1-b.tokens, policy.Rate)
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = durationFor(float64(policy.Burst)-b.tokens, policy.Rate)
+	return result, nil
+}
+
+// sweep drops the buckets that have refilled completely
+func (s *MemoryStore) sweep(now time.Time) {
+	for id, b := range s.buckets {
+		if now.Sub(b.updated) >= b.window {
+			delete(s.buckets, id)
+		}
+	}
+	s.lastSweep = now
+}
+
+// refill adds the tokens earned since the last update
+func (b *bucket) refill(policy Policy, now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(policy.Burst), b.tokens+elapsed*policy.Rate)
+		b.updated = now
+	}
+}
+
+// durationFor is how long rate takes to earn tokens
+func durationFor(tokens, rate float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / rate * float64(time.Second)))
+}
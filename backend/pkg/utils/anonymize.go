@@ -0,0 +1,19 @@
+package utils
+
+import "net"
+
+// AnonymizeIP truncates an IP address so it no longer identifies a single
+// client: IPv4 addresses keep their /24 network, IPv6 addresses their /48.
+// Unparseable input yields an empty string.
+func AnonymizeIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if ipv4 := parsed.To4(); ipv4 != nil {
+		return ipv4.Mask(net.CIDRMask(24, 32)).String()
+	}
+
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+)
+
+// botSignatures are lowercase user agent fragments of crawlers, link
+// unfurlers, uptime monitors and HTTP libraries
+var botSignatures = []string{
+	// Generic crawler markers
+	"bot", "crawler", "spider", "scraper", "headless",
+	// Link unfurlers and previews (iMessage announces itself as
+	// facebookexternalhit + Twitterbot)
+	"slack", "facebookexternalhit", "facebot", "whatsapp", "telegram",
+	"discord", "skypeuripreview", "iframely", "embedly", "vkshare",
+	"outlook-ios", "microsoft office", "google-pagerenderer", "bitlypreview",
+	// Uptime monitors
+	"pingdom", "uptime", "statuscake", "site24x7", "newrelicpinger",
+	"datadog", "betteruptime", "checkly", "monitor",
+	// Scripts and HTTP libraries
+	"curl/", "wget/", "python-requests", "python-urllib", "go-http-client",
+	"okhttp", "java/", "apache-httpclient", "node-fetch", "axios/", "libwww-perl",
+}
+
+// prefetchHeaders carry the speculative-load hints sent by browsers and
+// previewers; any of them containing prefetch or preview marks the request
+var prefetchHeaders = []string{"Purpose", "Sec-Purpose", "X-Purpose", "X-Moz"}
+
+// IsBotUserAgent reports whether a user agent belongs to an automated
+// client. An empty user agent is treated as a bot.
+func IsBotUserAgent(userAgent string) bool {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return true
+	}
+
+	for _, signature := range botSignatures {
+		if strings.Contains(ua, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPrefetchRequest reports whether a request was issued without a user
+// following the link: HEAD probes and browser prefetch or preview loads
+func IsPrefetchRequest(r *http.Request) bool {
+	if r.Method == http.MethodHead {
+		return true
+	}
+
+	for _, name := range prefetchHeaders {
+		value := strings.ToLower(r.Header.Get(name))
+		if strings.Contains(value, "prefetch") || strings.Contains(value, "preview") {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBotRequest classifies a redirect request as automated by its user agent
+// or prefetch hints
+func IsBotRequest(r *http.Request) bool {
+	return IsPrefetchRequest(r) || IsBotUserAgent(r.UserAgent())
+}
@@ -2,65 +2,98 @@ package utils
 
 import (
 	"crypto/rand"
-	"crypto/sha256"
+	"errors"
 	"fmt"
+	"math/big"
+	"strings"
 )
 
 const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
-// GenerateShortCode creates a secure short code using SHA-256 and crypto/rand
-func GenerateShortCode(url string) string {
-	// Generate cryptographically secure random bytes
-	randomBytes := make([]byte, 16)
-	if _, err := rand.Read(randomBytes); err != nil {
-		// Fallback to deterministic but still secure approach
-		return generateFallbackCode(url)
-	}
+// DefaultAlphabet is the base62 alphabet used for generated codes
+const DefaultAlphabet = charset
 
-	// Combine URL with random bytes and hash with SHA-256
-	hasher := sha256.New()
-	hasher.Write([]byte(url))
-	hasher.Write(randomBytes)
-	hash := hasher.Sum(nil)
+const (
+	// MinCodeLength is the shortest accepted short code or alias
+	MinCodeLength = 3
+	// MaxCodeLength is the longest accepted short code or alias
+	MaxCodeLength = 32
+)
 
-	// Convert first 8 bytes to base62 for 6-character code
-	return encodeBase62(hash[:8])
+// reservedCodes collide with application routes or are likely to be abused
+var reservedCodes = map[string]bool{
+	"api":       true,
+	"auth":      true,
+	"health":    true,
+	"admin":     true,
+	"login":     true,
+	"logout":    true,
+	"register":  true,
+	"dashboard": true,
+	"profile":   true,
+	"result":    true,
+	"static":    true,
+	"assets":    true,
 }
 
-func generateFallbackCode(url string) string {
-	// Secure fallback using only URL hash
-	hasher := sha256.New()
-	hasher.Write([]byte(url))
-	hash := hasher.Sum(nil)
-	return encodeBase62(hash[:8])
-}
+// GenerateCode creates a uniformly random code of the given length drawn
+// from alphabet using crypto/rand
+func GenerateCode(alphabet string, length int) (string, error) {
+	if len(alphabet) < 2 {
+		return "", errors.New("alphabet must have at least 2 characters")
+	}
+	if length < MinCodeLength || length > MaxCodeLength {
+		return "", fmt.Errorf("invalid code length: expected %d to %d, got %d", MinCodeLength, MaxCodeLength, length)
+	}
 
-func encodeBase62(input []byte) string {
-	result := make([]byte, 6)
+	max := big.NewInt(int64(len(alphabet)))
+	result := make([]byte, length)
 	for i := range result {
-		result[i] = charset[int(input[i%len(input)])%len(charset)]
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate code: %w", err)
+		}
+		result[i] = alphabet[n.Int64()]
 	}
-	return string(result)
+	return string(result), nil
 }
 
-// ValidateShortCode ensures the code meets security requirements
+// ValidateShortCode ensures the code meets security requirements.
+// Generated codes and custom aliases share the same rules: alphanumeric
+// characters plus inner hyphens and underscores, between MinCodeLength and
+// MaxCodeLength characters long.
 func ValidateShortCode(code string) error {
-	if len(code) != 6 {
-		return fmt.Errorf("invalid code length: expected 6, got %d", len(code))
+	if len(code) < MinCodeLength || len(code) > MaxCodeLength {
+		return fmt.Errorf("invalid code length: expected %d to %d, got %d", MinCodeLength, MaxCodeLength, len(code))
 	}
-	
-	for _, char := range code {
-		valid := false
-		for _, validChar := range charset {
-			if char == validChar {
-				valid = true
-				break
-			}
+
+	for i, char := range code {
+		if strings.ContainsRune(charset, char) {
+			continue
 		}
-		if !valid {
-			return fmt.Errorf("invalid character in code: %c", char)
+		if (char == '-' || char == '_') && i > 0 && i < len(code)-1 {
+			continue
 		}
+		return fmt.Errorf("invalid character in code: %c", char)
 	}
-	
+
 	return nil
 }
+
+// ValidateAlias checks a user-chosen alias for format and reserved words
+func ValidateAlias(alias string) error {
+	if err := ValidateShortCode(alias); err != nil {
+		return err
+	}
+
+	if IsReservedCode(alias) {
+		return fmt.Errorf("alias is reserved: %s", alias)
+	}
+
+	return nil
+}
+
+// IsReservedCode reports whether a code clashes with a reserved word
+func IsReservedCode(code string) bool {
+	return reservedCodes[strings.ToLower(code)]
+}
@@ -0,0 +1,106 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountEmails(t *testing.T) {
+	mbox := filepath.Join(t.TempDir(), "mail.mbox")
+	t.Setenv("MAIL_DRIVER", "file")
+	t.Setenv("MAIL_FILE", mbox)
+	t.Setenv("FRONTEND_URL", "https://app.example.com")
+	router := newAuthRouter(t)
+
+	w := serveAuth(router, http.MethodPost, "/auth/register", `{"name": "Ada", "email": "ada@example.com", "password": "correct-horse"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	t.Run("verifies the address of a new account", func(t *testing.T) {
+		token := mailedToken(t, mbox, "/verify-email")
+
+		w := serveAuth(router, http.MethodPost, "/auth/verify", `{"token": "`+token+`"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var verified struct {
+			User struct {
+				EmailVerifiedAt *time.Time `json:"email_verified_at"`
+			} `json:"user"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &verified))
+		assert.NotNil(t, verified.User.EmailVerifiedAt)
+
+		w = serveAuth(router, http.MethodPost, "/auth/verify", `{"token": "`+token+`"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "links work once")
+	})
+
+	t.Run("answers forgotten passwords alike for unknown emails", func(t *testing.T) {
+		known := serveAuth(router, http.MethodPost, "/auth/forgot", `{"email": "ada@example.com"}`)
+		unknown := serveAuth(router, http.MethodPost, "/auth/forgot", `{"email": "nobody@example.com"}`)
+		assert.Equal(t, http.StatusAccepted, known.Code)
+		assert.Equal(t, known.Code, unknown.Code)
+		assert.Equal(t, known.Body.String(), unknown.Body.String())
+	})
+
+	t.Run("resets a password and logs sessions out", func(t *testing.T) {
+		w := serveAuth(router, http.MethodPost, "/auth/login", `{"email": "ada@example.com", "password": "correct-horse"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		access := responseCookie(t, w, "ecolink_token")
+
+		w = serveAuth(router, http.MethodPost, "/auth/forgot", `{"email": "ada@example.com"}`)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		token := mailedToken(t, mbox, "/reset-password")
+
+		w = serveAuth(router, http.MethodPost, "/auth/reset", `{"token": "`+token+`", "password": "short"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "the new password is validated")
+		w = serveAuth(router, http.MethodPost, "/auth/reset", `{"token": "`+token+`", "password": "battery-staple"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = serveAuth(router, http.MethodGet, "/api/v1/me", "", access)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = serveAuth(router, http.MethodPost, "/auth/login", `{"email": "ada@example.com", "password": "correct-horse"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = serveAuth(router, http.MethodPost, "/auth/login", `{"email": "ada@example.com", "password": "battery-staple"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = serveAuth(router, http.MethodPost, "/auth/reset", `{"token": "`+token+`", "password": "another-password"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "links work once")
+	})
+
+	t.Run("refuses unknown links", func(t *testing.T) {
+		w := serveAuth(router, http.MethodPost, "/auth/reset", `{"token": "made-up", "password": "battery-staple"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = serveAuth(router, http.MethodPost, "/auth/verify", `{"token": "made-up"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+// mailedToken waits for a link to the frontend page at path in the mbox
+// written by the file mailer and returns the token of the latest one
+func mailedToken(t *testing.T, mbox, path string) string {
+	t.Helper()
+	link := regexp.MustCompile(regexp.QuoteMeta("https://app.example.com"+path) + `\?token=(\S+)`)
+	var token string
+	require.Eventually(t, func() bool {
+		content, err := os.ReadFile(mbox)
+		if err != nil {
+			return false
+		}
+		matches := link.FindAllStringSubmatch(string(content), -1)
+		if len(matches) == 0 {
+			return false
+		}
+		token, err = url.QueryUnescape(strings.TrimSpace(matches[len(matches)-1][1]))
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	return token
+}
@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys(t *testing.T) {
+	router := newAuthRouter(t)
+	w := serveAuth(router, http.MethodPost, "/auth/register", `{"name": "Ada", "email": "ada@example.com", "password": "correct-horse"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = serveAuth(router, http.MethodPost, "/auth/login", `{"email": "ada@example.com", "password": "correct-horse"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	access := responseCookie(t, w, "ecolink_token")
+
+	createKey := func(body string) (int, string) {
+		w := csrfRequest(t, router, access, http.MethodPost, "/api/v1/api-keys", body)
+		var created struct {
+			Key string `json:"key"`
+		}
+		if w.Code == http.StatusCreated {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		}
+		return w.Code, created.Key
+	}
+	withKey := func(method, path, body, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	code, writer := createKey(`{"name": "ci", "scopes": ["links:read", "links:write"]}`)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Regexp(t, `^eck_`, writer)
+	code, reader := createKey(`{"name": "dashboard", "scopes": ["stats:read"]}`)
+	require.Equal(t, http.StatusCreated, code)
+
+	t.Run("rejects invalid keys at creation", func(t *testing.T) {
+		code, _ := createKey(`{"name": "bad", "scopes": ["links:admin"]}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _ = createKey(`{"name": "bad", "scopes": []}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _ = createKey(`{"name": "bad", "scopes": ["links:read"], "expires_at": "2000-01-01T00:00:00Z"}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("creates links without CSRF tokens", func(t *testing.T) {
+		w := withKey(http.MethodPost, "/api/v1/links", `{"url": "https://example.com/from-ci"}`, writer)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		w = withKey(http.MethodGet, "/api/v1/links", "", writer)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "https://example.com/from-ci")
+	})
+
+	t.Run("enforces scopes", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, withKey(http.MethodGet, "/api/v1/links", "", reader).Code)
+		assert.Equal(t, http.StatusForbidden, withKey(http.MethodPost, "/api/v1/links", `{"url": "https://example.com"}`, reader).Code)
+	})
+
+	t.Run("cannot manage the account", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, withKey(http.MethodGet, "/api/v1/api-keys", "", writer).Code)
+		assert.Equal(t, http.StatusForbidden, withKey(http.MethodPost, "/api/v1/api-keys", `{"name": "x", "scopes": ["links:read"]}`, writer).Code)
+		assert.Equal(t, http.StatusForbidden, withKey(http.MethodGet, "/api/v1/me", "", writer).Code)
+	})
+
+	t.Run("is only accepted in the Authorization header", func(t *testing.T) {
+		w := serveAuth(router, http.MethodGet, "/api/v1/links", "", &http.Cookie{Name: "ecolink_token", Value: writer})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, http.StatusUnauthorized, withKey(http.MethodGet, "/api/v1/links", "", "eck_unknown").Code)
+	})
+
+	t.Run("lists keys without their values and deletes them", func(t *testing.T) {
+		w := serveAuth(router, http.MethodGet, "/api/v1/api-keys", "", access)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), writer)
+		var listed struct {
+			APIKeys []struct {
+				ID         string   `json:"id"`
+				Name       string   `json:"name"`
+				Prefix     string   `json:"prefix"`
+				Scopes     []string `json:"scopes"`
+				LastUsedAt *string  `json:"last_used_at"`
+			} `json:"api_keys"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+		require.Len(t, listed.APIKeys, 2)
+
+		var writerID string
+		for _, key := range listed.APIKeys {
+			if key.Name == "ci" {
+				writerID = key.ID
+				assert.Equal(t, writer[:len(key.Prefix)], key.Prefix)
+				assert.Equal(t, []string{"links:read", "links:write"}, key.Scopes)
+				assert.NotNil(t, key.LastUsedAt, "use is tracked")
+			}
+		}
+		require.NotEmpty(t, writerID)
+
+		assert.Equal(t, http.StatusNotFound, csrfRequest(t, router, access, http.MethodDelete, "/api/v1/api-keys/nope", "").Code)
+		assert.Equal(t, http.StatusOK, csrfRequest(t, router, access, http.MethodDelete, "/api/v1/api-keys/"+writerID, "").Code)
+		assert.Equal(t, http.StatusUnauthorized, withKey(http.MethodGet, "/api/v1/links", "", writer).Code)
+	})
+}
+
+// csrfRequest sends a request of a logged in browser through the CSRF
+// protection: it fetches a CSRF token and echoes it in the header
+func csrfRequest(t *testing.T, router *gin.Engine, access *http.Cookie, method, path, body string) *httptest.ResponseRecorder {
+	w := serveAuth(router, http.MethodGet, "/api/v1/csrf-token", "", access)
+	require.Equal(t, http.StatusOK, w.Code)
+	var csrf map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &csrf))
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CSRF-Token", csrf["csrf_token"])
+	req.AddCookie(access)
+	req.AddCookie(responseCookie(t, w, "csrf_token"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,83 @@
+package integration
+
+import (
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"ecolink-core/internal/auth/usecase"
+	"ecolink-core/internal/services"
+	"ecolink-core/pkg/database"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileReadsAuthUsers(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "ecolink.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The link cache wraps the database in production
+	cached := database.NewCachedDB(db, database.CacheConfig{Size: 10, TTL: time.Minute})
+	tokens := usecase.NewJWTTokenService("test-secret-key-32-characters-long", "ecolink", usecase.DefaultAccessTokenTTL)
+	authService := usecase.NewAuthService(cached, cached, cached, usecase.NewLoginGuard(cached, cached, usecase.DefaultLockoutPolicy), tokens, usecase.GoogleConfig{}, usecase.DefaultRefreshTokenTTL)
+	userService := services.NewUserService(cached)
+
+	registered, err := authService.Register(ctx, "Ada", "ada@example.com", "correct-horse")
+	require.NoError(t, err)
+
+	profile, err := userService.GetUser(ctx, registered.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.com", profile.Email)
+
+	// Users signed in with Google live in the same store
+	_, err = authService.UserRepo.CreateUserFromSocial(ctx,
+		&domain.User{ID: "social-1", Email: "grace@example.com", Name: "Grace"},
+		&domain.SocialProfile{UserID: "social-1", Provider: "google", ProviderID: "g-1"})
+	require.NoError(t, err)
+
+	profile, err = userService.GetUser(ctx, "social-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Grace", profile.Name)
+}
+
+func TestAuthUsersSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ecolink.db")
+	tokens := usecase.NewJWTTokenService("test-secret-key-32-characters-long", "ecolink", usecase.DefaultAccessTokenTTL)
+
+	db, err := database.NewSQLiteDB(path)
+	require.NoError(t, err)
+	authService := usecase.NewAuthService(db, db, db, usecase.NewLoginGuard(db, db, usecase.DefaultLockoutPolicy), tokens, usecase.GoogleConfig{}, usecase.DefaultRefreshTokenTTL)
+
+	registered, err := authService.Register(ctx, "Ada", "ada@example.com", "correct-horse")
+	require.NoError(t, err)
+	_, err = authService.UserRepo.CreateUserFromSocial(ctx,
+		&domain.User{ID: "social-1", Email: "grace@example.com", Name: "Grace"},
+		&domain.SocialProfile{UserID: "social-1", Provider: "google", ProviderID: "g-1"})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = database.NewSQLiteDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+	authService = usecase.NewAuthService(db, db, db, usecase.NewLoginGuard(db, db, usecase.DefaultLockoutPolicy), tokens, usecase.GoogleConfig{}, usecase.DefaultRefreshTokenTTL)
+
+	token, err := authService.Login(ctx, "ada@example.com", "correct-horse", domain.Client{})
+	require.NoError(t, err)
+	assert.Equal(t, registered.ID, token.UserID)
+
+	user, err := authService.UserRepo.FindByID(ctx, registered.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", user.Name)
+
+	_, err = authService.Register(ctx, "Ada again", "ada@example.com", "another-pass")
+	assert.Error(t, err)
+
+	// Social-only accounts have no password to log in with
+	_, err = authService.Login(ctx, "grace@example.com", "", domain.Client{})
+	assert.EqualError(t, err, "invalid credentials")
+}
@@ -25,7 +25,7 @@ func TestAuthFlow(t *testing.T) {
 	db, err := bootstrap.NewDBConnection(cfg)
 	assert.NoError(t, err)
 	
-	router := bootstrap.NewRouter(cfg, db)
+	router := newApplication(t, cfg, db).Router
 
 	t.Run("Health Check", func(t *testing.T) {
 		w := httptest.NewRecorder()
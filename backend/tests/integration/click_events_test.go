@@ -0,0 +1,210 @@
+package integration
+
+import (
+	"context"
+	"ecolink-core/internal/analytics/repository"
+	analytics "ecolink-core/internal/analytics/usecase"
+	"ecolink-core/internal/handlers"
+	"ecolink-core/internal/models"
+	"ecolink-core/internal/security"
+	"ecolink-core/internal/services"
+	"ecolink-core/pkg/database"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLinkHandler wires a link handler whose clicks are recorded in the
+// background; closing the returned recorder flushes them
+func newLinkHandler(t *testing.T, linkService *services.LinkService, db database.Database, clickRepo repository.ClickRepository) (*handlers.LinkHandler, *analytics.ClickRecorder) {
+	t.Helper()
+
+	analyticsService := analytics.NewAnalyticsService(clickRepo, nil)
+	clicks := analytics.NewClickRecorder(analyticsService, db, analytics.RecorderConfig{})
+	t.Cleanup(func() { clicks.Close(context.Background()) })
+
+	unlocker := security.NewLinkUnlocker("test-secret-key-32-characters-long", time.Minute)
+	return handlers.NewLinkHandler(linkService, analyticsService, clicks, unlocker), clicks
+}
+
+func TestRedirectRecordsClickEvents(t *testing.T) {
+	ctx := context.Background()
+	gin.SetMode(gin.TestMode)
+
+	db := database.NewMemoryDB()
+	clickRepo := repository.NewInMemoryClickRepository()
+	linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{}, nil)
+	linkHandler, clicks := newLinkHandler(t, linkService, db, clickRepo)
+
+	router := gin.New()
+	router.GET("/:code", linkHandler.RedirectLink)
+
+	require.NoError(t, db.SaveLink(ctx, &models.Link{Code: "promo1", URL: "https://example.com"}))
+
+	req := httptest.NewRequest("GET", "/promo1?src=newsletter", nil)
+	req.RemoteAddr = "203.0.113.42:51234"
+	req.Header.Set("Referer", "https://news.example.org/issue-12")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusFound, w.Code)
+	require.NoError(t, clicks.Close(context.Background()))
+
+	events, err := clickRepo.FindByCode(req.Context(), "promo1", time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	event := events[0]
+	assert.NotEmpty(t, event.ID)
+	assert.Equal(t, "promo1", event.Code)
+	assert.Equal(t, "https://news.example.org/issue-12", event.Referrer)
+	assert.Equal(t, "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0", event.UserAgent)
+	assert.Equal(t, "203.0.113.0", event.IPPrefix)
+	assert.Equal(t, "newsletter", event.Source)
+	assert.WithinDuration(t, time.Now(), event.Timestamp, time.Minute)
+
+	link, err := db.GetLink(ctx, "promo1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, link.Clicks)
+}
+
+func TestLinkStatsEndpoint(t *testing.T) {
+	ctx := context.Background()
+	gin.SetMode(gin.TestMode)
+
+	db := database.NewMemoryDB()
+	clickRepo := repository.NewInMemoryClickRepository()
+	linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{}, nil)
+	linkHandler, clicks := newLinkHandler(t, linkService, db, clickRepo)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		// Stand-in for RequireAuth
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	})
+	router.GET("/:code", linkHandler.RedirectLink)
+	router.GET("/api/v1/links/:code/stats", linkHandler.GetLinkStats)
+
+	require.NoError(t, db.SaveLink(ctx, &models.Link{Code: "promo1", URL: "https://example.com", UserID: "owner"}))
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/promo1", nil)
+		req.Header.Set("User-Agent", browserUserAgent)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusFound, w.Code)
+	}
+	require.NoError(t, clicks.Close(context.Background()))
+
+	getStats := func(userID, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/links/promo1/stats"+query, nil)
+		req.Header.Set("X-Test-User", userID)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("owner gets hourly stats", func(t *testing.T) {
+		w := getStats("owner", "?interval=hour&from="+time.Now().Add(-2*time.Hour).UTC().Format(time.RFC3339))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var stats struct {
+			TotalClicks int `json:"totalClicks"`
+			Series      []struct {
+				Clicks int `json:"clicks"`
+			} `json:"series"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		assert.Equal(t, 3, stats.TotalClicks)
+		assert.Len(t, stats.Series, 3)
+	})
+
+	t.Run("other users are forbidden", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, getStats("intruder", "").Code)
+	})
+
+	t.Run("bad parameters are rejected", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, getStats("owner", "?interval=year").Code)
+		assert.Equal(t, http.StatusBadRequest, getStats("owner", "?from=yesterday").Code)
+	})
+}
+
+const browserUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15"
+
+func TestRedirectSeparatesBotClicks(t *testing.T) {
+	ctx := context.Background()
+	gin.SetMode(gin.TestMode)
+
+	db := database.NewMemoryDB()
+	clickRepo := repository.NewInMemoryClickRepository()
+	linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{}, nil)
+	linkHandler, clicks := newLinkHandler(t, linkService, db, clickRepo)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		// Stand-in for RequireAuth
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	})
+	router.GET("/:code", linkHandler.RedirectLink)
+	router.HEAD("/:code", linkHandler.RedirectLink)
+	router.GET("/api/v1/links/:code/stats", linkHandler.GetLinkStats)
+
+	require.NoError(t, db.SaveLink(ctx, &models.Link{Code: "launch", URL: "https://example.com", UserID: "owner", MaxClicks: 2}))
+
+	visits := []struct {
+		method  string
+		headers map[string]string
+	}{
+		{"GET", map[string]string{"User-Agent": browserUserAgent}},
+		{"GET", map[string]string{"User-Agent": "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"}},
+		{"GET", map[string]string{"User-Agent": "facebookexternalhit/1.1 Facebot Twitterbot/1.0"}},
+		{"GET", map[string]string{"User-Agent": "Mozilla/5.0 (compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)"}},
+		{"GET", map[string]string{"User-Agent": browserUserAgent, "Sec-Purpose": "prefetch"}},
+		{"HEAD", map[string]string{"User-Agent": browserUserAgent}},
+	}
+	for _, visit := range visits {
+		req := httptest.NewRequest(visit.method, "/launch", nil)
+		for name, value := range visit.headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusFound, w.Code, "bots must not exhaust the click budget")
+	}
+	require.NoError(t, clicks.Close(context.Background()))
+
+	link, err := db.GetLink(ctx, "launch")
+	require.NoError(t, err)
+	assert.Equal(t, 1, link.Clicks)
+	assert.Equal(t, 5, link.BotClicks)
+
+	getStats := func(query string) (stats struct {
+		TotalClicks int  `json:"totalClicks"`
+		BotClicks   int  `json:"botClicks"`
+		IncludeBots bool `json:"includeBots"`
+	}) {
+		req := httptest.NewRequest("GET", "/api/v1/links/launch/stats"+query, nil)
+		req.Header.Set("X-Test-User", "owner")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		return stats
+	}
+
+	humanOnly := getStats("")
+	assert.Equal(t, 1, humanOnly.TotalClicks)
+	assert.Equal(t, 5, humanOnly.BotClicks)
+	assert.False(t, humanOnly.IncludeBots)
+
+	withBots := getStats("?include_bots=true")
+	assert.Equal(t, 6, withBots.TotalClicks)
+	assert.True(t, withBots.IncludeBots)
+}
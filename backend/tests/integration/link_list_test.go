@@ -0,0 +1,122 @@
+package integration
+
+import (
+	"context"
+	"ecolink-core/internal/analytics/repository"
+	"ecolink-core/internal/models"
+	"ecolink-core/internal/services"
+	"ecolink-core/pkg/database"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListLinks(t *testing.T) {
+	ctx := context.Background()
+	gin.SetMode(gin.TestMode)
+
+	db := database.NewMemoryDB()
+	linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{}, nil)
+	linkHandler, _ := newLinkHandler(t, linkService, db, repository.NewInMemoryClickRepository())
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		// Stand-in for RequireAuth
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	})
+	router.GET("/api/v1/links", linkHandler.GetUserLinks)
+
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		tags := []string{"all"}
+		if i%2 == 0 {
+			tags = append(tags, "even")
+		}
+		require.NoError(t, db.SaveLink(ctx, &models.Link{
+			Code:      fmt.Sprintf("link%d", i),
+			URL:       fmt.Sprintf("https://example.com/%d", i),
+			UserID:    "owner",
+			Tags:      tags,
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+	require.NoError(t, db.SaveLink(ctx, &models.Link{Code: "foreign", URL: "https://example.com", UserID: "someone-else", CreatedAt: base}))
+
+	type listResponse struct {
+		Links      []*models.Link `json:"links"`
+		NextCursor string         `json:"nextCursor"`
+	}
+
+	list := func(params url.Values) (*httptest.ResponseRecorder, listResponse) {
+		req := httptest.NewRequest("GET", "/api/v1/links?"+params.Encode(), nil)
+		req.Header.Set("X-Test-User", "owner")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var resp listResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w, resp
+	}
+
+	t.Run("pages newest first", func(t *testing.T) {
+		var codes []string
+		params := url.Values{"limit": {"2"}}
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 5, "pagination does not terminate")
+			w, resp := list(params)
+			require.Equal(t, http.StatusOK, w.Code)
+			for _, link := range resp.Links {
+				codes = append(codes, link.Code)
+			}
+			if resp.NextCursor == "" {
+				break
+			}
+			params.Set("cursor", resp.NextCursor)
+		}
+		assert.Equal(t, []string{"link4", "link3", "link2", "link1", "link0"}, codes)
+	})
+
+	t.Run("filters and sorts", func(t *testing.T) {
+		w, resp := list(url.Values{"tag": {"even"}, "order": {"asc"}})
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, resp.Links, 3)
+		assert.Equal(t, "link0", resp.Links[0].Code)
+		assert.Empty(t, resp.NextCursor)
+
+		w, resp = list(url.Values{"q": {"example.com/3"}})
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, resp.Links, 1)
+		assert.Equal(t, "link3", resp.Links[0].Code)
+	})
+
+	t.Run("empty result is an empty list", func(t *testing.T) {
+		w, _ := list(url.Values{"tag": {"missing"}})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"links": [], "nextCursor": ""}`, w.Body.String())
+	})
+
+	t.Run("rejects invalid parameters", func(t *testing.T) {
+		for _, params := range []url.Values{
+			{"sort": {"title"}},
+			{"order": {"sideways"}},
+			{"status": {"archived"}},
+			{"limit": {"0"}},
+			{"from": {"yesterday"}},
+			{"cursor": {"garbage"}},
+		} {
+			w, _ := list(params)
+			assert.Equal(t, http.StatusBadRequest, w.Code, params.Encode())
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"ecolink-core/internal/analytics/repository"
+	"ecolink-core/internal/models"
+	"ecolink-core/internal/services"
+	"ecolink-core/pkg/database"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateLink(t *testing.T) {
+	ctx := context.Background()
+	gin.SetMode(gin.TestMode)
+
+	db := database.NewMemoryDB()
+	linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{}, nil)
+	linkHandler, _ := newLinkHandler(t, linkService, db, repository.NewInMemoryClickRepository())
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		// Stand-in for RequireAuth
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	})
+	router.PATCH("/api/v1/links/:code", linkHandler.UpdateLink)
+
+	future := time.Now().Add(24 * time.Hour)
+	require.NoError(t, db.SaveLink(ctx, &models.Link{
+		Code:      "promo1",
+		URL:       "https://example.com/old",
+		UserID:    "owner",
+		Clicks:    7,
+		ExpiresAt: &future,
+	}))
+	require.NoError(t, db.SaveLink(ctx, &models.Link{Code: "taken1", URL: "https://example.com", UserID: "owner"}))
+
+	patch := func(code, userID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/api/v1/links/"+code, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", userID)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("owner can change destination and metadata", func(t *testing.T) {
+		w := patch("promo1", "owner", `{"url":"https://example.com/new","title":"Spring promo","tags":["spring"]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		link, err := db.GetLink(ctx, "promo1")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/new", link.URL)
+		assert.Equal(t, "Spring promo", link.Title)
+		assert.Equal(t, []string{"spring"}, link.Tags)
+		assert.Equal(t, 7, link.Clicks)
+		assert.NotNil(t, link.ExpiresAt, "absent fields stay unchanged")
+	})
+
+	t.Run("null expiresAt removes the expiry", func(t *testing.T) {
+		w := patch("promo1", "owner", `{"expiresAt":null}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		link, err := db.GetLink(ctx, "promo1")
+		require.NoError(t, err)
+		assert.Nil(t, link.ExpiresAt)
+	})
+
+	t.Run("alias change renames the link", func(t *testing.T) {
+		w := patch("promo1", "owner", `{"alias":"spring-sale"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Link models.Link `json:"link"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "spring-sale", resp.Link.Code)
+
+		_, err := db.GetLink(ctx, "promo1")
+		assert.Error(t, err)
+		link, err := db.GetLink(ctx, "spring-sale")
+		require.NoError(t, err)
+		assert.Equal(t, 7, link.Clicks)
+	})
+
+	t.Run("alias already in use conflicts", func(t *testing.T) {
+		w := patch("spring-sale", "owner", `{"alias":"taken1"}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		link, err := db.GetLink(ctx, "taken1")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com", link.URL)
+	})
+
+	t.Run("non-owner is forbidden", func(t *testing.T) {
+		w := patch("spring-sale", "intruder", `{"url":"https://evil.example.com"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		link, err := db.GetLink(ctx, "spring-sale")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/new", link.URL)
+	})
+
+	t.Run("invalid input is rejected", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, patch("spring-sale", "owner", `{"url":"not a url"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, patch("spring-sale", "owner", `{"alias":"api"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, patch("spring-sale", "owner", `{"expiresAt":"2001-01-01T00:00:00Z"}`).Code)
+	})
+
+	t.Run("empty fallbackUrl clears it", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, patch("spring-sale", "owner", `{"fallbackUrl":"https://example.com/ended"}`).Code)
+		require.Equal(t, http.StatusOK, patch("spring-sale", "owner", `{"fallbackUrl":""}`).Code)
+
+		link, err := db.GetLink(ctx, "spring-sale")
+		require.NoError(t, err)
+		assert.Empty(t, link.FallbackURL)
+	})
+
+	t.Run("unknown link returns 404", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, patch("missing", "owner", `{"title":"x"}`).Code)
+	})
+}
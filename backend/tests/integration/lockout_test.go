@@ -0,0 +1,66 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginLockout(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "2")
+	router := newAuthRouter(t)
+	w := serveAuth(router, http.MethodPost, "/auth/register", `{"name": "Ada", "email": "ada@example.com", "password": "correct-horse"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = serveAuth(router, http.MethodPost, "/auth/login", `{"email": "ada@example.com", "password": "correct-horse"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	access := responseCookie(t, w, "ecolink_token")
+
+	login := func(email, password string) int {
+		return serveAuth(router, http.MethodPost, "/auth/login", `{"email": "`+email+`", "password": "`+password+`"}`).Code
+	}
+
+	t.Run("answers unknown emails like wrong passwords", func(t *testing.T) {
+		known := serveAuth(router, http.MethodPost, "/auth/login", `{"email": "ada@example.com", "password": "wrong"}`)
+		unknown := serveAuth(router, http.MethodPost, "/auth/login", `{"email": "nobody@example.com", "password": "wrong"}`)
+		assert.Equal(t, http.StatusUnauthorized, known.Code)
+		assert.Equal(t, known.Body.String(), unknown.Body.String())
+	})
+
+	t.Run("locks the account after repeated failures", func(t *testing.T) {
+		w := serveAuth(router, http.MethodPost, "/auth/login", `{"email": "ada@example.com", "password": "wrong"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = serveAuth(router, http.MethodPost, "/auth/login", `{"email": "ada@example.com", "password": "correct-horse"}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		assert.InDelta(t, 60, retryAfter, 2)
+
+		// Unknown emails lock the same way
+		assert.Equal(t, http.StatusUnauthorized, login("nobody@example.com", "wrong"))
+		assert.Equal(t, http.StatusTooManyRequests, login("nobody@example.com", "wrong"))
+	})
+
+	t.Run("lists the account's auth events", func(t *testing.T) {
+		w := serveAuth(router, http.MethodGet, "/api/v1/auth-events", "", access)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var listed struct {
+			Events []struct {
+				Type     string `json:"type"`
+				IPPrefix string `json:"ip_prefix"`
+			} `json:"events"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+
+		var types []string
+		for _, event := range listed.Events {
+			types = append(types, event.Type)
+		}
+		assert.Equal(t, []string{"login_locked", "lockout_started", "login_failed", "login_failed", "login_succeeded"}, types)
+		assert.NotContains(t, w.Body.String(), "nobody@example.com")
+	})
+}
@@ -0,0 +1,68 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogoutRevokesTokens(t *testing.T) {
+	router := newAuthRouter(t)
+	w := serveAuth(router, http.MethodPost, "/auth/register", `{"name": "Ada", "email": "ada@example.com", "password": "correct-horse"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	login := func() (access, refresh *http.Cookie) {
+		w := serveAuth(router, http.MethodPost, "/auth/login", `{"email": "ada@example.com", "password": "correct-horse"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return responseCookie(t, w, "ecolink_token"), responseCookie(t, w, "ecolink_refresh")
+	}
+	me := func(access *http.Cookie) int {
+		return serveAuth(router, http.MethodGet, "/api/v1/me", "", access).Code
+	}
+
+	t.Run("logout revokes the access token", func(t *testing.T) {
+		access, refresh := login()
+		other, _ := login()
+		require.Equal(t, http.StatusOK, me(access))
+
+		// A copy of the token kept by an attacker stops working too
+		w := serveAuth(router, http.MethodPost, "/auth/logout", "", access, refresh)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusUnauthorized, me(access))
+		assert.Equal(t, http.StatusOK, me(other))
+
+		// Logging out again is harmless
+		assert.Equal(t, http.StatusOK, serveAuth(router, http.MethodPost, "/auth/logout", "", access, refresh).Code)
+	})
+
+	t.Run("revoking all sessions logs out every device", func(t *testing.T) {
+		access, _ := login()
+		otherAccess, otherRefresh := login()
+
+		w := serveAuth(router, http.MethodGet, "/api/v1/csrf-token", "", access)
+		require.Equal(t, http.StatusOK, w.Code)
+		var csrf map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &csrf))
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/sessions/revoke-all", nil)
+		req.Header.Set("X-CSRF-Token", csrf["csrf_token"])
+		req.AddCookie(access)
+		req.AddCookie(responseCookie(t, w, "csrf_token"))
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		assert.Equal(t, http.StatusUnauthorized, me(access))
+		assert.Equal(t, http.StatusUnauthorized, me(otherAccess))
+		assert.Equal(t, http.StatusUnauthorized, serveAuth(router, http.MethodPost, "/auth/refresh", "", otherRefresh).Code)
+
+		// Logging in again right away, likely within the same second, works
+		fresh, freshRefresh := login()
+		assert.Equal(t, http.StatusOK, me(fresh))
+		assert.Equal(t, http.StatusOK, serveAuth(router, http.MethodPost, "/auth/refresh", "", freshRefresh).Code)
+	})
+}
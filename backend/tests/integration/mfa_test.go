@@ -0,0 +1,141 @@
+package integration
+
+import (
+	"ecolink-core/pkg/totp"
+	"encoding/base32"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMFA(t *testing.T) {
+	t.Setenv("MFA_REQUIRED_DOMAINS", "corp.example")
+	router := newAuthRouter(t)
+
+	register := func(email string) *http.Cookie {
+		w := serveAuth(router, http.MethodPost, "/auth/register", `{"name": "Ada", "email": "`+email+`", "password": "correct-horse"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		w = serveAuth(router, http.MethodPost, "/auth/login", `{"email": "`+email+`", "password": "correct-horse"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return responseCookie(t, w, "ecolink_token")
+	}
+	access := register("ada@corp.example")
+
+	t.Run("holds back link writes until enabled", func(t *testing.T) {
+		w := csrfRequest(t, router, access, http.MethodPost, "/api/v1/links", `{"url": "https://example.com/docs"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = serveAuth(router, http.MethodGet, "/api/v1/mfa", "", access)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"enabled": false, "required": true, "recovery_codes_left": 0}`, w.Body.String())
+
+		// Reading links stays open
+		assert.Equal(t, http.StatusOK, serveAuth(router, http.MethodGet, "/api/v1/links", "", access).Code)
+	})
+
+	var secret []byte
+	var recoveryCodes []string
+	t.Run("enrolls an authenticator app", func(t *testing.T) {
+		w := csrfRequest(t, router, access, http.MethodPost, "/api/v1/mfa/enroll", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var enrollment struct {
+			Secret string `json:"secret"`
+			URI    string `json:"uri"`
+			QRCode string `json:"qr_code"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &enrollment))
+		assert.NotEmpty(t, enrollment.QRCode)
+		assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/"))
+		var err error
+		secret, err = base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+		require.NoError(t, err)
+
+		w = csrfRequest(t, router, access, http.MethodPost, "/api/v1/mfa/confirm", `{"code": "000000"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		code := totp.Code(secret, totp.Step(time.Now()))
+		w = csrfRequest(t, router, access, http.MethodPost, "/api/v1/mfa/confirm", `{"code": "`+code+`"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var confirmed struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &confirmed))
+		require.NotEmpty(t, confirmed.RecoveryCodes)
+		recoveryCodes = confirmed.RecoveryCodes
+
+		w = csrfRequest(t, router, access, http.MethodPost, "/api/v1/links", `{"url": "https://example.com/docs"}`)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	})
+
+	t.Run("logs in in two steps", func(t *testing.T) {
+		require.NotEmpty(t, recoveryCodes)
+		w := serveAuth(router, http.MethodPost, "/auth/login", `{"email": "ada@corp.example", "password": "correct-horse"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Empty(t, w.Result().Cookies(), "no session before the second factor")
+		var login struct {
+			MFARequired bool   `json:"mfa_required"`
+			MFAToken    string `json:"mfa_token"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &login))
+		require.True(t, login.MFARequired)
+
+		// The challenge is no access token
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+		req.Header.Set("Authorization", "Bearer "+login.MFAToken)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = serveAuth(router, http.MethodPost, "/auth/mfa", `{"mfa_token": "`+login.MFAToken+`", "code": "000000"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = serveAuth(router, http.MethodPost, "/auth/mfa", `{"mfa_token": "made-up", "code": "`+recoveryCodes[0]+`"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = serveAuth(router, http.MethodPost, "/auth/mfa", `{"mfa_token": "`+login.MFAToken+`", "code": "`+recoveryCodes[0]+`"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		responseCookie(t, w, "ecolink_refresh")
+		w = serveAuth(router, http.MethodGet, "/api/v1/me", "", responseCookie(t, w, "ecolink_token"))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("disables with a code", func(t *testing.T) {
+		require.Len(t, recoveryCodes, 10)
+		w := csrfRequest(t, router, access, http.MethodPost, "/api/v1/mfa/disable", `{"code": "`+recoveryCodes[0]+`"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "recovery codes work once")
+		w = csrfRequest(t, router, access, http.MethodPost, "/api/v1/mfa/disable", `{"code": "`+recoveryCodes[1]+`"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = serveAuth(router, http.MethodPost, "/auth/login", `{"email": "ada@corp.example", "password": "correct-horse"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		responseCookie(t, w, "ecolink_token")
+		w = csrfRequest(t, router, access, http.MethodPost, "/api/v1/links", `{"url": "https://example.com/more"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("holds API keys to their owner's policy", func(t *testing.T) {
+		grace := register("grace@corp.example")
+		w := csrfRequest(t, router, grace, http.MethodPost, "/api/v1/api-keys", `{"name": "ci", "scopes": ["links:write"]}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created struct {
+			Key string `json:"key"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(`{"url": "https://example.com/ci"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+created.Key)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		// Other domains are free to go without
+		other := register("linus@example.com")
+		w = csrfRequest(t, router, other, http.MethodPost, "/api/v1/links", `{"url": "https://example.com/mine"}`)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	})
+}
@@ -0,0 +1,104 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimits(t *testing.T) {
+	t.Setenv("RATE_LIMIT_RPS", "2")
+	t.Setenv("RATE_LIMIT_AUTH_PER_MINUTE", "4")
+	t.Setenv("RATE_LIMIT_WRITES_PER_MINUTE", "2")
+	router := newAuthRouter(t)
+
+	w := serveAuth(router, http.MethodPost, "/auth/register", `{"name": "Ada", "email": "ada@example.com", "password": "correct-horse"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = serveAuth(router, http.MethodPost, "/auth/login", `{"email": "ada@example.com", "password": "correct-horse"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	access := responseCookie(t, w, "ecolink_token")
+	assert.Equal(t, "4", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "4;w=60", w.Header().Get("RateLimit-Policy"))
+
+	t.Run("limits password guesses per client IP", func(t *testing.T) {
+		guess := func(remoteAddr string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email": "ada@example.com", "password": "guess"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.RemoteAddr = remoteAddr
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		for i := 0; i < 4; i++ {
+			assert.Equal(t, http.StatusUnauthorized, guess("198.51.100.1:1234").Code)
+		}
+		w := guess("198.51.100.1:1234")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		assert.InDelta(t, 15, retryAfter, 1)
+
+		assert.Equal(t, http.StatusUnauthorized, guess("198.51.100.2:1234").Code)
+	})
+
+	t.Run("ignores X-Forwarded-For from untrusted clients", func(t *testing.T) {
+		// Each guess on another email, so the login lockout stays out of it
+		guess := func(forwardedFor string) int {
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email": "`+forwardedFor+`@example.com", "password": "guess"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			req.RemoteAddr = "198.51.100.3:1234"
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		for i := 0; i < 4; i++ {
+			assert.Equal(t, http.StatusUnauthorized, guess("203.0.113."+strconv.Itoa(i)))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, guess("203.0.113.99"), "a spoofed address gets no fresh bucket")
+	})
+
+	t.Run("limits redirects per client IP", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, http.StatusNotFound, serveAuth(router, http.MethodGet, "/missing", "").Code)
+		}
+		w := serveAuth(router, http.MethodGet, "/missing", "")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("limits API writes per API key", func(t *testing.T) {
+		w := csrfRequest(t, router, access, http.MethodPost, "/api/v1/api-keys", `{"name": "ci", "scopes": ["links:write"]}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created struct {
+			Key string `json:"key"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+		createLink := func() int {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(`{"url": "https://example.com"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+created.Key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+		assert.Equal(t, http.StatusCreated, createLink())
+		assert.Equal(t, http.StatusCreated, createLink())
+		assert.Equal(t, http.StatusTooManyRequests, createLink())
+
+		// The key's budget is its own; the user's session still has one write
+		w = csrfRequest(t, router, access, http.MethodPost, "/api/v1/links", `{"url": "https://example.com"}`)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	})
+}
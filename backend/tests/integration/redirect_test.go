@@ -0,0 +1,189 @@
+package integration
+
+import (
+	"context"
+	"ecolink-core/internal/analytics/repository"
+	"ecolink-core/internal/handlers"
+	"ecolink-core/internal/models"
+	"ecolink-core/internal/security"
+	"ecolink-core/internal/services"
+	"ecolink-core/pkg/database"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirectLifecycle(t *testing.T) {
+	ctx := context.Background()
+	gin.SetMode(gin.TestMode)
+
+	db := database.NewMemoryDB()
+	linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{}, nil)
+	linkHandler, _ := newLinkHandler(t, linkService, db, repository.NewInMemoryClickRepository())
+
+	router := gin.New()
+	router.GET("/:code", linkHandler.RedirectLink)
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, db.SaveLink(ctx, &models.Link{Code: "plain1", URL: "https://example.com"}))
+	require.NoError(t, db.SaveLink(ctx, &models.Link{Code: "expiring", URL: "https://example.com", ExpiresAt: &future}))
+	require.NoError(t, db.SaveLink(ctx, &models.Link{Code: "expired", URL: "https://example.com", ExpiresAt: &past}))
+	require.NoError(t, db.SaveLink(ctx, &models.Link{
+		Code:        "fallback",
+		URL:         "https://example.com",
+		ExpiresAt:   &past,
+		FallbackURL: "https://example.com/ended",
+	}))
+
+	t.Run("plain link redirects temporarily since it is editable", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/plain1", nil))
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+	})
+
+	t.Run("expiring link redirects temporarily", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/expiring", nil))
+
+		assert.Equal(t, http.StatusFound, w.Code)
+	})
+
+	t.Run("expired link returns 410", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/expired", nil))
+
+		assert.Equal(t, http.StatusGone, w.Code)
+	})
+
+	t.Run("expired link with fallback redirects to fallback", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/fallback", nil))
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://example.com/ended", w.Header().Get("Location"))
+	})
+
+	t.Run("unknown link returns 404", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestPasswordProtectedRedirect(t *testing.T) {
+	ctx := context.Background()
+	gin.SetMode(gin.TestMode)
+
+	db := database.NewMemoryDB()
+	linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{}, nil)
+	linkHandler, _ := newLinkHandler(t, linkService, db, repository.NewInMemoryClickRepository())
+
+	router := gin.New()
+	router.GET("/:code", linkHandler.RedirectLink)
+	router.POST("/:code/unlock", linkHandler.UnlockLink)
+
+	_, err := linkService.CreateLink(ctx, models.CreateLinkRequest{
+		URL:      "https://docs.example.com/internal",
+		Alias:    "team-docs",
+		Password: "s3cret-pass",
+	}, "user-1")
+	require.NoError(t, err)
+
+	t.Run("locked link serves unlock step instead of redirecting", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/team-docs", nil)
+		req.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), `action="/team-docs/unlock"`)
+		assert.Empty(t, w.Header().Get("Location"))
+
+		link, err := db.GetLink(ctx, "team-docs")
+		require.NoError(t, err)
+		assert.Equal(t, 0, link.Clicks)
+	})
+
+	t.Run("wrong password is rejected", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/team-docs/unlock", strings.NewReader(`{"password":"nope"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, w.Result().Cookies())
+	})
+
+	var unlockCookie *http.Cookie
+	t.Run("correct password unlocks the link", func(t *testing.T) {
+		form := url.Values{"password": {"s3cret-pass"}}
+		req := httptest.NewRequest("POST", "/team-docs/unlock", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, "/team-docs", w.Header().Get("Location"))
+
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == handlers.UnlockCookieName {
+				unlockCookie = cookie
+			}
+		}
+		require.NotNil(t, unlockCookie)
+		assert.Equal(t, "/team-docs", unlockCookie.Path)
+		assert.True(t, unlockCookie.HttpOnly)
+
+		req = httptest.NewRequest("GET", "/team-docs", nil)
+		req.AddCookie(unlockCookie)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://docs.example.com/internal", w.Header().Get("Location"))
+	})
+
+	t.Run("unlock cookie does not open other links", func(t *testing.T) {
+		_, err := linkService.CreateLink(ctx, models.CreateLinkRequest{
+			URL:      "https://docs.example.com/other",
+			Alias:    "other-docs",
+			Password: "another-pass",
+		}, "user-1")
+		require.NoError(t, err)
+
+		teamDocs, err := db.GetLink(ctx, "team-docs")
+		require.NoError(t, err)
+		token, _ := security.NewLinkUnlocker("test-secret-key-32-characters-long", time.Minute).Sign("team-docs", teamDocs.PasswordHash)
+		req := httptest.NewRequest("GET", "/other-docs", nil)
+		req.AddCookie(&http.Cookie{Name: handlers.UnlockCookieName, Value: token})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("changing the password revokes unlocks", func(t *testing.T) {
+		require.NotNil(t, unlockCookie)
+		password := "n3w-secret-pass"
+		_, err := linkService.UpdateLink(ctx, "team-docs", "user-1", models.UpdateLinkRequest{Password: &password})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("GET", "/team-docs", nil)
+		req.AddCookie(unlockCookie)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
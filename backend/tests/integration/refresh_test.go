@@ -0,0 +1,112 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"ecolink-core/internal/bootstrap"
+	"ecolink-core/internal/config"
+	"ecolink-core/pkg/database"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshEndpoint(t *testing.T) {
+	router := newAuthRouter(t)
+	post := func(path, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		return serveAuth(router, http.MethodPost, path, body, cookies...)
+	}
+
+	w := post("/auth/register", `{"name": "Ada", "email": "ada@example.com", "password": "correct-horse"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = post("/auth/login", `{"email": "ada@example.com", "password": "correct-horse"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	access := responseCookie(t, w, "ecolink_token")
+	refresh := responseCookie(t, w, "ecolink_refresh")
+	assert.Equal(t, "/auth", refresh.Path)
+	assert.True(t, refresh.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, refresh.SameSite)
+	assert.NotContains(t, w.Body.String(), refresh.Value, "refresh tokens never reach scripts")
+
+	t.Run("requires the refresh cookie", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, post("/auth/refresh", "").Code)
+		assert.Equal(t, http.StatusUnauthorized, post("/auth/refresh", "", access).Code)
+	})
+
+	t.Run("rotates the cookies", func(t *testing.T) {
+		w := post("/auth/refresh", "", refresh)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		rotated := responseCookie(t, w, "ecolink_refresh")
+		assert.NotEqual(t, refresh.Value, rotated.Value)
+		assert.NotEmpty(t, responseCookie(t, w, "ecolink_token").Value)
+
+		// Replaying the old token logs every client of this login out
+		w = post("/auth/refresh", "", refresh)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Negative(t, responseCookie(t, w, "ecolink_refresh").MaxAge)
+		assert.Equal(t, http.StatusUnauthorized, post("/auth/refresh", "", rotated).Code)
+	})
+
+	t.Run("logout revokes the refresh token", func(t *testing.T) {
+		w := post("/auth/login", `{"email": "ada@example.com", "password": "correct-horse"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		refresh := responseCookie(t, w, "ecolink_refresh")
+
+		w = post("/auth/logout", "", refresh)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Negative(t, responseCookie(t, w, "ecolink_token").MaxAge)
+		assert.Negative(t, responseCookie(t, w, "ecolink_refresh").MaxAge)
+
+		assert.Equal(t, http.StatusUnauthorized, post("/auth/refresh", "", refresh).Code)
+	})
+}
+
+// newAuthRouter builds the application router on a memory database
+func newAuthRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret-key-32-characters-long")
+	t.Setenv("GOOGLE_CLIENT_ID", "test-client-id")
+	t.Setenv("GOOGLE_CLIENT_SECRET", "test-client-secret")
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	return newApplication(t, cfg, database.NewMemoryDB()).Router
+}
+
+// newApplication wires the application on db and shuts it down, flushing
+// buffered clicks, when the test ends
+func newApplication(t *testing.T, cfg *config.Config, db database.Database) *bootstrap.Application {
+	app := bootstrap.NewApplication(cfg, db)
+	t.Cleanup(func() {
+		assert.NoError(t, app.Shutdown(context.Background()))
+	})
+	return app
+}
+
+// serveAuth sends a JSON request with cookies through router
+func serveAuth(router *gin.Engine, method, path, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// responseCookie returns the cookie a response sets, failing if it is absent
+func responseCookie(t *testing.T, w *httptest.ResponseRecorder, name string) *http.Cookie {
+	t.Helper()
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	require.Failf(t, "missing cookie", "response sets no %s cookie", name)
+	return nil
+}
@@ -0,0 +1,79 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sessionList struct {
+	Sessions []struct {
+		ID        string `json:"id"`
+		UserAgent string `json:"user_agent"`
+		IPPrefix  string `json:"ip_prefix"`
+		Current   bool   `json:"current"`
+	} `json:"sessions"`
+}
+
+func TestSessions(t *testing.T) {
+	router := newAuthRouter(t)
+	w := serveAuth(router, http.MethodPost, "/auth/register", `{"name": "Ada", "email": "ada@example.com", "password": "correct-horse"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = serveAuth(router, http.MethodPost, "/auth/register", `{"name": "Grace", "email": "grace@example.com", "password": "correct-horse"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	login := func(email string) *http.Cookie {
+		w := serveAuth(router, http.MethodPost, "/auth/login", `{"email": "`+email+`", "password": "correct-horse"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return responseCookie(t, w, "ecolink_token")
+	}
+	list := func(access *http.Cookie) sessionList {
+		w := serveAuth(router, http.MethodGet, "/api/v1/sessions", "", access)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var sessions sessionList
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sessions))
+		return sessions
+	}
+
+	laptop := login("ada@example.com")
+	phone := login("ada@example.com")
+	grace := login("grace@example.com")
+
+	t.Run("lists the user's active sessions", func(t *testing.T) {
+		sessions := list(laptop)
+		require.Len(t, sessions.Sessions, 2)
+		var current int
+		for _, session := range sessions.Sessions {
+			if session.Current {
+				current++
+			}
+			assert.NotEmpty(t, session.ID)
+			assert.Equal(t, "192.0.2.0", session.IPPrefix, "only the network is kept")
+		}
+		assert.Equal(t, 1, current)
+		assert.NotContains(t, serveAuth(router, http.MethodGet, "/api/v1/sessions", "", laptop).Body.String(), "user_id")
+	})
+
+	t.Run("revokes a single session", func(t *testing.T) {
+		var phoneID string
+		for _, session := range list(phone).Sessions {
+			if session.Current {
+				phoneID = session.ID
+			}
+		}
+		require.NotEmpty(t, phoneID)
+
+		// Other users' sessions look missing
+		assert.Equal(t, http.StatusNotFound, csrfRequest(t, router, grace, http.MethodDelete, "/api/v1/sessions/"+phoneID, "").Code)
+		assert.Equal(t, http.StatusNotFound, csrfRequest(t, router, laptop, http.MethodDelete, "/api/v1/sessions/nope", "").Code)
+
+		w := csrfRequest(t, router, laptop, http.MethodDelete, "/api/v1/sessions/"+phoneID, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, http.StatusUnauthorized, serveAuth(router, http.MethodGet, "/api/v1/me", "", phone).Code)
+		assert.Equal(t, http.StatusOK, serveAuth(router, http.MethodGet, "/api/v1/me", "", laptop).Code)
+		assert.Len(t, list(laptop).Sessions, 1)
+	})
+}
@@ -0,0 +1,113 @@
+package integration
+
+import (
+	"context"
+	"ecolink-core/internal/analytics/repository"
+	"ecolink-core/internal/models"
+	"ecolink-core/internal/services"
+	"ecolink-core/pkg/database"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyDB fails every link read with err while it is set, like a database
+// that lost its connection
+type flakyDB struct {
+	*database.MemoryDB
+	err error
+}
+
+func (db *flakyDB) GetLink(ctx context.Context, code string) (*models.Link, error) {
+	if db.err != nil {
+		return nil, db.err
+	}
+	return db.MemoryDB.GetLink(ctx, code)
+}
+
+func (db *flakyDB) ListLinks(ctx context.Context, query database.LinkQuery) (*database.LinkPage, error) {
+	if db.err != nil {
+		return nil, db.err
+	}
+	return db.MemoryDB.ListLinks(ctx, query)
+}
+
+func TestStorageErrorStatuses(t *testing.T) {
+	ctx := context.Background()
+	gin.SetMode(gin.TestMode)
+
+	db := &flakyDB{MemoryDB: database.NewMemoryDB()}
+	linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{}, nil)
+	linkHandler, _ := newLinkHandler(t, linkService, db, repository.NewInMemoryClickRepository())
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		// Stand-in for RequireAuth
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	})
+	router.GET("/:code", linkHandler.RedirectLink)
+	router.GET("/api/v1/links", linkHandler.GetUserLinks)
+	router.DELETE("/api/v1/links/:code", linkHandler.DeleteLink)
+
+	require.NoError(t, db.SaveLink(ctx, &models.Link{Code: "promo1", URL: "https://example.com", UserID: "owner"}))
+
+	do := func(method, path, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("User-Agent", browserUserAgent)
+		req.Header.Set("X-Test-User", userID)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("deleting a missing link is not found", func(t *testing.T) {
+		w := do("DELETE", "/api/v1/links/nope", "owner")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("deleting another user's link is forbidden", func(t *testing.T) {
+		w := do("DELETE", "/api/v1/links/promo1", "intruder")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		_, err := db.GetLink(ctx, "promo1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("unavailable storage asks clients to retry", func(t *testing.T) {
+		db.err = fmt.Errorf("%w: connection refused", database.ErrUnavailable)
+		defer func() { db.err = nil }()
+
+		for _, path := range []string{"/promo1", "/api/v1/links"} {
+			w := do("GET", path, "owner")
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code, path)
+			assert.NotEmpty(t, w.Header().Get("Retry-After"), path)
+		}
+
+		w := do("DELETE", "/api/v1/links/promo1", "owner")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+
+	t.Run("other storage failures are internal errors", func(t *testing.T) {
+		db.err = errors.New("corrupt document")
+		defer func() { db.err = nil }()
+
+		w := do("GET", "/promo1", "owner")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("listing links is never not found", func(t *testing.T) {
+		db.err = fmt.Errorf("index %w", database.ErrNotFound)
+		defer func() { db.err = nil }()
+
+		w := do("GET", "/api/v1/links", "owner")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.NotContains(t, w.Body.String(), "not found")
+	})
+}
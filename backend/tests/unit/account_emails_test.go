@@ -0,0 +1,248 @@
+package unit
+
+import (
+	"context"
+	"crypto/sha256"
+	"ecolink-core/internal/auth/domain"
+	"ecolink-core/internal/auth/usecase"
+	"ecolink-core/pkg/database"
+	"ecolink-core/pkg/mailer"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// outbox is a mailer.Mailer keeping the emails it is handed
+type outbox struct {
+	mutex    sync.Mutex
+	messages []mailer.Message
+}
+
+func (o *outbox) Send(ctx context.Context, msg mailer.Message) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+func (o *outbox) sent() []mailer.Message {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return append([]mailer.Message(nil), o.messages...)
+}
+
+var linkToken = regexp.MustCompile(`\?token=(\S+)`)
+
+// lastToken waits for an email to reach to and returns the token of the
+// link it holds
+func (o *outbox) lastToken(t *testing.T, to string) string {
+	t.Helper()
+	var token string
+	require.Eventually(t, func() bool {
+		messages := o.sent()
+		for i := len(messages) - 1; i >= 0; i-- {
+			if match := linkToken.FindStringSubmatch(messages[i].Body); messages[i].To == to && match != nil {
+				token, _ = url.QueryUnescape(match[1])
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+	return token
+}
+
+// flakyResetDB fails the first password reset as a storage outage would
+type flakyResetDB struct {
+	*database.MemoryDB
+	failed bool
+}
+
+func (db *flakyResetDB) ResetPasswordWithToken(ctx context.Context, tokenHash, passwordHash string, usedAt time.Time) error {
+	if !db.failed {
+		db.failed = true
+		return database.ErrUnavailable
+	}
+	return db.MemoryDB.ResetPasswordWithToken(ctx, tokenHash, passwordHash, usedAt)
+}
+
+func TestAccountEmailService(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T) (*database.MemoryDB, *usecase.AuthService, *usecase.AccountEmailService, *outbox) {
+		db := database.NewMemoryDB()
+		tokenService := usecase.NewJWTTokenService("test-secret-key-32-bytes-long!", "test", usecase.DefaultAccessTokenTTL)
+		authService := usecase.NewAuthService(db, db, db, usecase.NewLoginGuard(db, db, usecase.DefaultLockoutPolicy), tokenService, usecase.GoogleConfig{}, usecase.DefaultRefreshTokenTTL)
+		mail := &outbox{}
+		return db, authService, usecase.NewAccountEmailService(authService, db, db, mail, "https://app.example.com/"), mail
+	}
+
+	t.Run("verifies an address once", func(t *testing.T) {
+		_, authService, emails, mail := setup(t)
+		user, err := authService.Register(ctx, "Ada", "ada@example.com", "correct-horse")
+		require.NoError(t, err)
+		assert.Nil(t, user.EmailVerifiedAt)
+
+		require.NoError(t, emails.SendVerification(ctx, user))
+		require.Len(t, mail.sent(), 1)
+		assert.Contains(t, mail.sent()[0].Body, "https://app.example.com/verify-email?token=")
+		token := mail.lastToken(t, "ada@example.com")
+
+		// A reset link cannot verify, nor a verification link reset
+		err = emails.ResetPassword(ctx, token, "new-password", testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidEmailToken)
+
+		verified, err := emails.VerifyEmail(ctx, token, testClient)
+		require.NoError(t, err)
+		require.NotNil(t, verified.EmailVerifiedAt)
+
+		_, err = emails.VerifyEmail(ctx, token, testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidEmailToken)
+
+		// Verified users get no more links
+		require.NoError(t, emails.SendVerification(ctx, verified))
+		assert.Len(t, mail.sent(), 1)
+	})
+
+	t.Run("refuses links to a changed address", func(t *testing.T) {
+		db, authService, emails, mail := setup(t)
+		user, err := authService.Register(ctx, "Ada", "ada@example.com", "correct-horse")
+		require.NoError(t, err)
+		require.NoError(t, emails.SendVerification(ctx, user))
+		token := mail.lastToken(t, "ada@example.com")
+
+		changed := *user
+		changed.Email = "ada@elsewhere.example"
+		require.NoError(t, db.UpdateUser(ctx, &changed))
+
+		_, err = emails.VerifyEmail(ctx, token, testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidEmailToken)
+	})
+
+	t.Run("resets a password and logs every session out", func(t *testing.T) {
+		db, authService, emails, mail := setup(t)
+		user, err := authService.Register(ctx, "Ada", "ada@example.com", "correct-horse")
+		require.NoError(t, err)
+		login, err := authService.Login(ctx, "ada@example.com", "correct-horse", testClient)
+		require.NoError(t, err)
+		apiKeys := usecase.NewAPIKeyService(db)
+		_, key, err := apiKeys.CreateKey(ctx, user.ID, "ci", []string{domain.ScopeLinksWrite}, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, emails.RequestPasswordReset(ctx, "ada@example.com", testClient))
+		first := mail.lastToken(t, "ada@example.com")
+		require.NoError(t, emails.RequestPasswordReset(ctx, "ada@example.com", testClient))
+		require.Eventually(t, func() bool { return len(mail.sent()) == 2 }, time.Second, 10*time.Millisecond)
+		second := mail.lastToken(t, "ada@example.com")
+		assert.NotEqual(t, first, second)
+
+		require.NoError(t, emails.ResetPassword(ctx, second, "battery-staple", testClient))
+
+		// The new password works, the old one and every reset link do not
+		_, err = authService.Login(ctx, "ada@example.com", "correct-horse", testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+		_, err = authService.Login(ctx, "ada@example.com", "battery-staple", testClient)
+		require.NoError(t, err)
+		for _, token := range []string{first, second} {
+			err = emails.ResetPassword(ctx, token, "another-password", testClient)
+			assert.ErrorIs(t, err, usecase.ErrInvalidEmailToken)
+		}
+
+		_, err = authService.Refresh(ctx, login.RefreshToken, testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidRefreshToken)
+		_, err = apiKeys.Authenticate(ctx, key)
+		assert.ErrorIs(t, err, usecase.ErrInvalidAPIKey)
+
+		// Following the link proved the address
+		found, err := db.FindByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, found.EmailVerifiedAt)
+
+		// The owner is told about the change
+		messages := mail.sent()
+		assert.Equal(t, "Your EcoLink password was changed", messages[len(messages)-1].Subject)
+
+		events, err := authService.ListAuthEvents(ctx, user.ID)
+		require.NoError(t, err)
+		var types []string
+		for _, event := range events {
+			types = append(types, event.Type)
+		}
+		assert.Contains(t, types, domain.AuthEventPasswordResetRequested)
+		assert.Contains(t, types, domain.AuthEventPasswordReset)
+	})
+
+	t.Run("a failed reset keeps the link working", func(t *testing.T) {
+		db, authService, _, mail := setup(t)
+		flaky := &flakyResetDB{MemoryDB: db}
+		emails := usecase.NewAccountEmailService(authService, flaky, db, mail, "https://app.example.com/")
+		_, err := authService.Register(ctx, "Ada", "ada@example.com", "correct-horse")
+		require.NoError(t, err)
+
+		require.NoError(t, emails.RequestPasswordReset(ctx, "ada@example.com", testClient))
+		token := mail.lastToken(t, "ada@example.com")
+
+		err = emails.ResetPassword(ctx, token, "battery-staple", testClient)
+		assert.ErrorIs(t, err, database.ErrUnavailable)
+		_, err = authService.Login(ctx, "ada@example.com", "correct-horse", testClient)
+		require.NoError(t, err, "the old password still works")
+
+		require.NoError(t, emails.ResetPassword(ctx, token, "battery-staple", testClient))
+		_, err = authService.Login(ctx, "ada@example.com", "battery-staple", testClient)
+		require.NoError(t, err)
+	})
+
+	t.Run("lifts a lockout on reset", func(t *testing.T) {
+		_, authService, emails, mail := setup(t)
+		_, err := authService.Register(ctx, "Ada", "ada@example.com", "correct-horse")
+		require.NoError(t, err)
+		for i := 0; i < usecase.DefaultLockoutPolicy.MaxFailures; i++ {
+			authService.Login(ctx, "ada@example.com", "wrong", domain.Client{})
+		}
+		_, err = authService.Login(ctx, "ada@example.com", "correct-horse", domain.Client{})
+		require.ErrorIs(t, err, usecase.ErrLoginLocked)
+
+		require.NoError(t, emails.RequestPasswordReset(ctx, "ada@example.com", domain.Client{}))
+		require.NoError(t, emails.ResetPassword(ctx, mail.lastToken(t, "ada@example.com"), "battery-staple", domain.Client{}))
+
+		_, err = authService.Login(ctx, "ada@example.com", "battery-staple", domain.Client{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("sends nothing for unknown and social-only emails", func(t *testing.T) {
+		db, _, emails, mail := setup(t)
+		_, err := db.CreateUserFromSocial(ctx, &domain.User{ID: "u-1", Email: "grace@example.com"},
+			&domain.SocialProfile{UserID: "u-1", Provider: "google", ProviderID: "g-42"})
+		require.NoError(t, err)
+
+		require.NoError(t, emails.RequestPasswordReset(ctx, "nobody@example.com", testClient))
+		require.NoError(t, emails.RequestPasswordReset(ctx, "grace@example.com", testClient))
+		assert.Empty(t, mail.sent())
+	})
+
+	t.Run("refuses expired links", func(t *testing.T) {
+		db, authService, emails, _ := setup(t)
+		user, err := authService.Register(ctx, "Ada", "ada@example.com", "correct-horse")
+		require.NoError(t, err)
+
+		// Tokens are stored as their SHA-256; plant an expired one
+		now := time.Now().UTC()
+		hash := sha256.Sum256([]byte("expired-token"))
+		require.NoError(t, db.CreateEmailToken(ctx, &domain.EmailToken{
+			TokenHash: hex.EncodeToString(hash[:]),
+			UserID:    user.ID,
+			Email:     user.Email,
+			Purpose:   domain.EmailTokenResetPassword,
+			CreatedAt: now.Add(-2 * time.Hour),
+			ExpiresAt: now.Add(-time.Hour),
+		}))
+		err = emails.ResetPassword(ctx, "expired-token", "battery-staple", testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidEmailToken)
+		_, err = authService.Login(ctx, "ada@example.com", "correct-horse", testClient)
+		assert.NoError(t, err, "the password is unchanged")
+	})
+}
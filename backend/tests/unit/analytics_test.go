@@ -0,0 +1,195 @@
+package unit
+
+import (
+	"context"
+	"ecolink-core/internal/analytics/domain"
+	"ecolink-core/internal/analytics/repository"
+	analytics "ecolink-core/internal/analytics/usecase"
+	"ecolink-core/pkg/geoip"
+	"ecolink-core/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymizeIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		ip       string
+		expected string
+	}{
+		{"IPv4", "203.0.113.42", "203.0.113.0"},
+		{"IPv4-mapped IPv6", "::ffff:203.0.113.42", "203.0.113.0"},
+		{"IPv6", "2001:db8:85a3:8d3:1319:8a2e:370:7348", "2001:db8:85a3::"},
+		{"Invalid", "not-an-ip", ""},
+		{"Empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, utils.AnonymizeIP(tt.ip))
+		})
+	}
+}
+
+func TestAnalyticsService_ClickHistory(t *testing.T) {
+	ctx := context.Background()
+	service := analytics.NewAnalyticsService(repository.NewInMemoryClickRepository(), nil)
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	t.Run("source tag precedence", func(t *testing.T) {
+		require.NoError(t, service.RecordClick(ctx, analytics.ClickInput{
+			Code:  "src1",
+			Query: map[string][]string{"utm_source": {"twitter"}, "src": {"qr-poster"}},
+		}))
+
+		events, err := service.GetClicks(ctx, "src1", from, to)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "qr-poster", events[0].Source)
+	})
+
+	t.Run("history follows alias changes", func(t *testing.T) {
+		require.NoError(t, service.RecordClick(ctx, analytics.ClickInput{Code: "old-code"}))
+		require.NoError(t, service.ReassignClicks(ctx, "old-code", "new-code"))
+
+		events, err := service.GetClicks(ctx, "new-code", from, to)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "new-code", events[0].Code)
+
+		events, err = service.GetClicks(ctx, "old-code", from, to)
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("history is dropped with the link", func(t *testing.T) {
+		require.NoError(t, service.DeleteClicks(ctx, "new-code"))
+
+		events, err := service.GetClicks(ctx, "new-code", from, to)
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+}
+
+func TestAnalyticsService_GetLinkStats(t *testing.T) {
+	ctx := context.Background()
+	clickRepo := repository.NewInMemoryClickRepository()
+	service := analytics.NewAnalyticsService(clickRepo, nil)
+
+	const (
+		chromeDesktop = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"
+		safariIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+	)
+
+	// Monday 2025-03-03 is the start of the first week
+	base := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	seed := []*domain.ClickEvent{
+		{ID: "1", Code: "promo1", Timestamp: base.Add(1 * time.Hour), UserAgent: chromeDesktop, Referrer: "https://www.google.com/search?q=eco"},
+		{ID: "2", Code: "promo1", Timestamp: base.Add(1*time.Hour + 30*time.Minute), UserAgent: safariIPhone, Referrer: "https://t.co/abc"},
+		{ID: "3", Code: "promo1", Timestamp: base.Add(26 * time.Hour), UserAgent: chromeDesktop},
+		{ID: "4", Code: "promo1", Timestamp: base.Add(8 * 24 * time.Hour), UserAgent: safariIPhone, Referrer: "https://google.com/"},
+		{ID: "5", Code: "other", Timestamp: base.Add(1 * time.Hour), UserAgent: chromeDesktop},
+	}
+	for _, event := range seed {
+		require.NoError(t, clickRepo.RecordClick(ctx, event))
+	}
+
+	t.Run("daily series with breakdowns", func(t *testing.T) {
+		stats, err := service.GetLinkStats(ctx, "promo1", analytics.StatsQuery{
+			From:     base,
+			To:       base.AddDate(0, 0, 3),
+			Interval: domain.IntervalDay,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, stats.TotalClicks)
+		require.Len(t, stats.Series, 3)
+		assert.Equal(t, base, stats.Series[0].Start)
+		assert.Equal(t, 2, stats.Series[0].Clicks)
+		assert.Equal(t, 1, stats.Series[1].Clicks)
+		assert.Equal(t, 0, stats.Series[2].Clicks)
+
+		assert.Equal(t, []domain.BreakdownEntry{{Key: "direct", Clicks: 1}, {Key: "google.com", Clicks: 1}, {Key: "t.co", Clicks: 1}}, stats.Referrers)
+		assert.Equal(t, []domain.BreakdownEntry{{Key: "Chrome", Clicks: 2}, {Key: "Safari", Clicks: 1}}, stats.Browsers)
+		assert.Equal(t, []domain.BreakdownEntry{{Key: "Windows", Clicks: 2}, {Key: "iOS", Clicks: 1}}, stats.OperatingSystems)
+		assert.Equal(t, []domain.BreakdownEntry{{Key: "desktop", Clicks: 2}, {Key: "mobile", Clicks: 1}}, stats.Devices)
+	})
+
+	t.Run("hourly and weekly buckets", func(t *testing.T) {
+		hourly, err := service.GetLinkStats(ctx, "promo1", analytics.StatsQuery{
+			From:     base,
+			To:       base.Add(3 * time.Hour),
+			Interval: domain.IntervalHour,
+		})
+		require.NoError(t, err)
+		require.Len(t, hourly.Series, 3)
+		assert.Equal(t, 2, hourly.Series[1].Clicks)
+
+		weekly, err := service.GetLinkStats(ctx, "promo1", analytics.StatsQuery{
+			From:     base.Add(36 * time.Hour),
+			To:       base.AddDate(0, 0, 14),
+			Interval: domain.IntervalWeek,
+		})
+		require.NoError(t, err)
+		require.Len(t, weekly.Series, 2)
+		assert.Equal(t, base, weekly.Series[0].Start, "weeks start on Monday")
+		assert.Equal(t, 0, weekly.Series[0].Clicks)
+		assert.Equal(t, 1, weekly.Series[1].Clicks)
+	})
+
+	t.Run("invalid queries", func(t *testing.T) {
+		_, err := service.GetLinkStats(ctx, "promo1", analytics.StatsQuery{From: base, To: base.Add(time.Hour), Interval: "month"})
+		assert.ErrorIs(t, err, analytics.ErrInvalidInterval)
+
+		_, err = service.GetLinkStats(ctx, "promo1", analytics.StatsQuery{From: base, To: base, Interval: domain.IntervalDay})
+		assert.ErrorIs(t, err, analytics.ErrInvalidRange)
+
+		_, err = service.GetLinkStats(ctx, "promo1", analytics.StatsQuery{From: base, To: base.AddDate(1, 0, 0), Interval: domain.IntervalHour})
+		assert.ErrorIs(t, err, analytics.ErrInvalidRange)
+	})
+
+	t.Run("ranges with too many clicks are refused", func(t *testing.T) {
+		query := analytics.StatsQuery{From: base, To: base.AddDate(0, 0, 14), Interval: domain.IntervalDay, MaxEvents: 4}
+		_, err := service.GetLinkStats(ctx, "promo1", query)
+		require.NoError(t, err)
+
+		query.MaxEvents = 3
+		_, err = service.GetLinkStats(ctx, "promo1", query)
+		assert.ErrorIs(t, err, analytics.ErrInvalidRange)
+	})
+}
+
+type stubLocator map[string]geoip.Location
+
+func (l stubLocator) Lookup(ip string) (geoip.Location, error) {
+	return l[ip], nil
+}
+
+func TestAnalyticsService_GeoIPEnrichment(t *testing.T) {
+	ctx := context.Background()
+	service := analytics.NewAnalyticsService(repository.NewInMemoryClickRepository(), stubLocator{
+		"81.2.69.160": {Country: "GB", Region: "ENG"},
+		"81.2.69.161": {Country: "GB", Region: "ENG"},
+		"2001:480::1": {Country: "US"},
+	})
+
+	for _, ip := range []string{"81.2.69.160", "81.2.69.161", "2001:480::1", "8.8.8.8"} {
+		require.NoError(t, service.RecordClick(ctx, analytics.ClickInput{Code: "geo1", ClientIP: ip}))
+	}
+
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	events, err := service.GetClicks(ctx, "geo1", from, to)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, "GB", events[0].Country)
+	assert.Equal(t, "ENG", events[0].Region)
+	assert.Equal(t, "81.2.69.0", events[0].IPPrefix, "location is resolved before anonymization")
+
+	stats, err := service.GetLinkStats(ctx, "geo1", analytics.StatsQuery{From: from, To: to, Interval: domain.IntervalHour})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.BreakdownEntry{{Key: "GB", Clicks: 2}, {Key: "US", Clicks: 1}, {Key: "Unknown", Clicks: 1}}, stats.Countries)
+	assert.Equal(t, []domain.BreakdownEntry{{Key: "GB-ENG", Clicks: 2}, {Key: "Unknown", Clicks: 2}}, stats.Regions)
+}
@@ -2,8 +2,12 @@ package unit
 
 import (
 	"context"
+	"crypto/sha256"
+	"ecolink-core/internal/auth/domain"
 	"ecolink-core/internal/auth/usecase"
 	"ecolink-core/pkg/database"
+	"encoding/hex"
+	"fmt"
 	"testing"
 	"time"
 
@@ -11,16 +15,18 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+var testClient = domain.Client{UserAgent: "test-agent", IP: "203.0.113.7"}
+
 func TestAuthService_Register(t *testing.T) {
 	// Setup
-	userRepo := database.NewMemoryUserRepository()
-	tokenService := usecase.NewJWTTokenService("test-secret-key-32-bytes-long!", "test")
+	userRepo := database.NewMemoryDB()
+	tokenService := usecase.NewJWTTokenService("test-secret-key-32-bytes-long!", "test", usecase.DefaultAccessTokenTTL)
 	googleConfig := usecase.GoogleConfig{
 		ClientID:     "test-client-id",
 		ClientSecret: "test-client-secret",
 		RedirectURI:  "http://localhost:3000/callback",
 	}
-	authService := usecase.NewAuthService(userRepo, tokenService, googleConfig)
+	authService := usecase.NewAuthService(userRepo, userRepo, userRepo, usecase.NewLoginGuard(userRepo, userRepo, usecase.DefaultLockoutPolicy), tokenService, googleConfig, usecase.DefaultRefreshTokenTTL)
 
 	t.Run("successful registration", func(t *testing.T) {
 		ctx := context.Background()
@@ -34,7 +40,7 @@ func TestAuthService_Register(t *testing.T) {
 		assert.Equal(t, name, user.Name)
 		assert.Equal(t, email, user.Email)
 		assert.NotEmpty(t, user.ID)
-		assert.True(t, user.CreatedAt.IsZero()) // CreatedAt not set in current implementation
+		assert.False(t, user.CreatedAt.IsZero())
 	})
 
 	t.Run("duplicate email registration", func(t *testing.T) {
@@ -54,10 +60,10 @@ func TestAuthService_Register(t *testing.T) {
 
 func TestAuthService_Login(t *testing.T) {
 	// Setup
-	userRepo := database.NewMemoryUserRepository()
-	tokenService := usecase.NewJWTTokenService("test-secret-key-32-bytes-long!", "test")
+	userRepo := database.NewMemoryDB()
+	tokenService := usecase.NewJWTTokenService("test-secret-key-32-bytes-long!", "test", usecase.DefaultAccessTokenTTL)
 	googleConfig := usecase.GoogleConfig{}
-	authService := usecase.NewAuthService(userRepo, tokenService, googleConfig)
+	authService := usecase.NewAuthService(userRepo, userRepo, userRepo, usecase.NewLoginGuard(userRepo, userRepo, usecase.DefaultLockoutPolicy), tokenService, googleConfig, usecase.DefaultRefreshTokenTTL)
 
 	t.Run("successful login", func(t *testing.T) {
 		ctx := context.Background()
@@ -69,7 +75,7 @@ func TestAuthService_Login(t *testing.T) {
 		require.NoError(t, err)
 
 		// Login
-		token, err := authService.Login(ctx, email, password)
+		token, err := authService.Login(ctx, email, password, testClient)
 
 		require.NoError(t, err)
 		assert.NotEmpty(t, token.Token)
@@ -80,7 +86,7 @@ func TestAuthService_Login(t *testing.T) {
 	t.Run("invalid credentials", func(t *testing.T) {
 		ctx := context.Background()
 
-		token, err := authService.Login(ctx, "nonexistent@example.com", "wrongpassword")
+		token, err := authService.Login(ctx, "nonexistent@example.com", "wrongpassword", testClient)
 
 		assert.Error(t, err)
 		assert.Nil(t, token)
@@ -88,15 +94,252 @@ func TestAuthService_Login(t *testing.T) {
 	})
 }
 
+func TestAuthService_Refresh(t *testing.T) {
+	db := database.NewMemoryDB()
+	tokenService := usecase.NewJWTTokenService("test-secret-key-32-bytes-long!", "test", usecase.DefaultAccessTokenTTL)
+	authService := usecase.NewAuthService(db, db, db, usecase.NewLoginGuard(db, db, usecase.DefaultLockoutPolicy), tokenService, usecase.GoogleConfig{}, usecase.DefaultRefreshTokenTTL)
+
+	ctx := context.Background()
+	_, err := authService.Register(ctx, "Refresh User", "refresh@example.com", "testpassword123")
+	require.NoError(t, err)
+
+	t.Run("rotates the refresh token", func(t *testing.T) {
+		login, err := authService.Login(ctx, "refresh@example.com", "testpassword123", testClient)
+		require.NoError(t, err)
+		require.NotEmpty(t, login.RefreshToken)
+		assert.True(t, login.RefreshExpiresAt.After(login.ExpiresAt))
+
+		refreshed, err := authService.Refresh(ctx, login.RefreshToken, testClient)
+		require.NoError(t, err)
+		assert.NotEmpty(t, refreshed.Token)
+		assert.Equal(t, login.UserID, refreshed.UserID)
+		assert.NotEqual(t, login.RefreshToken, refreshed.RefreshToken)
+
+		_, err = authService.Refresh(ctx, refreshed.RefreshToken, testClient)
+		assert.NoError(t, err)
+	})
+
+	t.Run("reuse revokes the token family", func(t *testing.T) {
+		login, err := authService.Login(ctx, "refresh@example.com", "testpassword123", testClient)
+		require.NoError(t, err)
+		refreshed, err := authService.Refresh(ctx, login.RefreshToken, testClient)
+		require.NoError(t, err)
+
+		_, err = authService.Refresh(ctx, login.RefreshToken, testClient)
+		assert.ErrorIs(t, err, usecase.ErrRefreshTokenReused)
+
+		// The legitimate client's token went with it
+		_, err = authService.Refresh(ctx, refreshed.RefreshToken, testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidRefreshToken)
+
+		// Other logins are untouched
+		other, err := authService.Login(ctx, "refresh@example.com", "testpassword123", testClient)
+		require.NoError(t, err)
+		_, err = authService.Refresh(ctx, other.RefreshToken, testClient)
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects unknown and expired tokens", func(t *testing.T) {
+		_, err := authService.Refresh(ctx, "not-a-token", testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidRefreshToken)
+
+		shortLived := usecase.NewAuthService(db, db, db, usecase.NewLoginGuard(db, db, usecase.DefaultLockoutPolicy), tokenService, usecase.GoogleConfig{}, time.Nanosecond)
+		login, err := shortLived.Login(ctx, "refresh@example.com", "testpassword123", testClient)
+		require.NoError(t, err)
+		_, err = shortLived.Refresh(ctx, login.RefreshToken, testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidRefreshToken)
+	})
+
+	t.Run("rejects a token family without a session", func(t *testing.T) {
+		user, _, err := db.FindByEmail(ctx, "refresh@example.com")
+		require.NoError(t, err)
+
+		sum := sha256.Sum256([]byte("orphan-token"))
+		require.NoError(t, db.CreateRefreshToken(ctx, &domain.RefreshToken{
+			TokenHash: hex.EncodeToString(sum[:]),
+			FamilyID:  "orphan-family",
+			UserID:    user.ID,
+			CreatedAt: time.Now().UTC(),
+			ExpiresAt: time.Now().UTC().Add(time.Hour),
+		}))
+
+		_, err = authService.Refresh(ctx, "orphan-token", testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidRefreshToken)
+	})
+
+	t.Run("revoking ends the login", func(t *testing.T) {
+		login, err := authService.Login(ctx, "refresh@example.com", "testpassword123", testClient)
+		require.NoError(t, err)
+
+		require.NoError(t, authService.RevokeRefreshToken(ctx, login.RefreshToken))
+		require.NoError(t, authService.RevokeRefreshToken(ctx, login.RefreshToken))
+
+		_, err = authService.Refresh(ctx, login.RefreshToken, testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidRefreshToken)
+		assert.NotErrorIs(t, err, usecase.ErrRefreshTokenReused)
+	})
+}
+
+func TestAuthService_Sessions(t *testing.T) {
+	db := database.NewMemoryDB()
+	tokenService := usecase.NewJWTTokenService("test-secret-key-32-bytes-long!", "test", usecase.DefaultAccessTokenTTL)
+	authService := usecase.NewAuthService(db, db, db, usecase.NewLoginGuard(db, db, usecase.DefaultLockoutPolicy), tokenService, usecase.GoogleConfig{}, usecase.DefaultRefreshTokenTTL)
+
+	ctx := context.Background()
+	user, err := authService.Register(ctx, "Session User", "sessions@example.com", "testpassword123")
+	require.NoError(t, err)
+	other, err := authService.Register(ctx, "Other User", "other@example.com", "testpassword123")
+	require.NoError(t, err)
+
+	t.Run("login starts a session kept by refreshes", func(t *testing.T) {
+		login, err := authService.Login(ctx, "sessions@example.com", "testpassword123", testClient)
+		require.NoError(t, err)
+		claims, err := tokenService.ValidateToken(login.Token)
+		require.NoError(t, err)
+
+		session, err := db.FindSession(ctx, claims.SessionID)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, session.UserID)
+		assert.Equal(t, "test-agent", session.UserAgent)
+		assert.Equal(t, "203.0.113.0", session.IPPrefix)
+
+		refreshed, err := authService.Refresh(ctx, login.RefreshToken, domain.Client{UserAgent: "new-agent", IP: "198.51.100.9"})
+		require.NoError(t, err)
+		refreshedClaims, err := tokenService.ValidateToken(refreshed.Token)
+		require.NoError(t, err)
+		assert.Equal(t, claims.SessionID, refreshedClaims.SessionID)
+
+		session, err = db.FindSession(ctx, claims.SessionID)
+		require.NoError(t, err)
+		assert.Equal(t, "new-agent", session.UserAgent)
+		assert.Equal(t, "198.51.100.0", session.IPPrefix)
+	})
+
+	t.Run("revoking a session ends its refresh tokens", func(t *testing.T) {
+		login, err := authService.Login(ctx, "sessions@example.com", "testpassword123", testClient)
+		require.NoError(t, err)
+		claims, err := tokenService.ValidateToken(login.Token)
+		require.NoError(t, err)
+
+		err = authService.RevokeSession(ctx, other.ID, claims.SessionID)
+		assert.ErrorIs(t, err, database.ErrSessionNotFound)
+
+		require.NoError(t, authService.RevokeSession(ctx, user.ID, claims.SessionID))
+		_, err = authService.Refresh(ctx, login.RefreshToken, testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidRefreshToken)
+
+		sessions, err := authService.ListSessions(ctx, user.ID)
+		require.NoError(t, err)
+		for _, session := range sessions {
+			assert.NotEqual(t, claims.SessionID, session.ID)
+		}
+	})
+}
+
+func TestAuthService_Lockout(t *testing.T) {
+	ctx := context.Background()
+	policy := usecase.LockoutPolicy{
+		MaxFailures:      3,
+		MaxFailuresPerIP: 5,
+		Lockout:          time.Minute,
+		MaxLockout:       3 * time.Minute,
+		Window:           time.Hour,
+	}
+	setup := func(t *testing.T) (*database.MemoryDB, *usecase.AuthService) {
+		db := database.NewMemoryDB()
+		tokenService := usecase.NewJWTTokenService("test-secret-key-32-bytes-long!", "test", usecase.DefaultAccessTokenTTL)
+		authService := usecase.NewAuthService(db, db, db, usecase.NewLoginGuard(db, db, policy), tokenService, usecase.GoogleConfig{}, usecase.DefaultRefreshTokenTTL)
+		_, err := authService.Register(ctx, "Ada", "ada@example.com", "correct-horse")
+		require.NoError(t, err)
+		return db, authService
+	}
+
+	t.Run("fails alike for unknown emails and wrong passwords", func(t *testing.T) {
+		_, authService := setup(t)
+		_, err := authService.Login(ctx, "ada@example.com", "wrong", testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+		_, err = authService.Login(ctx, "nobody@example.com", "wrong", testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+	})
+
+	t.Run("locks an account out with growing lockouts", func(t *testing.T) {
+		db, authService := setup(t)
+		client := domain.Client{IP: "203.0.113.1"}
+		for i := 0; i < 3; i++ {
+			_, err := authService.Login(ctx, "ada@example.com", "wrong", client)
+			assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+		}
+
+		// Even the right password is refused now
+		_, err := authService.Login(ctx, "Ada@Example.com", "correct-horse", domain.Client{IP: "198.51.100.1"})
+		var locked *usecase.LoginLockedError
+		require.ErrorAs(t, err, &locked)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), locked.Until, 5*time.Second)
+
+		// Each further failure doubles the lockout, up to the maximum
+		for _, lockout := range []time.Duration{2 * time.Minute, 3 * time.Minute} {
+			require.NoError(t, db.LockLogin(ctx, "email:ada@example.com", time.Time{}))
+			_, err = authService.Login(ctx, "ada@example.com", "wrong", client)
+			assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+			attempts, err := db.FindLoginAttempts(ctx, "email:ada@example.com")
+			require.NoError(t, err)
+			assert.WithinDuration(t, time.Now().Add(lockout), attempts.LockedUntil, 5*time.Second)
+		}
+	})
+
+	t.Run("locks unknown emails out too", func(t *testing.T) {
+		_, authService := setup(t)
+		for i := 0; i < 3; i++ {
+			_, err := authService.Login(ctx, "nobody@example.com", "wrong", domain.Client{})
+			assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+		}
+		_, err := authService.Login(ctx, "nobody@example.com", "wrong", domain.Client{})
+		assert.ErrorIs(t, err, usecase.ErrLoginLocked)
+	})
+
+	t.Run("locks a client address out across accounts", func(t *testing.T) {
+		_, authService := setup(t)
+		client := domain.Client{IP: "203.0.113.9"}
+		for i := 0; i < 5; i++ {
+			_, err := authService.Login(ctx, fmt.Sprintf("user%d@example.com", i), "wrong", client)
+			assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+		}
+		_, err := authService.Login(ctx, "ada@example.com", "correct-horse", client)
+		assert.ErrorIs(t, err, usecase.ErrLoginLocked)
+		_, err = authService.Login(ctx, "ada@example.com", "correct-horse", domain.Client{IP: "203.0.113.10"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("a successful login resets the account and is audited", func(t *testing.T) {
+		db, authService := setup(t)
+		for i := 0; i < 2; i++ {
+			_, err := authService.Login(ctx, "ada@example.com", "wrong", testClient)
+			assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+		}
+		token, err := authService.Login(ctx, "ada@example.com", "correct-horse", testClient)
+		require.NoError(t, err)
+		_, err = db.FindLoginAttempts(ctx, "email:ada@example.com")
+		assert.ErrorIs(t, err, database.ErrNotFound)
+
+		events, err := authService.ListAuthEvents(ctx, token.UserID)
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+		assert.Equal(t, domain.AuthEventLoginSucceeded, events[0].Type)
+		assert.Equal(t, domain.AuthEventLoginFailed, events[1].Type)
+		assert.Equal(t, "203.0.113.0", events[1].IPPrefix)
+		assert.Equal(t, "test-agent", events[1].UserAgent)
+	})
+}
+
 func TestTokenService_GenerateAndValidate(t *testing.T) {
-	tokenService := usecase.NewJWTTokenService("test-secret-key-32-bytes-long!", "test")
+	tokenService := usecase.NewJWTTokenService("test-secret-key-32-bytes-long!", "test", usecase.DefaultAccessTokenTTL)
 
 	t.Run("generate and validate token", func(t *testing.T) {
 		userID := "test-user-123"
 		email := "test@example.com"
 
 		// Generate token
-		authToken, err := tokenService.GenerateToken(userID, email)
+		authToken, err := tokenService.GenerateToken(userID, email, "session-1")
 		require.NoError(t, err)
 		assert.NotEmpty(t, authToken.Token)
 		assert.Equal(t, userID, authToken.UserID)
@@ -106,6 +349,8 @@ func TestTokenService_GenerateAndValidate(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, userID, claims.UserID)
 		assert.Equal(t, email, claims.Email)
+		assert.NotEmpty(t, claims.ID)
+		assert.Equal(t, "session-1", claims.SessionID)
 	})
 
 	t.Run("invalid token", func(t *testing.T) {
@@ -0,0 +1,65 @@
+package unit
+
+import (
+	"ecolink-core/pkg/utils"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsBotUserAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		expected  bool
+	}{
+		{"Chrome desktop", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36", false},
+		{"Safari iPhone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1", false},
+		{"Firefox", "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0", false},
+		{"Empty", "", true},
+		{"Googlebot", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", true},
+		{"Slack unfurler", "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", true},
+		{"Slack image proxy", "Slack-ImgProxy (+https://api.slack.com/robots)", true},
+		{"Twitter", "Twitterbot/1.0", true},
+		{"iMessage preview", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_11_1) AppleWebKit/601.2.4 (KHTML, like Gecko) Version/9.0.1 Safari/601.2.4 facebookexternalhit/1.1 Facebot Twitterbot/1.0", true},
+		{"WhatsApp", "WhatsApp/2.23.20.0", true},
+		{"Pingdom", "Pingdom.com_bot_version_1.4_(http://www.pingdom.com/)", true},
+		{"UptimeRobot", "Mozilla/5.0+(compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)", true},
+		{"curl", "curl/8.5.0", true},
+		{"Headless Chrome", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/126.0.0.0 Safari/537.36", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, utils.IsBotUserAgent(tt.userAgent))
+		})
+	}
+}
+
+func TestIsPrefetchRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		header   string
+		value    string
+		expected bool
+	}{
+		{"Plain GET", "GET", "", "", false},
+		{"HEAD probe", "HEAD", "", "", true},
+		{"Chrome speculation", "GET", "Sec-Purpose", "prefetch;prerender", true},
+		{"Legacy Purpose", "GET", "Purpose", "prefetch", true},
+		{"Firefox prefetch", "GET", "X-Moz", "prefetch", true},
+		{"Safari preview", "GET", "X-Purpose", "preview", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/abc123", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			assert.Equal(t, tt.expected, utils.IsPrefetchRequest(req))
+		})
+	}
+}
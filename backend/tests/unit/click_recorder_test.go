@@ -0,0 +1,159 @@
+package unit
+
+import (
+	"context"
+	"ecolink-core/internal/analytics/repository"
+	analytics "ecolink-core/internal/analytics/usecase"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countCall struct {
+	code         string
+	humans, bots int
+}
+
+// stubCounter records AddClicks calls, optionally failing or blocking them
+type stubCounter struct {
+	mu      sync.Mutex
+	calls   []countCall
+	err     error
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *stubCounter) AddClicks(ctx context.Context, code string, humans, bots int) error {
+	if c.started != nil {
+		c.started <- struct{}{}
+		<-c.release
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, countCall{code, humans, bots})
+	return c.err
+}
+
+func (c *stubCounter) totals() map[string]countCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	totals := make(map[string]countCall)
+	for _, call := range c.calls {
+		total := totals[call.code]
+		total.code = call.code
+		total.humans += call.humans
+		total.bots += call.bots
+		totals[call.code] = total
+	}
+	return totals
+}
+
+func TestClickRecorder(t *testing.T) {
+	ctx := context.Background()
+	window := func() (time.Time, time.Time) { return time.Now().Add(-time.Minute), time.Now().Add(time.Minute) }
+
+	t.Run("batches counters and events and flushes on close", func(t *testing.T) {
+		clickRepo := repository.NewInMemoryClickRepository()
+		counter := &stubCounter{}
+		recorder := analytics.NewClickRecorder(analytics.NewAnalyticsService(clickRepo, nil), counter, analytics.RecorderConfig{FlushInterval: time.Hour})
+
+		assert.True(t, recorder.Enqueue(analytics.ClickInput{Code: "abc"}))
+		assert.True(t, recorder.Enqueue(analytics.ClickInput{Code: "abc"}))
+		assert.True(t, recorder.Enqueue(analytics.ClickInput{Code: "abc", Bot: true}))
+		assert.True(t, recorder.Enqueue(analytics.ClickInput{Code: "xyz", Counted: true}))
+		require.NoError(t, recorder.Close(ctx))
+
+		assert.Len(t, counter.calls, 1, "clicks counted by the caller are not counted again")
+		assert.Equal(t, countCall{"abc", 2, 1}, counter.totals()["abc"])
+
+		from, to := window()
+		events, err := clickRepo.FindByCode(ctx, "abc", from, to)
+		require.NoError(t, err)
+		assert.Len(t, events, 3)
+		events, err = clickRepo.FindByCode(ctx, "xyz", from, to)
+		require.NoError(t, err)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("flushes full batches and on interval", func(t *testing.T) {
+		clickRepo := repository.NewInMemoryClickRepository()
+		counter := &stubCounter{}
+		recorder := analytics.NewClickRecorder(analytics.NewAnalyticsService(clickRepo, nil), counter, analytics.RecorderConfig{BatchSize: 2, FlushInterval: 10 * time.Millisecond})
+		defer recorder.Close(ctx)
+
+		for i := 0; i < 3; i++ {
+			recorder.Enqueue(analytics.ClickInput{Code: "abc"})
+		}
+
+		assert.Eventually(t, func() bool {
+			return counter.totals()["abc"].humans == 3
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("flush writes buffered clicks without closing", func(t *testing.T) {
+		clickRepo := repository.NewInMemoryClickRepository()
+		counter := &stubCounter{}
+		recorder := analytics.NewClickRecorder(analytics.NewAnalyticsService(clickRepo, nil), counter, analytics.RecorderConfig{FlushInterval: time.Hour})
+		defer recorder.Close(ctx)
+
+		recorder.Enqueue(analytics.ClickInput{Code: "abc"})
+		recorder.Enqueue(analytics.ClickInput{Code: "abc"})
+		require.NoError(t, recorder.Flush(ctx))
+		assert.Equal(t, countCall{"abc", 2, 0}, counter.totals()["abc"])
+
+		from, to := window()
+		events, err := clickRepo.FindByCode(ctx, "abc", from, to)
+		require.NoError(t, err)
+		assert.Len(t, events, 2)
+
+		assert.True(t, recorder.Enqueue(analytics.ClickInput{Code: "abc"}), "the recorder keeps running")
+		require.NoError(t, recorder.Close(ctx))
+		require.NoError(t, recorder.Flush(ctx), "flushing a closed recorder is a no-op")
+	})
+
+	t.Run("counter failures do not lose events", func(t *testing.T) {
+		clickRepo := repository.NewInMemoryClickRepository()
+		counter := &stubCounter{err: errors.New("unavailable")}
+		recorder := analytics.NewClickRecorder(analytics.NewAnalyticsService(clickRepo, nil), counter, analytics.RecorderConfig{})
+
+		recorder.Enqueue(analytics.ClickInput{Code: "abc"})
+		require.NoError(t, recorder.Close(ctx))
+
+		from, to := window()
+		events, err := clickRepo.FindByCode(ctx, "abc", from, to)
+		require.NoError(t, err)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("drops clicks instead of blocking when full", func(t *testing.T) {
+		counter := &stubCounter{started: make(chan struct{}), release: make(chan struct{})}
+		recorder := analytics.NewClickRecorder(analytics.NewAnalyticsService(repository.NewInMemoryClickRepository(), nil), counter, analytics.RecorderConfig{BufferSize: 1, BatchSize: 1})
+
+		require.True(t, recorder.Enqueue(analytics.ClickInput{Code: "abc"}))
+		<-counter.started // Worker is now stuck flushing the first click
+
+		assert.True(t, recorder.Enqueue(analytics.ClickInput{Code: "abc"}))
+		assert.False(t, recorder.Enqueue(analytics.ClickInput{Code: "abc"}))
+		assert.Equal(t, int64(1), recorder.Dropped())
+
+		go func() {
+			for range counter.started {
+			}
+		}()
+		close(counter.release)
+		require.NoError(t, recorder.Close(ctx))
+		close(counter.started)
+	})
+
+	t.Run("rejects clicks after close", func(t *testing.T) {
+		recorder := analytics.NewClickRecorder(analytics.NewAnalyticsService(repository.NewInMemoryClickRepository(), nil), &stubCounter{}, analytics.RecorderConfig{})
+		require.NoError(t, recorder.Close(ctx))
+		assert.False(t, recorder.Enqueue(analytics.ClickInput{Code: "abc"}))
+	})
+}
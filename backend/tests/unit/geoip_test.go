@@ -0,0 +1,80 @@
+package unit
+
+import (
+	"ecolink-core/pkg/geoip"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestMMDB builds a tiny City-style database in a temp directory
+func writeTestMMDB(t *testing.T) string {
+	t.Helper()
+
+	tree, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: "GeoIP2-City", RecordSize: 24})
+	require.NoError(t, err)
+
+	_, network, err := net.ParseCIDR("81.2.69.0/24")
+	require.NoError(t, err)
+	require.NoError(t, tree.Insert(network, mmdbtype.Map{
+		"country": mmdbtype.Map{"iso_code": mmdbtype.String("GB")},
+		"subdivisions": mmdbtype.Slice{
+			mmdbtype.Map{"iso_code": mmdbtype.String("ENG")},
+		},
+	}))
+
+	_, network, err = net.ParseCIDR("2001:480::/32")
+	require.NoError(t, err)
+	require.NoError(t, tree.Insert(network, mmdbtype.Map{
+		"country": mmdbtype.Map{"iso_code": mmdbtype.String("US")},
+	}))
+
+	path := filepath.Join(t.TempDir(), "test-city.mmdb")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	_, err = tree.WriteTo(file)
+	require.NoError(t, err)
+	return path
+}
+
+func TestMMDBLocator(t *testing.T) {
+	locator, err := geoip.OpenMMDB(writeTestMMDB(t))
+	require.NoError(t, err)
+	defer locator.Close()
+
+	tests := []struct {
+		name     string
+		ip       string
+		expected geoip.Location
+	}{
+		{"IPv4 with region", "81.2.69.160", geoip.Location{Country: "GB", Region: "ENG"}},
+		{"IPv6 country only", "2001:480::1", geoip.Location{Country: "US"}},
+		{"Not in database", "8.8.8.8", geoip.Location{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, err := locator.Lookup(tt.ip)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, location)
+		})
+	}
+
+	t.Run("invalid IP", func(t *testing.T) {
+		_, err := locator.Lookup("not-an-ip")
+		assert.Error(t, err)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := geoip.OpenMMDB(filepath.Join(t.TempDir(), "missing.mmdb"))
+		assert.Error(t, err)
+	})
+}
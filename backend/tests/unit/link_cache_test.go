@@ -0,0 +1,141 @@
+package unit
+
+import (
+	"context"
+	"ecolink-core/internal/models"
+	"ecolink-core/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingDB counts the link lookups reaching the wrapped database
+type countingDB struct {
+	database.Database
+	reads map[string]int
+}
+
+func (db *countingDB) GetLink(ctx context.Context, code string) (*models.Link, error) {
+	db.reads[code]++
+	return db.Database.GetLink(ctx, code)
+}
+
+func newCachedDB(config database.CacheConfig) (*database.CachedDB, *countingDB) {
+	backend := &countingDB{Database: database.NewMemoryDB(), reads: make(map[string]int)}
+	return database.NewCachedDB(backend, config), backend
+}
+
+func TestCachedDB(t *testing.T) {
+	ctx := context.Background()
+	config := database.CacheConfig{Size: 2, TTL: time.Minute, NegativeTTL: time.Minute}
+
+	t.Run("serves repeated lookups from cache", func(t *testing.T) {
+		cache, backend := newCachedDB(config)
+		require.NoError(t, cache.SaveLink(ctx, &models.Link{Code: "viral", URL: "https://example.com"}))
+
+		for i := 0; i < 5; i++ {
+			link, err := cache.GetLink(ctx, "viral")
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com", link.URL)
+		}
+		assert.Equal(t, 1, backend.reads["viral"])
+	})
+
+	t.Run("caches unknown codes until created", func(t *testing.T) {
+		cache, backend := newCachedDB(config)
+
+		for i := 0; i < 3; i++ {
+			_, err := cache.GetLink(ctx, "ghost")
+			assert.ErrorIs(t, err, database.ErrLinkNotFound)
+		}
+		assert.Equal(t, 1, backend.reads["ghost"])
+
+		require.NoError(t, cache.SaveLink(ctx, &models.Link{Code: "ghost", URL: "https://example.com"}))
+		link, err := cache.GetLink(ctx, "ghost")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com", link.URL)
+	})
+
+	t.Run("invalidates on update and delete", func(t *testing.T) {
+		cache, _ := newCachedDB(config)
+		require.NoError(t, cache.SaveLink(ctx, &models.Link{Code: "promo", URL: "https://old.example.com"}))
+		_, err := cache.GetLink(ctx, "promo")
+		require.NoError(t, err)
+
+		require.NoError(t, cache.UpdateLink(ctx, "promo", &models.Link{Code: "promo", URL: "https://new.example.com"}))
+		link, err := cache.GetLink(ctx, "promo")
+		require.NoError(t, err)
+		assert.Equal(t, "https://new.example.com", link.URL)
+
+		require.NoError(t, cache.UpdateLink(ctx, "promo", &models.Link{Code: "promo2", URL: "https://new.example.com"}))
+		_, err = cache.GetLink(ctx, "promo")
+		assert.ErrorIs(t, err, database.ErrLinkNotFound)
+
+		require.NoError(t, cache.DeleteLink(ctx, "promo2"))
+		_, err = cache.GetLink(ctx, "promo2")
+		assert.ErrorIs(t, err, database.ErrLinkNotFound)
+	})
+
+	t.Run("evicts least recently used codes", func(t *testing.T) {
+		cache, backend := newCachedDB(config)
+		for _, code := range []string{"aaa", "bbb", "ccc"} {
+			require.NoError(t, cache.SaveLink(ctx, &models.Link{Code: code, URL: "https://example.com/" + code}))
+		}
+
+		cache.GetLink(ctx, "aaa")
+		cache.GetLink(ctx, "bbb")
+		cache.GetLink(ctx, "aaa") // bbb is now least recently used
+		cache.GetLink(ctx, "ccc") // evicts bbb
+		cache.GetLink(ctx, "aaa")
+		cache.GetLink(ctx, "bbb")
+
+		assert.Equal(t, 1, backend.reads["aaa"])
+		assert.Equal(t, 2, backend.reads["bbb"])
+	})
+
+	t.Run("expires entries after TTL", func(t *testing.T) {
+		cache, backend := newCachedDB(database.CacheConfig{Size: 10, TTL: 10 * time.Millisecond, NegativeTTL: 10 * time.Millisecond})
+		require.NoError(t, cache.SaveLink(ctx, &models.Link{Code: "short", URL: "https://example.com"}))
+
+		cache.GetLink(ctx, "short")
+		time.Sleep(20 * time.Millisecond)
+		cache.GetLink(ctx, "short")
+
+		assert.Equal(t, 2, backend.reads["short"])
+	})
+
+	t.Run("keeps counters fresh and never caches budgeted links", func(t *testing.T) {
+		cache, backend := newCachedDB(config)
+		require.NoError(t, cache.SaveLink(ctx, &models.Link{Code: "plain", URL: "https://example.com"}))
+		require.NoError(t, cache.SaveLink(ctx, &models.Link{Code: "budget", URL: "https://example.com", MaxClicks: 5}))
+
+		cache.GetLink(ctx, "plain")
+		require.NoError(t, cache.AddClicks(ctx, "plain", 2, 1))
+		link, err := cache.GetLink(ctx, "plain")
+		require.NoError(t, err)
+		assert.Equal(t, 2, link.Clicks)
+		assert.Equal(t, 1, link.BotClicks)
+
+		cache.GetLink(ctx, "budget")
+		cache.GetLink(ctx, "budget")
+		assert.Equal(t, 2, backend.reads["budget"])
+	})
+
+	t.Run("returns copies of cached links", func(t *testing.T) {
+		cache, _ := newCachedDB(config)
+		require.NoError(t, cache.SaveLink(ctx, &models.Link{Code: "copy", URL: "https://example.com", Tags: []string{"a"}}))
+		cache.GetLink(ctx, "copy")
+
+		link, err := cache.GetLink(ctx, "copy")
+		require.NoError(t, err)
+		link.URL = "https://evil.example.com"
+		link.Tags[0] = "b"
+
+		link, err = cache.GetLink(ctx, "copy")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com", link.URL)
+		assert.Equal(t, []string{"a"}, link.Tags)
+	})
+}
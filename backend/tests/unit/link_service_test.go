@@ -0,0 +1,297 @@
+package unit
+
+import (
+	"context"
+	"ecolink-core/internal/analytics/repository"
+	analytics "ecolink-core/internal/analytics/usecase"
+	"ecolink-core/internal/models"
+	"ecolink-core/internal/services"
+	"ecolink-core/pkg/database"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readBarrierDB holds link lookups until the expected number of them were
+// made, so concurrent redirects all see the link before any click counts
+type readBarrierDB struct {
+	database.Database
+	reads sync.WaitGroup
+}
+
+func (db *readBarrierDB) GetLink(ctx context.Context, code string) (*models.Link, error) {
+	link, err := db.Database.GetLink(ctx, code)
+	db.reads.Done()
+	db.reads.Wait()
+	return link, err
+}
+
+// failingHistory cannot move click history
+type failingHistory struct{}
+
+func (failingHistory) Flush(ctx context.Context) error { return nil }
+
+func (failingHistory) ReassignClicks(ctx context.Context, oldCode, newCode string) error {
+	return errors.New("unavailable")
+}
+
+func TestLinkService_CreateLinkWithAlias(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDB()
+	linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{}, nil)
+
+	t.Run("custom alias is used as short code", func(t *testing.T) {
+		resp, err := linkService.CreateLink(ctx, models.CreateLinkRequest{
+			URL:   "https://example.com/spring",
+			Alias: "spring-sale",
+		}, "user-1")
+
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080/spring-sale", resp.ShortURL)
+
+		link, err := db.GetLink(ctx, "spring-sale")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/spring", link.URL)
+		assert.Equal(t, "user-1", link.UserID)
+	})
+
+	t.Run("taken alias is rejected", func(t *testing.T) {
+		_, err := linkService.CreateLink(ctx, models.CreateLinkRequest{
+			URL:   "https://example.com/other",
+			Alias: "spring-sale",
+		}, "user-2")
+
+		assert.ErrorIs(t, err, services.ErrAliasUnavailable)
+
+		link, err := db.GetLink(ctx, "spring-sale")
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", link.UserID)
+	})
+
+	t.Run("reserved alias is rejected", func(t *testing.T) {
+		_, err := linkService.CreateLink(ctx, models.CreateLinkRequest{
+			URL:   "https://example.com",
+			Alias: "health",
+		}, "user-1")
+
+		assert.ErrorIs(t, err, services.ErrInvalidAlias)
+	})
+
+	t.Run("malformed alias is rejected", func(t *testing.T) {
+		_, err := linkService.CreateLink(ctx, models.CreateLinkRequest{
+			URL:   "https://example.com",
+			Alias: "spring/sale",
+		}, "user-1")
+
+		assert.ErrorIs(t, err, services.ErrInvalidAlias)
+	})
+}
+
+func TestLinkService_CollisionSafeAllocation(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDB()
+	linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{
+		Length:      3,
+		Alphabet:    "ab",
+		MaxAttempts: 50,
+	}, nil)
+
+	t.Run("codes stay unique and grow when the space is crowded", func(t *testing.T) {
+		// Only 8 codes of length 3 exist for a 2-character alphabet
+		codes := make(map[string]bool)
+		for i := 0; i < 20; i++ {
+			resp, err := linkService.CreateLink(ctx, models.CreateLinkRequest{
+				URL: fmt.Sprintf("https://example.com/%d", i),
+			}, "user-1")
+			require.NoError(t, err)
+
+			assert.False(t, codes[resp.ShortURL], "duplicate short URL %s", resp.ShortURL)
+			codes[resp.ShortURL] = true
+		}
+
+		page, err := db.ListLinks(ctx, database.LinkQuery{UserID: "user-1"})
+		require.NoError(t, err)
+		assert.Len(t, page.Links, 20)
+	})
+
+	t.Run("reserved codes are never generated", func(t *testing.T) {
+		// Every other 3-character code is taken, leaving only the reserved "api"
+		for i := 0; i < 50; i++ {
+			crowded := database.NewMemoryDB()
+			for _, a := range "api" {
+				for _, b := range "api" {
+					for _, c := range "api" {
+						code := string([]rune{a, b, c})
+						if code == "api" {
+							continue
+						}
+						require.NoError(t, crowded.SaveLink(ctx, &models.Link{Code: code, URL: "https://example.com", UserID: "user-1"}))
+					}
+				}
+			}
+
+			service := services.NewLinkService(crowded, "http://localhost:8080", services.CodeConfig{
+				Length:      3,
+				Alphabet:    "api",
+				MaxAttempts: 50,
+			}, nil)
+			resp, err := service.CreateLink(ctx, models.CreateLinkRequest{URL: "https://example.com/new"}, "user-1")
+			require.NoError(t, err)
+			assert.NotEqual(t, "http://localhost:8080/api", resp.ShortURL)
+		}
+	})
+
+	t.Run("existing link is never overwritten", func(t *testing.T) {
+		original := &models.Link{Code: "taken", URL: "https://original.example.com", UserID: "user-1"}
+		require.NoError(t, db.SaveLink(ctx, original))
+
+		err := db.SaveLink(ctx, &models.Link{Code: "taken", URL: "https://hijack.example.com", UserID: "user-2"})
+		assert.ErrorIs(t, err, database.ErrCodeTaken)
+
+		link, err := db.GetLink(ctx, "taken")
+		require.NoError(t, err)
+		assert.Equal(t, "https://original.example.com", link.URL)
+	})
+}
+
+func TestLinkService_RenameKeepsClicks(t *testing.T) {
+	ctx := context.Background()
+	alias := func(code string) models.UpdateLinkRequest { return models.UpdateLinkRequest{Alias: &code} }
+
+	t.Run("buffered and recorded clicks follow the new code", func(t *testing.T) {
+		db := database.NewMemoryDB()
+		clickRepo := repository.NewInMemoryClickRepository()
+		analyticsService := analytics.NewAnalyticsService(clickRepo, nil)
+		recorder := analytics.NewClickRecorder(analyticsService, db, analytics.RecorderConfig{FlushInterval: time.Hour})
+		defer recorder.Close(ctx)
+		linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{}, recorder)
+
+		require.NoError(t, db.SaveLink(ctx, &models.Link{Code: "promo", URL: "https://example.com", UserID: "user-1"}))
+		require.NoError(t, analyticsService.RecordClick(ctx, analytics.ClickInput{Code: "promo", Counted: true}))
+		require.True(t, recorder.Enqueue(analytics.ClickInput{Code: "promo"}))
+
+		_, err := linkService.UpdateLink(ctx, "promo", "user-1", alias("promo-2"))
+		require.NoError(t, err)
+
+		link, err := db.GetLink(ctx, "promo-2")
+		require.NoError(t, err)
+		assert.Equal(t, 1, link.Clicks, "the buffered click is counted before the rename")
+
+		from, to := time.Now().Add(-time.Minute), time.Now().Add(time.Minute)
+		events, err := clickRepo.FindByCode(ctx, "promo-2", from, to)
+		require.NoError(t, err)
+		assert.Len(t, events, 2)
+		events, err = clickRepo.FindByCode(ctx, "promo", from, to)
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("a failed move is reported", func(t *testing.T) {
+		db := database.NewMemoryDB()
+		linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{}, failingHistory{})
+
+		require.NoError(t, db.SaveLink(ctx, &models.Link{Code: "promo", URL: "https://example.com", UserID: "user-1"}))
+		_, err := linkService.UpdateLink(ctx, "promo", "user-1", alias("promo-2"))
+		assert.Error(t, err)
+	})
+}
+
+func TestLinkService_LinkExpiration(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDB()
+	linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{}, nil)
+
+	t.Run("past expiration date is rejected on create", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		_, err := linkService.CreateLink(ctx, models.CreateLinkRequest{
+			URL:       "https://example.com",
+			ExpiresAt: &past,
+		}, "user-1")
+
+		assert.ErrorIs(t, err, services.ErrInvalidExpiry)
+	})
+
+	t.Run("expired link is gone with fallback", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute)
+		require.NoError(t, db.SaveLink(ctx, &models.Link{
+			Code:        "expired",
+			URL:         "https://example.com/promo",
+			ExpiresAt:   &past,
+			FallbackURL: "https://example.com/promo-ended",
+		}))
+
+		_, err := linkService.ResolveLink(ctx, "expired", services.ResolveOptions{})
+		require.ErrorIs(t, err, services.ErrLinkGone)
+
+		var gone *services.GoneError
+		require.ErrorAs(t, err, &gone)
+		assert.Equal(t, "https://example.com/promo-ended", gone.FallbackURL)
+	})
+
+	t.Run("click budget is enforced", func(t *testing.T) {
+		resp, err := linkService.CreateLink(ctx, models.CreateLinkRequest{
+			URL:       "https://example.com/limited",
+			Alias:     "limited",
+			MaxClicks: 2,
+		}, "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080/limited", resp.ShortURL)
+
+		for i := 0; i < 2; i++ {
+			link, err := linkService.ResolveLink(ctx, "limited", services.ResolveOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/limited", link.URL)
+		}
+
+		_, err = linkService.ResolveLink(ctx, "limited", services.ResolveOptions{})
+		assert.ErrorIs(t, err, services.ErrLinkGone)
+	})
+
+	t.Run("concurrent clicks stay within the budget", func(t *testing.T) {
+		const clicks = 20
+		db := &readBarrierDB{Database: database.NewMemoryDB()}
+		require.NoError(t, db.SaveLink(ctx, &models.Link{Code: "drop", URL: "https://example.com/drop", MaxClicks: 5}))
+		linkService := services.NewLinkService(db, "http://localhost:8080", services.CodeConfig{}, nil)
+
+		var wg sync.WaitGroup
+		var served atomic.Int32
+		db.reads.Add(clicks)
+		for i := 0; i < clicks; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := linkService.ResolveLink(ctx, "drop", services.ResolveOptions{})
+				if err == nil {
+					served.Add(1)
+				} else {
+					assert.ErrorIs(t, err, services.ErrLinkGone)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(5), served.Load())
+		link, err := db.Database.GetLink(ctx, "drop")
+		require.NoError(t, err)
+		assert.Equal(t, 5, link.Clicks)
+	})
+
+	t.Run("links with a lifecycle are not deduplicated", func(t *testing.T) {
+		future := time.Now().Add(24 * time.Hour)
+		first, err := linkService.CreateLink(ctx, models.CreateLinkRequest{URL: "https://example.com/campaign"}, "user-1")
+		require.NoError(t, err)
+
+		second, err := linkService.CreateLink(ctx, models.CreateLinkRequest{
+			URL:       "https://example.com/campaign",
+			ExpiresAt: &future,
+		}, "user-1")
+		require.NoError(t, err)
+		assert.NotEqual(t, first.ShortURL, second.ShortURL)
+	})
+}
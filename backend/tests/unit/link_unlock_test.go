@@ -0,0 +1,48 @@
+package unit
+
+import (
+	"ecolink-core/internal/security"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinkUnlocker(t *testing.T) {
+	unlocker := security.NewLinkUnlocker("test-secret-key-32-bytes-long!", time.Minute)
+	const hash = "$2a$10$7EqJtq98hPqEX7fNZaFWoOhi5BWX4Z1WZ5u1Q3aHq6d0QJb2tq8xK"
+
+	t.Run("valid token for the same code", func(t *testing.T) {
+		token, expiresAt := unlocker.Sign("team-docs", hash)
+		assert.True(t, expiresAt.After(time.Now()))
+		assert.True(t, unlocker.Verify("team-docs", hash, token))
+	})
+
+	t.Run("token is bound to its code", func(t *testing.T) {
+		token, _ := unlocker.Sign("team-docs", hash)
+		assert.False(t, unlocker.Verify("other-docs", hash, token))
+	})
+
+	t.Run("token is bound to the password", func(t *testing.T) {
+		token, _ := unlocker.Sign("team-docs", hash)
+		assert.False(t, unlocker.Verify("team-docs", "$2a$10$another-password-hash", token))
+		assert.False(t, unlocker.Verify("team-docs", "", token))
+	})
+
+	t.Run("token from another secret is rejected", func(t *testing.T) {
+		other := security.NewLinkUnlocker("another-secret-key-32-bytes-long", time.Minute)
+		token, _ := other.Sign("team-docs", hash)
+		assert.False(t, unlocker.Verify("team-docs", hash, token))
+	})
+
+	t.Run("expired token is rejected", func(t *testing.T) {
+		expired := security.NewLinkUnlocker("test-secret-key-32-bytes-long!", -time.Second)
+		token, _ := expired.Sign("team-docs", hash)
+		assert.False(t, expired.Verify("team-docs", hash, token))
+	})
+
+	t.Run("malformed token is rejected", func(t *testing.T) {
+		assert.False(t, unlocker.Verify("team-docs", hash, "not-a-token"))
+		assert.False(t, unlocker.Verify("team-docs", hash, ""))
+	})
+}
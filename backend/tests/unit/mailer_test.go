@@ -0,0 +1,49 @@
+package unit
+
+import (
+	"context"
+	"ecolink-core/pkg/mailer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "mail.mbox")
+	m := mailer.NewFileMailer(path, "EcoLink <no-reply@example.com>")
+
+	t.Run("appends messages", func(t *testing.T) {
+		require.NoError(t, m.Send(ctx, mailer.Message{To: "ada@example.com", Subject: "Hello", Body: "First line\nSecond line"}))
+		require.NoError(t, m.Send(ctx, mailer.Message{To: "grace@example.com", Subject: "Héllo", Body: "Other"}))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		mbox := string(content)
+		assert.Equal(t, 2, strings.Count(mbox, "From MAILER-DAEMON "))
+		assert.Contains(t, mbox, "From: EcoLink <no-reply@example.com>\r\nTo: ada@example.com\r\nSubject: Hello\r\n")
+		assert.Contains(t, mbox, "First line\r\nSecond line\r\n")
+		assert.Contains(t, mbox, "Subject: =?utf-8?q?H=C3=A9llo?=\r\n", "non-ASCII subjects are encoded")
+	})
+
+	t.Run("refuses header injection", func(t *testing.T) {
+		err := m.Send(ctx, mailer.Message{To: "ada@example.com\r\nBcc: eve@example.com", Subject: "Hi"})
+		assert.Error(t, err)
+		err = m.Send(ctx, mailer.Message{To: "ada@example.com", Subject: "Hi\nBcc: eve@example.com"})
+		assert.Error(t, err)
+		assert.Error(t, m.Send(ctx, mailer.Message{Subject: "No recipient"}))
+	})
+}
+
+func TestSMTPMailer_Config(t *testing.T) {
+	_, err := mailer.NewSMTPMailer(mailer.SMTPConfig{Port: 587, From: "no-reply@example.com"})
+	assert.Error(t, err, "a host is required")
+	_, err = mailer.NewSMTPMailer(mailer.SMTPConfig{Host: "smtp.example.com", Port: 587, From: "not an address"})
+	assert.Error(t, err)
+	_, err = mailer.NewSMTPMailer(mailer.SMTPConfig{Host: "smtp.example.com", Port: 587, From: "EcoLink <no-reply@example.com>"})
+	assert.NoError(t, err)
+}
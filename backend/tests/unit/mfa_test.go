@@ -0,0 +1,243 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"ecolink-core/internal/auth/domain"
+	"ecolink-core/internal/auth/usecase"
+	"ecolink-core/pkg/database"
+	"ecolink-core/pkg/totp"
+	"encoding/base32"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// totpCode is the code of secret offset time steps from now. Each code is
+// accepted once, so a test takes the current one, then the next.
+func totpCode(secret []byte, offset int64) string {
+	return totp.Code(secret, totp.Step(time.Now())+offset)
+}
+
+// enableMFA sets up an authenticator app for userID and returns its secret
+// and recovery codes, having spent the current code
+func enableMFA(t *testing.T, mfa *usecase.MFAService, userID string) ([]byte, []string) {
+	t.Helper()
+	enrollment, err := mfa.Enroll(context.Background(), userID)
+	require.NoError(t, err)
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+	require.NoError(t, err)
+
+	codes, err := mfa.Confirm(context.Background(), userID, totpCode(secret, 0), testClient)
+	require.NoError(t, err)
+	return secret, codes
+}
+
+func TestMFAService(t *testing.T) {
+	ctx := context.Background()
+	tokenService := usecase.NewJWTTokenService("test-secret-key-32-bytes-long!", "test", usecase.DefaultAccessTokenTTL)
+	setup := func(t *testing.T) (*database.MemoryDB, *usecase.AuthService, *usecase.MFAService, *domain.User) {
+		db := database.NewMemoryDB()
+		authService := usecase.NewAuthService(db, db, db, usecase.NewLoginGuard(db, db, usecase.DefaultLockoutPolicy), tokenService, usecase.GoogleConfig{}, usecase.DefaultRefreshTokenTTL)
+		mfa := usecase.NewMFAService(authService, db, usecase.MFAConfig{
+			Issuer:          "EcoLink",
+			EncryptionKey:   bytes.Repeat([]byte{7}, 32),
+			RequiredDomains: []string{"@Corp.example"},
+		})
+		user, err := authService.Register(ctx, "Ada", "ada@corp.example", "correct-horse")
+		require.NoError(t, err)
+		return db, authService, mfa, user
+	}
+	// challenge logs in with the password and returns the MFA challenge
+	challenge := func(t *testing.T, authService *usecase.AuthService) string {
+		t.Helper()
+		_, err := authService.Login(ctx, "ada@corp.example", "correct-horse", testClient)
+		var required *usecase.MFARequiredError
+		require.ErrorAs(t, err, &required)
+		assert.WithinDuration(t, time.Now().Add(usecase.MFAChallengeTTL), required.ExpiresAt, 5*time.Second)
+		return required.Token
+	}
+
+	t.Run("enrolls an app and makes logins two-step", func(t *testing.T) {
+		_, authService, mfa, user := setup(t)
+		enrollment, err := mfa.Enroll(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/EcoLink:ada@corp.example?"))
+		assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+		png, err := base64.StdEncoding.DecodeString(enrollment.QRCode)
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(png, []byte("\x89PNG")))
+
+		// Until confirmed, logins take the password alone
+		_, err = authService.Login(ctx, "ada@corp.example", "correct-horse", testClient)
+		require.NoError(t, err)
+
+		secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+		require.NoError(t, err)
+		_, err = mfa.Confirm(ctx, user.ID, "000000", testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidMFACode)
+		codes, err := mfa.Confirm(ctx, user.ID, totpCode(secret, 0), testClient)
+		require.NoError(t, err)
+		require.Len(t, codes, usecase.RecoveryCodeCount)
+		assert.Regexp(t, `^[A-Z2-7]{4}(-[A-Z2-7]{4}){3}$`, codes[0])
+
+		status, err := mfa.Status(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, status.Enabled)
+		assert.True(t, status.Required)
+		assert.Equal(t, usecase.RecoveryCodeCount, status.RecoveryCodesLeft)
+
+		_, err = mfa.Enroll(ctx, user.ID)
+		assert.ErrorIs(t, err, usecase.ErrMFAEnabled)
+
+		// The challenge grants nothing by itself
+		mfaToken := challenge(t, authService)
+		_, err = tokenService.ValidateToken(mfaToken)
+		assert.Error(t, err)
+
+		// The code used to confirm was spent
+		_, err = mfa.CompleteLogin(ctx, mfaToken, totpCode(secret, 0), testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidMFACode)
+		token, err := mfa.CompleteLogin(ctx, mfaToken, totpCode(secret, 1), testClient)
+		require.NoError(t, err)
+		claims, err := tokenService.ValidateToken(token.Token)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, claims.UserID)
+		assert.NotEmpty(t, token.RefreshToken)
+	})
+
+	t.Run("accepts each recovery code once", func(t *testing.T) {
+		_, authService, mfa, user := setup(t)
+		_, codes := enableMFA(t, mfa, user.ID)
+
+		// Codes are forgiving about case and dashes
+		typed := strings.ToLower(strings.ReplaceAll(codes[3], "-", " "))
+		_, err := mfa.CompleteLogin(ctx, challenge(t, authService), typed, testClient)
+		require.NoError(t, err)
+		_, err = mfa.CompleteLogin(ctx, challenge(t, authService), codes[3], testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidMFACode)
+
+		status, err := mfa.Status(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, usecase.RecoveryCodeCount-1, status.RecoveryCodesLeft)
+
+		events, err := authService.ListAuthEvents(ctx, user.ID)
+		require.NoError(t, err)
+		var types []string
+		for _, event := range events {
+			types = append(types, event.Type)
+		}
+		assert.Contains(t, types, domain.AuthEventMFAEnabled)
+		assert.Contains(t, types, domain.AuthEventRecoveryCodeUsed)
+	})
+
+	t.Run("regenerates recovery codes", func(t *testing.T) {
+		_, authService, mfa, user := setup(t)
+		secret, old := enableMFA(t, mfa, user.ID)
+
+		_, err := mfa.RegenerateRecoveryCodes(ctx, user.ID, "123456", testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidMFACode)
+		codes, err := mfa.RegenerateRecoveryCodes(ctx, user.ID, totpCode(secret, 1), testClient)
+		require.NoError(t, err)
+		assert.Len(t, codes, usecase.RecoveryCodeCount)
+
+		_, err = mfa.CompleteLogin(ctx, challenge(t, authService), old[0], testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidMFACode)
+		_, err = mfa.CompleteLogin(ctx, challenge(t, authService), codes[0], testClient)
+		assert.NoError(t, err)
+	})
+
+	t.Run("locks out code guessing", func(t *testing.T) {
+		_, authService, mfa, user := setup(t)
+		secret, _ := enableMFA(t, mfa, user.ID)
+
+		mfaToken := challenge(t, authService)
+		for i := 0; i < usecase.DefaultLockoutPolicy.MaxFailures; i++ {
+			_, err := mfa.CompleteLogin(ctx, mfaToken, "000000", domain.Client{})
+			require.ErrorIs(t, err, usecase.ErrInvalidMFACode)
+		}
+		_, err := mfa.CompleteLogin(ctx, mfaToken, totpCode(secret, 1), domain.Client{})
+		assert.ErrorIs(t, err, usecase.ErrLoginLocked)
+	})
+
+	t.Run("disables the second factor", func(t *testing.T) {
+		_, authService, mfa, user := setup(t)
+		_, codes := enableMFA(t, mfa, user.ID)
+		mfaToken := challenge(t, authService)
+
+		assert.ErrorIs(t, mfa.Disable(ctx, user.ID, "000000", testClient), usecase.ErrInvalidMFACode)
+		require.NoError(t, mfa.Disable(ctx, user.ID, codes[0], testClient))
+		assert.ErrorIs(t, mfa.Disable(ctx, user.ID, codes[1], testClient), usecase.ErrMFANotEnabled)
+
+		// Logins take the password alone again, and pending challenges die
+		_, err := authService.Login(ctx, "ada@corp.example", "correct-horse", testClient)
+		require.NoError(t, err)
+		_, err = mfa.CompleteLogin(ctx, mfaToken, codes[1], testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidMFAChallenge)
+	})
+
+	t.Run("refuses forged challenges", func(t *testing.T) {
+		_, authService, mfa, user := setup(t)
+		_, codes := enableMFA(t, mfa, user.ID)
+
+		access, err := tokenService.GenerateToken(user.ID, user.Email, "session-1")
+		require.NoError(t, err)
+		_, err = mfa.CompleteLogin(ctx, access.Token, codes[0], testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidMFAChallenge)
+
+		other := usecase.NewJWTTokenService("another-secret-key-32-bytes-long", "test", usecase.DefaultAccessTokenTTL)
+		forged, _, err := other.GenerateMFAChallenge(user.ID)
+		require.NoError(t, err)
+		_, err = mfa.CompleteLogin(ctx, forged, codes[0], testClient)
+		assert.ErrorIs(t, err, usecase.ErrInvalidMFAChallenge)
+
+		_, err = mfa.CompleteLogin(ctx, challenge(t, authService), codes[0], testClient)
+		assert.NoError(t, err, "the recovery code was not spent")
+	})
+
+	t.Run("stores secrets encrypted", func(t *testing.T) {
+		db, authService, mfa, user := setup(t)
+		secret, _ := enableMFA(t, mfa, user.ID)
+
+		stored, err := db.FindMFA(ctx, user.ID)
+		require.NoError(t, err)
+		assert.False(t, bytes.Contains(stored.Secret, secret))
+
+		// Another key cannot read it
+		otherKey := usecase.NewMFAService(authService, db, usecase.MFAConfig{Issuer: "EcoLink", EncryptionKey: bytes.Repeat([]byte{8}, 32)})
+		_, err = otherKey.CompleteLogin(ctx, challenge(t, authService), totpCode(secret, 1), testClient)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, usecase.ErrInvalidMFACode)
+	})
+
+	t.Run("requires a second factor for listed domains", func(t *testing.T) {
+		db, authService, mfa, user := setup(t)
+		missing, err := mfa.Missing(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, missing)
+
+		other, err := authService.Register(ctx, "Grace", "grace@example.com", "correct-horse")
+		require.NoError(t, err)
+		missing, err = mfa.Missing(ctx, other.ID)
+		require.NoError(t, err)
+		assert.False(t, missing)
+
+		// A pending enrollment does not count
+		_, err = mfa.Enroll(ctx, user.ID)
+		require.NoError(t, err)
+		missing, err = mfa.Missing(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, missing)
+
+		enableMFA(t, mfa, user.ID)
+		missing, err = mfa.Missing(ctx, user.ID)
+		require.NoError(t, err)
+		assert.False(t, missing)
+		_, err = db.FindMFA(ctx, other.ID)
+		assert.ErrorIs(t, err, database.ErrMFANotFound)
+	})
+}
@@ -0,0 +1,87 @@
+package unit
+
+import (
+	"context"
+	"ecolink-core/pkg/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	ctx := context.Background()
+	policy := ratelimit.Policy{Name: "test", Rate: 1, Burst: 3}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("allows a burst then refills", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+		for remaining := 2; remaining >= 0; remaining-- {
+			result, err := store.Take(ctx, policy, "client", start)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, 3, result.Limit)
+			assert.Equal(t, remaining, result.Remaining)
+		}
+
+		result, err := store.Take(ctx, policy, "client", start)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, time.Second, result.RetryAfter)
+		assert.Equal(t, 3*time.Second, result.Reset)
+
+		result, err = store.Take(ctx, policy, "client", start.Add(time.Second))
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+	})
+
+	t.Run("keeps keys and policies apart", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+		for i := 0; i < 3; i++ {
+			_, err := store.Take(ctx, policy, "client", start)
+			require.NoError(t, err)
+		}
+
+		result, err := store.Take(ctx, policy, "other", start)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+
+		other := ratelimit.Policy{Name: "other", Rate: 1, Burst: 3}
+		result, err = store.Take(ctx, other, "client", start)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	})
+
+	t.Run("never refills beyond the burst", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+		_, err := store.Take(ctx, policy, "client", start)
+		require.NoError(t, err)
+
+		result, err := store.Take(ctx, policy, "client", start.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Remaining)
+	})
+
+	t.Run("sweeps keep buckets that are still refilling", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+		slow := ratelimit.Policy{Name: "slow", Rate: 1.0 / 60, Burst: 2}
+		for i := 0; i < 2; i++ {
+			_, err := store.Take(ctx, slow, "client", start)
+			require.NoError(t, err)
+		}
+
+		// A sweep runs past its interval, while the slow bucket has only
+		// earned one of its two tokens back
+		fast := ratelimit.Policy{Name: "fast", Rate: 100, Burst: 100}
+		later := start.Add(61 * time.Second)
+		_, err := store.Take(ctx, fast, "client", later)
+		require.NoError(t, err)
+
+		result, err := store.Take(ctx, slow, "client", later)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+	})
+}
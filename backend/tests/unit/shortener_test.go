@@ -2,71 +2,84 @@ package unit
 
 import (
 	"ecolink-core/pkg/utils"
+	"strings"
 	"testing"
 )
 
-func TestGenerateShortCode(t *testing.T) {
+func TestValidateShortCode(t *testing.T) {
 	tests := []struct {
-		name string
-		url  string
+		name    string
+		code    string
+		wantErr bool
 	}{
-		{"Valid HTTP URL", "http://example.com"},
-		{"Valid HTTPS URL", "https://example.com"},
-		{"Long URL", "https://example.com/very/long/path/with/many/segments"},
+		{"Valid code", "abc123", false},
+		{"Valid uppercase", "ABC123", false},
+		{"Valid alias", "spring-sale", false},
+		{"Valid underscore", "spring_sale_2025", false},
+		{"Shortest alias", "abc", false},
+		{"Empty code", "", true},
+		{"Too short", "ab", true},
+		{"Too long", strings.Repeat("a", 33), true},
+		{"Leading hyphen", "-abc12", true},
+		{"Trailing underscore", "abc12_", true},
+		{"Path traversal", "../abc", true},
+		{"Special characters", "abc@12", true},
+		{"Whitespace", "abc 12", true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			code := utils.GenerateShortCode(tt.url)
-			
-			// Test code length
-			if len(code) != 6 {
-				t.Errorf("Expected code length 6, got %d", len(code))
-			}
-			
-			// Test code validation
-			if err := utils.ValidateShortCode(code); err != nil {
-				t.Errorf("Generated code failed validation: %v", err)
+			err := utils.ValidateShortCode(tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateShortCode() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestGenerateShortCodeUniqueness(t *testing.T) {
-	url := "https://example.com"
-	codes := make(map[string]bool)
-	
-	// Generate 1000 codes and check for uniqueness
-	for i := 0; i < 1000; i++ {
-		code := utils.GenerateShortCode(url)
-		if codes[code] {
-			t.Errorf("Duplicate code generated: %s", code)
-		}
-		codes[code] = true
-	}
-}
-
-func TestValidateShortCode(t *testing.T) {
+func TestValidateAlias(t *testing.T) {
 	tests := []struct {
 		name    string
-		code    string
+		alias   string
 		wantErr bool
 	}{
-		{"Valid code", "abc123", false},
-		{"Valid uppercase", "ABC123", false},
-		{"Empty code", "", true},
-		{"Too short", "abc12", true},
-		{"Too long", "abc1234", true},
-		{"Invalid characters", "abc-12", true},
-		{"Special characters", "abc@12", true},
+		{"Valid alias", "spring-sale", false},
+		{"Reserved word", "api", true},
+		{"Reserved word any case", "Health", true},
+		{"Reserved prefix is allowed", "api-docs", false},
+		{"Invalid format", "spring sale", true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := utils.ValidateShortCode(tt.code)
+			err := utils.ValidateAlias(tt.alias)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("ValidateShortCode() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ValidateAlias() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestGenerateCode(t *testing.T) {
+	t.Run("respects length and alphabet", func(t *testing.T) {
+		code, err := utils.GenerateCode("xyz", 12)
+		if err != nil {
+			t.Fatalf("GenerateCode() error = %v", err)
+		}
+		if len(code) != 12 {
+			t.Errorf("Expected code length 12, got %d", len(code))
+		}
+		if strings.Trim(code, "xyz") != "" {
+			t.Errorf("Code %s contains characters outside the alphabet", code)
+		}
+	})
+
+	t.Run("rejects invalid parameters", func(t *testing.T) {
+		if _, err := utils.GenerateCode("a", 6); err == nil {
+			t.Error("Expected error for single-character alphabet")
+		}
+		if _, err := utils.GenerateCode(utils.DefaultAlphabet, utils.MaxCodeLength+1); err == nil {
+			t.Error("Expected error for oversized length")
+		}
+	})
+}
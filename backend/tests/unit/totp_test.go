@@ -0,0 +1,74 @@
+package unit
+
+import (
+	"ecolink-core/pkg/totp"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTP(t *testing.T) {
+	// The SHA1 secret of the RFC 6238 test vectors
+	secret := []byte("12345678901234567890")
+
+	t.Run("matches the RFC 6238 test vectors", func(t *testing.T) {
+		// The RFC lists eight digits; codes are their last six
+		for unix, want := range map[int64]string{
+			59:          "287082",
+			1111111109:  "081804",
+			1111111111:  "050471",
+			1234567890:  "005924",
+			2000000000:  "279037",
+			20000000000: "353130",
+		} {
+			assert.Equal(t, want, totp.Code(secret, totp.Step(time.Unix(unix, 0))), "at %d", unix)
+		}
+	})
+
+	t.Run("accepts codes of neighbouring steps only", func(t *testing.T) {
+		now := time.Unix(1111111111, 0)
+		current := totp.Step(now)
+
+		step, ok := totp.Validate(secret, totp.Code(secret, current), now, 1)
+		assert.True(t, ok)
+		assert.Equal(t, current, step)
+		step, ok = totp.Validate(secret, totp.Code(secret, current-1), now, 1)
+		assert.True(t, ok)
+		assert.Equal(t, current-1, step)
+
+		_, ok = totp.Validate(secret, totp.Code(secret, current-2), now, 1)
+		assert.False(t, ok)
+		_, ok = totp.Validate(secret, totp.Code(secret, current+1), now, 0)
+		assert.False(t, ok)
+		_, ok = totp.Validate(secret, "", now, 1)
+		assert.False(t, ok)
+		_, ok = totp.Validate(secret, "50471", now, 1)
+		assert.False(t, ok)
+	})
+
+	t.Run("generates distinct secrets", func(t *testing.T) {
+		first, err := totp.GenerateSecret()
+		require.NoError(t, err)
+		second, err := totp.GenerateSecret()
+		require.NoError(t, err)
+		assert.Len(t, first, totp.SecretSize)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("builds provisioning URIs", func(t *testing.T) {
+		uri, err := url.Parse(totp.ProvisioningURI("EcoLink", "ada@example.com", secret))
+		require.NoError(t, err)
+		assert.Equal(t, "otpauth", uri.Scheme)
+		assert.Equal(t, "totp", uri.Host)
+		assert.Equal(t, "/EcoLink:ada@example.com", uri.Path)
+		assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri.Query().Get("secret"))
+		assert.Equal(t, "EcoLink", uri.Query().Get("issuer"))
+		assert.Equal(t, "6", uri.Query().Get("digits"))
+		assert.Equal(t, "30", uri.Query().Get("period"))
+		assert.False(t, strings.Contains(uri.Query().Get("secret"), "="), "secrets are unpadded")
+	})
+}
@@ -22,9 +22,10 @@ func TestValidator_ShortCode(t *testing.T) {
 		{"valid code", "abc123", false},
 		{"valid uppercase", "ABC123", false},
 		{"valid mixed", "AbC123", false},
-		{"too short", "abc12", true},
-		{"too long", "abc1234", true},
-		{"invalid chars", "abc-12", true},
+		{"valid alias", "spring-sale", false},
+		{"too short", "ab", true},
+		{"too long", "abcdefghijklmnopqrstuvwxyz0123456", true},
+		{"invalid chars", "abc.12", true},
 		{"with spaces", "abc 12", true},
 		{"with symbols", "abc@12", true},
 	}